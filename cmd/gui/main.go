@@ -6,7 +6,8 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"time"
@@ -14,49 +15,127 @@ import (
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
+	"psu/pkg/config"
+	"psu/pkg/notify"
 	"psu/pkg/psu"
+	"psu/pkg/rules"
 )
 
-type config struct {
-	Host     string `json:"host"`
-	Port     string `json:"port"`
-	Sections []int  `json:"sections"`
-}
-
 func main() {
 	path, err := os.Getwd()
 	if err != nil {
 		panic(err)
 	}
 
-	file, err := os.ReadFile(filepath.Join(path, "config.json"))
+	cfg, err := config.Load(filepath.Join(path, "config.json"))
 	if err != nil {
 		panic(err)
 	}
 
-	cfg := config{}
-	if err := json.Unmarshal(file, &cfg); err != nil {
+	access, model, err := cfg.Access()
+	if err != nil {
 		panic(err)
 	}
 
-	p, err := psu.New(
-		psu.WithSocketConn(cfg.Host, cfg.Port),
-		psu.WithReadWriteDeadline(100*time.Millisecond),
-		psu.WithRetries(3))
+	// Set-points are written directly to PSU, not through watcher or cache
+	setter, _ := access.(psu.Setter)
+
+	var notifier *notify.Notifier
+	if len(cfg.Webhooks) > 0 {
+		notifier, err = notify.New(notify.WithTargets(cfg.Targets()...))
+		if err != nil {
+			panic(err)
+		}
+		go func() {
+			_ = notifier.Run(context.Background())
+		}()
+		// Readings passing through watcher are turned into events, whoever asked for them
+		access, err = psu.NewWatcher(psu.WatcherWithAccess(access), psu.WatcherWithHandler(notifier.Notify))
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// View and DataLogger ask for the same sections, don't bother PSU twice
+	cache, err := psu.NewCache(psu.CacheWithAccess(access))
 	if err != nil {
 		panic(err)
 	}
 
-	v, err := psu.NewView(
-		psu.ViewWithPSU(p),
+	opts := []psu.ViewOption{
+		psu.ViewWithAccess(cache),
+		psu.ViewWithModel(model),
 		psu.ViewWithSections(cfg.Sections...),
-	)
+	}
+	if cfg.Energy {
+		// Refresh is done every second, allow few readings to be lost
+		opts = append(opts, psu.ViewWithEnergy(5*time.Second))
+	}
+	if cfg.DataLog != nil {
+		d, err := cfg.DataLogger(cache)
+		if err != nil {
+			panic(err)
+		}
+		opts = append(opts, psu.ViewWithDataLogger(d))
+	}
+	if cfg.AutoOff > 0 {
+		// Outputs are switched off on time, even when refresh is stopped in background
+		opts = append(opts, psu.ViewWithAutoOff(time.Duration(cfg.AutoOff)))
+	}
+	if len(cfg.Limits) > 0 {
+		opts = append(opts, psu.ViewWithLimits(cfg.SoftLimits()...))
+	}
+	if cfg.PowerCycle != nil {
+		opts = append(opts, psu.ViewWithPowerCycle(cfg.CycleOptions()...))
+	}
+	if notifier != nil {
+		opts = append(opts, psu.ViewWithEventHandler(notifier.Notify))
+	}
+	if cfg.Alerts > 0 {
+		opts = append(opts, psu.ViewWithAlerts(cfg.Alerts))
+	}
+	if cfg.Charts != nil {
+		window := time.Duration(cfg.Charts.Window)
+		if window == 0 {
+			window = 5 * time.Minute
+		}
+		opts = append(opts, psu.ViewWithCharts(window, cfg.Charts.Dir))
+	}
+	// warning is shown, once window is up
+	var warning string
+	if cfg.Setpoints != nil {
+		if setter != nil {
+			opts = append(opts, psu.ViewWithSetpoints(setter, cfg.Envelopes()...))
+		} else {
+			// The rest of GUI works with remote server anyway
+			warning = "Setpoints are disabled: psud server (remote) can't change them. Connect to PSU directly (host, port) to change setpoints."
+		}
+	}
+
+	v, err := psu.NewView(opts...)
 
 	if err != nil {
 		panic(err)
 	}
 
+	if cfg.Rules != "" {
+		parsed, err := rules.Load(cfg.Rules)
+		if err != nil {
+			panic(err)
+		}
+		// View starts and stops DataLogger, so record button shows its state.
+		// Outputs are switched through View, so they aren't alerts.
+		engine, err := rules.New(rules.WithAccess(v.Access()), rules.WithRules(parsed...), rules.WithRecorder(v))
+		if err != nil {
+			panic(err)
+		}
+		go func() {
+			_ = engine.Run(context.Background())
+		}()
+	}
+
 	gui := app.New()
 	gui.Settings().SetTheme(theme.DarkTheme())
 
@@ -65,6 +144,11 @@ func main() {
 	})
 
 	gui.Lifecycle().SetOnExitedForeground(func() {
+		if cfg.Alerts > 0 {
+			// Keep reading, so trips and connection loss are notified in background
+			v.BackgroundRefresh(5 * time.Second)
+			return
+		}
 		v.StopBackgroundRefresh()
 	})
 
@@ -75,7 +159,16 @@ func main() {
 	w := gui.NewWindow("CPX400DP")
 	w.SetContent(ctn)
 
-	w.Resize(fyne.NewSize(280, 160))
+	size := fyne.NewSize(280, 190)
+	if cfg.Charts != nil {
+		size = fyne.NewSize(640, 520)
+	}
+	w.Resize(size)
+	if warning != "" {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
+		dialog.ShowInformation("Configuration", warning, w)
+	}
 	w.ShowAndRun()
-
+	// Nobody is left to watch countdowns, timed outputs are switched off
+	v.Close()
 }
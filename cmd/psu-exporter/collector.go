@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package main
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"psu/pkg/psu"
+)
+
+const namespace = "psu"
+
+// instrument is single PSU scraped by collector
+type instrument struct {
+	name     string
+	access   psu.Access
+	sections []int
+}
+
+// collector asks instruments for readings on each scrape
+type collector struct {
+	instruments []instrument
+
+	up         *prometheus.Desc
+	voltage    *prometheus.Desc
+	setVoltage *prometheus.Desc
+	current    *prometheus.Desc
+	setCurrent *prometheus.Desc
+	enabled    *prometheus.Desc
+	mode       *prometheus.Desc
+	trip       *prometheus.Desc
+	duration   *prometheus.HistogramVec
+	errors     *prometheus.CounterVec
+}
+
+var (
+	_ prometheus.Collector = (*collector)(nil)
+)
+
+var (
+	modes = []psu.Mode{psu.ModeUnknown, psu.ModeCV, psu.ModeCC, psu.ModeUnregulated}
+	trips = []psu.Trip{psu.TripOverVoltage, psu.TripOverCurrent, psu.TripHard}
+)
+
+func newCollector(instruments []instrument) *collector {
+	sectionLabels := []string{"instrument", "section"}
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, labels, nil)
+	}
+	return &collector{
+		instruments: instruments,
+		up:          desc("up", "Whether all sections of instrument were read successfully.", "instrument"),
+		voltage:     desc("output_voltage_volts", "Actual output voltage.", sectionLabels...),
+		setVoltage:  desc("output_voltage_setpoint_volts", "Output voltage set-point.", sectionLabels...),
+		current:     desc("output_current_amperes", "Actual output current.", sectionLabels...),
+		setCurrent:  desc("output_current_setpoint_amperes", "Output current set-point.", sectionLabels...),
+		enabled:     desc("output_enabled", "Whether output is switched on.", sectionLabels...),
+		mode:        desc("output_mode", "Regulation mode of output, 1 for the current one.", append(sectionLabels, "mode")...),
+		trip:        desc("output_trip", "Whether protection has tripped.", append(sectionLabels, "trip")...),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "exporter",
+			Name:      "command_duration_seconds",
+			Help:      "Duration of reading single section.",
+			Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"instrument"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "exporter",
+			Name:      "command_errors_total",
+			Help:      "Number of failed section readings.",
+		}, []string{"instrument"}),
+	}
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.up, c.voltage, c.setVoltage, c.current, c.setCurrent, c.enabled, c.mode, c.trip} {
+		ch <- d
+	}
+	c.duration.Describe(ch)
+	c.errors.Describe(ch)
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	// Instruments are independent, each of them has its own connection
+	wg := sync.WaitGroup{}
+	for _, inst := range c.instruments {
+		wg.Add(1)
+		go func(inst instrument) {
+			defer wg.Done()
+			c.collectInstrument(ch, inst)
+		}(inst)
+	}
+	wg.Wait()
+
+	c.duration.Collect(ch)
+	c.errors.Collect(ch)
+}
+
+func (c *collector) collectInstrument(ch chan<- prometheus.Metric, inst instrument) {
+	up := 1.0
+	for _, section := range inst.sections {
+		start := time.Now()
+		s, err := inst.access.Section(section)
+		c.duration.WithLabelValues(inst.name).Observe(time.Since(start).Seconds())
+		if err != nil {
+			log.Error("error on reading ", inst.name, " section ", section, ": ", err)
+			c.errors.WithLabelValues(inst.name).Inc()
+			up = 0
+			continue
+		}
+		c.collectSection(ch, inst.name, strconv.Itoa(section), s)
+	}
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, up, inst.name)
+}
+
+func (c *collector) collectSection(ch chan<- prometheus.Metric, name, section string, s *psu.Section) {
+	gauge := func(desc *prometheus.Desc, text string) {
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			log.Error("error on parsing ", text, ": ", err)
+			return
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, name, section)
+	}
+	gauge(c.voltage, s.ActualVoltage)
+	gauge(c.setVoltage, s.SetVoltage)
+	gauge(c.current, s.ActualCurrent)
+	gauge(c.setCurrent, s.SetCurrent)
+
+	ch <- prometheus.MustNewConstMetric(c.enabled, prometheus.GaugeValue, boolToFloat(s.State), name, section)
+	for _, mode := range modes {
+		ch <- prometheus.MustNewConstMetric(c.mode, prometheus.GaugeValue, boolToFloat(s.Mode == mode), name, section, modeLabel(mode))
+	}
+	for _, trip := range trips {
+		ch <- prometheus.MustNewConstMetric(c.trip, prometheus.GaugeValue, boolToFloat(s.Trip.Has(trip)), name, section, strings.ToLower(trip.String()))
+	}
+}
+
+func modeLabel(m psu.Mode) string {
+	if m == psu.ModeUnknown {
+		return "unknown"
+	}
+	return strings.ToLower(m.String())
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/psu"
+)
+
+type CollectorTestSuite struct {
+	suite.Suite
+	mock *AccessMocker
+}
+
+type AccessMocker struct {
+	mock.Mock
+}
+
+func TestCollector(t *testing.T) {
+	suite.Run(t, new(CollectorTestSuite))
+}
+
+func (t *CollectorTestSuite) SetupTest() {
+	t.mock = new(AccessMocker)
+}
+
+func (a *AccessMocker) Section(section int) (*psu.Section, error) {
+	args := a.Called(section)
+	return args.Get(0).(*psu.Section), args.Error(1)
+}
+
+func (a *AccessMocker) SetState(section int, value bool) (bool, error) {
+	args := a.Called(section, value)
+	return args.Bool(0), args.Error(1)
+}
+
+func (t *CollectorTestSuite) collector(sections ...int) *collector {
+	return newCollector([]instrument{{name: "bench", access: t.mock, sections: sections}})
+}
+
+func (t *CollectorTestSuite) TestSection() {
+	t.mock.On("Section", 1).Return(&psu.Section{
+		State:         true,
+		ActualVoltage: "11.99",
+		SetVoltage:    "12.00",
+		ActualCurrent: "0.500",
+		SetCurrent:    "1.000",
+		Mode:          psu.ModeCV,
+	}, nil)
+	expected := `
+# HELP psu_output_voltage_volts Actual output voltage.
+# TYPE psu_output_voltage_volts gauge
+psu_output_voltage_volts{instrument="bench",section="1"} 11.99
+# HELP psu_output_current_setpoint_amperes Output current set-point.
+# TYPE psu_output_current_setpoint_amperes gauge
+psu_output_current_setpoint_amperes{instrument="bench",section="1"} 1
+# HELP psu_output_enabled Whether output is switched on.
+# TYPE psu_output_enabled gauge
+psu_output_enabled{instrument="bench",section="1"} 1
+# HELP psu_output_mode Regulation mode of output, 1 for the current one.
+# TYPE psu_output_mode gauge
+psu_output_mode{instrument="bench",mode="cc",section="1"} 0
+psu_output_mode{instrument="bench",mode="cv",section="1"} 1
+psu_output_mode{instrument="bench",mode="unknown",section="1"} 0
+psu_output_mode{instrument="bench",mode="unreg",section="1"} 0
+# HELP psu_up Whether all sections of instrument were read successfully.
+# TYPE psu_up gauge
+psu_up{instrument="bench"} 1
+`
+	t.Nil(testutil.CollectAndCompare(t.collector(1), strings.NewReader(expected),
+		"psu_output_voltage_volts", "psu_output_current_setpoint_amperes", "psu_output_enabled", "psu_output_mode", "psu_up"))
+}
+
+func (t *CollectorTestSuite) TestTrip() {
+	t.mock.On("Section", 1).Return(&psu.Section{
+		ActualVoltage: "0.00",
+		SetVoltage:    "12.00",
+		ActualCurrent: "0.000",
+		SetCurrent:    "1.000",
+		Trip:          psu.TripOverCurrent | psu.TripHard,
+	}, nil)
+	expected := `
+# HELP psu_output_trip Whether protection has tripped.
+# TYPE psu_output_trip gauge
+psu_output_trip{instrument="bench",section="1",trip="hard"} 1
+psu_output_trip{instrument="bench",section="1",trip="ocp"} 1
+psu_output_trip{instrument="bench",section="1",trip="ovp"} 0
+`
+	t.Nil(testutil.CollectAndCompare(t.collector(1), strings.NewReader(expected), "psu_output_trip"))
+}
+
+func (t *CollectorTestSuite) TestError() {
+	t.mock.On("Section", 1).Return(&psu.Section{ActualVoltage: "5.00", SetVoltage: "5.00", ActualCurrent: "0.100", SetCurrent: "1.000"}, nil)
+	t.mock.On("Section", 2).Return((*psu.Section)(nil), errors.New("timeout"))
+	c := t.collector(1, 2)
+	expected := `
+# HELP psu_exporter_command_errors_total Number of failed section readings.
+# TYPE psu_exporter_command_errors_total counter
+psu_exporter_command_errors_total{instrument="bench"} 1
+# HELP psu_output_enabled Whether output is switched on.
+# TYPE psu_output_enabled gauge
+psu_output_enabled{instrument="bench",section="1"} 0
+# HELP psu_up Whether all sections of instrument were read successfully.
+# TYPE psu_up gauge
+psu_up{instrument="bench"} 0
+`
+	t.Nil(testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"psu_exporter_command_errors_total", "psu_output_enabled", "psu_up"))
+	// Section, which can't be read, isn't exported
+	t.Equal(len(modes), testutil.CollectAndCount(c, "psu_output_mode"))
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+// psu-exporter exposes readings of one or more PSUs as Prometheus metrics
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap/zapcore"
+	"psu/pkg/psu"
+)
+
+type config struct {
+	Listen      string             `json:"listen"`
+	LogLevel    string             `json:"logLevel"`
+	Instruments []instrumentConfig `json:"instruments"`
+}
+
+type instrumentConfig struct {
+	Name     string `json:"name"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Sections []int  `json:"sections"`
+}
+
+var log = psu.NewDefaultZap(zapcore.InfoLevel)
+
+func main() {
+	path := flag.String("config", "config.json", "path to configuration file")
+	flag.Parse()
+
+	file, err := os.ReadFile(*path)
+	if err != nil {
+		panic(err)
+	}
+
+	cfg := config{
+		Listen:   ":9101",
+		LogLevel: "info",
+	}
+	if err := json.Unmarshal(file, &cfg); err != nil {
+		panic(err)
+	}
+	level, err := zapcore.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		panic(err)
+	}
+	log = psu.NewDefaultZap(level)
+
+	instruments := make([]instrument, len(cfg.Instruments))
+	for i, instCfg := range cfg.Instruments {
+		p, err := psu.New(
+			psu.WithSocketConn(instCfg.Host, instCfg.Port),
+			psu.WithReadWriteDeadline(100*time.Millisecond),
+			psu.WithRetries(3),
+			psu.WithLogLevel(cfg.LogLevel))
+		if err != nil {
+			panic(err)
+		}
+		name := instCfg.Name
+		if name == "" {
+			name = instCfg.Host + ":" + instCfg.Port
+		}
+		instruments[i] = instrument{
+			name:     name,
+			access:   p,
+			sections: instCfg.Sections,
+		}
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		newCollector(instruments),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	log.Info("Listening on ", cfg.Listen)
+	if err := http.ListenAndServe(cfg.Listen, nil); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+// psu-mqtt bridges single PSU with MQTT broker
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"go.uber.org/zap/zapcore"
+	"psu/pkg/mqtt"
+	"psu/pkg/psu"
+)
+
+type config struct {
+	LogLevel string `json:"logLevel"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Sections []int  `json:"sections"`
+	// Interval between readings in seconds
+	Interval int          `json:"interval"`
+	Broker   brokerConfig `json:"broker"`
+	// Topic is base of default topics, Topics overrides them
+	Topic  string       `json:"topic"`
+	Topics *mqtt.Topics `json:"topics"`
+	// Discovery is Home Assistant discovery prefix, empty disables discovery
+	Discovery string      `json:"discovery"`
+	Device    mqtt.Device `json:"device"`
+}
+
+type brokerConfig struct {
+	URL      string `json:"url"`
+	ClientID string `json:"clientId"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+var log = psu.NewDefaultZap(zapcore.InfoLevel)
+
+func main() {
+	path := flag.String("config", "config.json", "path to configuration file")
+	flag.Parse()
+
+	file, err := os.ReadFile(*path)
+	if err != nil {
+		panic(err)
+	}
+
+	cfg := config{
+		LogLevel:  "info",
+		Interval:  5,
+		Topic:     "cpx400dp",
+		Discovery: "homeassistant",
+		Broker: brokerConfig{
+			URL:      "tcp://localhost:1883",
+			ClientID: "psu-mqtt",
+		},
+	}
+	if err := json.Unmarshal(file, &cfg); err != nil {
+		panic(err)
+	}
+	level, err := zapcore.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		panic(err)
+	}
+	log = psu.NewDefaultZap(level)
+	topics := mqtt.DefaultTopics(cfg.Topic)
+	if cfg.Topics != nil {
+		topics = *cfg.Topics
+	}
+
+	p, err := psu.New(
+		psu.WithSocketConn(cfg.Host, cfg.Port),
+		psu.WithReadWriteDeadline(100*time.Millisecond),
+		psu.WithRetries(3),
+		psu.WithLogLevel(cfg.LogLevel))
+	if err != nil {
+		panic(err)
+	}
+	mqtt.SetLogger(log)
+
+	// Broker announces bridge offline, if connection is lost.
+	// Session is kept, so commands are still subscribed after reconnect.
+	opts := paho.NewClientOptions().
+		AddBroker(cfg.Broker.URL).
+		SetClientID(cfg.Broker.ClientID).
+		SetUsername(cfg.Broker.Username).
+		SetPassword(cfg.Broker.Password).
+		SetCleanSession(false).
+		SetAutoReconnect(true).
+		SetWill(topics.Availability, mqtt.PayloadOffline, 1, true).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			log.Error("connection to broker lost: ", err)
+		})
+	client := paho.NewClient(opts)
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		panic(token.Error())
+	}
+	defer client.Disconnect(250)
+
+	b, err := mqtt.New(
+		mqtt.WithPSU(p),
+		mqtt.WithClient(mqtt.NewPahoClient(client, 5*time.Second)),
+		mqtt.WithSections(cfg.Sections...),
+		mqtt.WithInterval(time.Duration(cfg.Interval)*time.Second),
+		mqtt.WithTopics(topics),
+		mqtt.WithDiscovery(cfg.Discovery),
+		mqtt.WithDevice(cfg.Device),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Info("Connected to ", cfg.Broker.URL)
+	if err := b.Run(ctx); err != nil {
+		panic(err)
+	}
+}
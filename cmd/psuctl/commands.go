@@ -0,0 +1,210 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"psu/pkg/psu"
+)
+
+var (
+	errFailed = errors.New("not all sections were handled")
+)
+
+// sectionCommand prints readings, exits with exitTripped if any protection is tripped
+func sectionCommand(a *app, args []string) error {
+	flags := a.flags("section")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	sections, err := a.sections(flags.Args(), true)
+	if err != nil {
+		return err
+	}
+	access, err := a.connect()
+	if err != nil {
+		return err
+	}
+
+	readings := read(access, sections)
+	if err := a.printReadings(readings); err != nil {
+		return err
+	}
+
+	tripped := false
+	for _, r := range readings {
+		if r.err != nil {
+			return errFailed
+		}
+		tripped = tripped || r.section.Trip != 0
+	}
+	if tripped {
+		return errTripped
+	}
+	return nil
+}
+
+// stateCommand switches outputs to state returned by next, which gets current state
+func stateCommand(next func(state bool) bool) command {
+	return func(a *app, args []string) error {
+		flags := a.flags("state")
+		if err := flags.Parse(args); err != nil {
+			return errUsage
+		}
+		sections, err := a.sections(flags.Args(), false)
+		if err != nil {
+			return err
+		}
+		access, err := a.connect()
+		if err != nil {
+			return err
+		}
+
+		var result []switched
+		failed := false
+		for _, number := range sections {
+			state, err := setState(access, number, next)
+			if err != nil {
+				fmt.Fprintf(a.stderr, "section %d: %v\n", number, err)
+				failed = true
+				continue
+			}
+			result = append(result, switched{Section: number, State: state})
+		}
+		if err := a.printSwitched(result); err != nil {
+			return err
+		}
+		if failed {
+			return errFailed
+		}
+		return nil
+	}
+}
+
+func setState(access psu.Access, number int, next func(state bool) bool) (bool, error) {
+	// Reading state is needed only to toggle, but it also verifies section exists
+	s, err := access.Section(number)
+	if err != nil {
+		return false, err
+	}
+	return access.SetState(number, next(s.State))
+}
+
+// watchCommand prints readings every interval, until interrupted
+func watchCommand(a *app, args []string) error {
+	flags := a.flags("watch")
+	interval := flags.Duration("interval", 1*time.Second, "time between readings")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("%w: interval must be positive", errUsage)
+	}
+	sections, err := a.sections(flags.Args(), true)
+	if err != nil {
+		return err
+	}
+	access, err := a.connect()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		readings := read(access, sections)
+		if err := a.printWatch(time.Now(), readings); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// rawCommand needs direct connection, as psud doesn't forward raw commands
+func rawCommand(a *app, args []string) error {
+	flags := a.flags("raw")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("%w: command required", errUsage)
+	}
+	p, err := a.cfg.PSU(quiet)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	var result []exchange
+	for _, cmd := range flags.Args() {
+		reply, err := p.Raw(cmd)
+		if err != nil {
+			_ = a.printRaw(result)
+			return fmt.Errorf("%s: %w", cmd, err)
+		}
+		result = append(result, exchange{Command: cmd, Reply: reply, Query: psu.IsQuery(cmd)})
+	}
+	return a.printRaw(result)
+}
+
+func identifyCommand(a *app, args []string) error {
+	flags := a.flags("identify")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	access, err := a.connect()
+	if err != nil {
+		return err
+	}
+	identifier, ok := access.(psu.Identifier)
+	if !ok {
+		return fmt.Errorf("%w: instrument can't be identified", errUsage)
+	}
+	id, err := identifier.Identify()
+	if err != nil {
+		return err
+	}
+	return a.printIdentification(strings.TrimSpace(id))
+}
+
+type reading struct {
+	number  int
+	section *psu.Section
+	err     error
+}
+
+type switched struct {
+	Section int  `json:"section"`
+	State   bool `json:"state"`
+}
+
+type exchange struct {
+	Command string `json:"command"`
+	Reply   string `json:"reply,omitempty"`
+	Query   bool   `json:"-"`
+}
+
+func read(access psu.Access, sections []int) []reading {
+	readings := make([]reading, len(sections))
+	for i, number := range sections {
+		s, err := access.Section(number)
+		readings[i] = reading{number: number, section: s, err: err}
+	}
+	return readings
+}
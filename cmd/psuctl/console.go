@@ -0,0 +1,223 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/peterh/liner"
+	"psu/pkg/psu"
+)
+
+const consoleHelp = `Commands are sent to PSU as typed, replies of queries (containing '?') are printed.
+Use Tab to complete command, Up/Down to browse history, Ctrl-D to quit.
+
+  :help          show this help and known commands
+  :save FILE     save transcript of session to FILE
+  :quit          quit console
+`
+
+// console is interactive session with PSU
+type console struct {
+	psu        *psu.PSU
+	sections   []int
+	out        io.Writer
+	transcript []string
+	// live receives transcript as it goes, may be nil
+	live io.Writer
+}
+
+// consoleCommand talks raw protocol, like nc on port 9221, but with history and completion
+func consoleCommand(a *app, args []string) error {
+	flags := a.flags("console")
+	transcript := flags.String("transcript", "", "append transcript of session to file")
+	history := flags.String("history", historyPath(), "file with command history, empty disables history")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	p, err := a.cfg.PSU(quiet)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	c := &console{psu: p, sections: a.cfg.Sections, out: a.stdout}
+	if len(c.sections) == 0 {
+		c.sections = []int{1, 2}
+	}
+	if *transcript != "" {
+		f, err := os.OpenFile(*transcript, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+		defer f.Close()
+		c.live = f
+	}
+
+	line := liner.NewLiner()
+	defer line.Close()
+	line.SetCtrlCAborts(true)
+	line.SetTabCompletionStyle(liner.TabPrints)
+	line.SetCompleter(c.complete)
+	if *history != "" {
+		if f, err := os.Open(*history); err == nil {
+			_, _ = line.ReadHistory(f)
+			_ = f.Close()
+		}
+		defer saveHistory(line, *history)
+	}
+
+	fmt.Fprintf(c.out, "Connected to %s:%s, type :help for help\n", a.cfg.Host, a.cfg.Port)
+	for {
+		input, err := line.Prompt("psu> ")
+		if errors.Is(err, liner.ErrPromptAborted) {
+			continue
+		}
+		if err != nil {
+			// Ctrl-D
+			fmt.Fprintln(c.out)
+			return nil
+		}
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+		line.AppendHistory(input)
+		if strings.HasPrefix(input, ":") {
+			if quit := c.meta(input); quit {
+				return nil
+			}
+			continue
+		}
+		c.exchange(input)
+	}
+}
+
+// exchange sends cmd and prints reply, registers are decoded to bit names
+func (c *console) exchange(cmd string) {
+	c.record(">", cmd)
+	if _, known := psu.Lookup(cmd); !known && !strings.Contains(cmd, ";") {
+		fmt.Fprintln(c.out, "warning: unknown command, sent anyway")
+	}
+	reply, err := c.psu.Raw(cmd)
+	if err != nil {
+		c.record("!", err.Error())
+		fmt.Fprintln(c.out, "error:", err)
+		return
+	}
+	if !psu.IsQuery(cmd) {
+		return
+	}
+	c.record("<", reply)
+	if names, ok := psu.DecodeReply(cmd, reply); ok {
+		decoded := "-"
+		if len(names) > 0 {
+			decoded = strings.Join(names, " | ")
+		}
+		fmt.Fprintf(c.out, "%s  (%s)\n", reply, decoded)
+		return
+	}
+	fmt.Fprintln(c.out, reply)
+}
+
+// meta handles console commands, returns true if console should quit
+func (c *console) meta(input string) bool {
+	name, arg, _ := strings.Cut(input, " ")
+	switch name {
+	case ":quit", ":q", ":exit":
+		return true
+	case ":help", ":h":
+		fmt.Fprint(c.out, consoleHelp)
+		fmt.Fprintln(c.out)
+		w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+		for _, info := range psu.Commands() {
+			fmt.Fprintf(w, "  %s\t%s\n", info.Syntax, info.Description)
+		}
+		_ = w.Flush()
+	case ":save":
+		if err := c.save(strings.TrimSpace(arg)); err != nil {
+			fmt.Fprintln(c.out, "error:", err)
+			return false
+		}
+		fmt.Fprintln(c.out, "transcript saved")
+	default:
+		fmt.Fprintf(c.out, "unknown console command %q, type :help for help\n", name)
+	}
+	return false
+}
+
+// complete returns known commands starting with line, section numbers are filled in
+func (c *console) complete(line string) []string {
+	prefix := strings.ToUpper(line)
+	seen := make(map[string]bool)
+	var candidates []string
+	add := func(candidate string) {
+		if strings.HasPrefix(strings.ToUpper(candidate), prefix) && !seen[candidate] {
+			seen[candidate] = true
+			candidates = append(candidates, candidate)
+		}
+	}
+	for _, info := range psu.Commands() {
+		suffix := ""
+		if info.HasArgument() {
+			suffix = " "
+		}
+		if !strings.Contains(info.Syntax, "<N>") {
+			add(info.Header() + suffix)
+			continue
+		}
+		for _, section := range c.sections {
+			add(info.Expand(section) + suffix)
+		}
+	}
+	if strings.HasPrefix(line, ":") {
+		for _, name := range []string{":help", ":save ", ":quit"} {
+			add(name)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// record appends entry to transcript, dir is ">" for command, "<" for reply and "!" for error
+func (c *console) record(dir, text string) {
+	entry := time.Now().Format("2006-01-02 15:04:05.000") + " " + dir + " " + text
+	c.transcript = append(c.transcript, entry)
+	if c.live != nil {
+		_, _ = fmt.Fprintln(c.live, entry)
+	}
+}
+
+func (c *console) save(path string) error {
+	if path == "" {
+		return errors.New("file name required")
+	}
+	return os.WriteFile(path, []byte(strings.Join(c.transcript, "\n")+"\n"), 0o644)
+}
+
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".psuctl_history")
+}
+
+func saveHistory(line *liner.State, path string) {
+	f, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	_, _ = line.WriteHistory(f)
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"psu/pkg/config"
+	"psu/pkg/psu"
+)
+
+type cycleJSON struct {
+	Time    time.Time `json:"time"`
+	Cycle   int       `json:"cycle"`
+	Section int       `json:"section"`
+	Current float64   `json:"current"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// cycleCommand power cycles sections, each result is printed as soon as cycle is done.
+// Defaults are taken from powerCycle of config.
+func cycleCommand(a *app, args []string) error {
+	defaults := defaultCycle(a.cfg.PowerCycle)
+	flags := a.flags("cycle")
+	off := flags.Duration("off", defaults.off, "time outputs stay off")
+	on := flags.Duration("on", defaults.on, "time outputs stay on")
+	settle := flags.Duration("settle", defaults.settle, "time between switching on and measuring current")
+	count := flags.Int("count", defaults.cycles, "number of cycles")
+	stop := flags.Bool("stop", false, "stop on the first failure")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	sections, err := a.sections(flags.Args(), false)
+	if err != nil {
+		return err
+	}
+	access, err := a.connect()
+	if err != nil {
+		return err
+	}
+
+	c, err := psu.NewPowerCycle(
+		psu.CycleWithAccess(access),
+		psu.CycleWithSections(sections...),
+		psu.CycleWithOffTime(*off),
+		psu.CycleWithOnTime(*on),
+		psu.CycleWithSettle(*settle),
+		psu.CycleWithCycles(*count),
+		psu.CycleWithStopOnFailure(*stop),
+		psu.CycleWithHandler(a.printCycle),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	summary, err := c.Run(ctx)
+	if !a.json {
+		fmt.Fprintf(a.stdout, "%d cycles, %d failures\n", summary.Cycles, summary.Failures)
+	}
+	// Interrupt ends test early, it isn't failure on its own
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	if summary.Failures > 0 {
+		return errFailed
+	}
+	return nil
+}
+
+type cycleDefaults struct {
+	off, on, settle time.Duration
+	cycles          int
+}
+
+func defaultCycle(cfg *config.PowerCycle) cycleDefaults {
+	d := cycleDefaults{off: 1 * time.Second, on: 1 * time.Second, settle: 0, cycles: 1}
+	if cfg == nil {
+		return d
+	}
+	if cfg.Off > 0 {
+		d.off = time.Duration(cfg.Off)
+	}
+	if cfg.On > 0 {
+		d.on = time.Duration(cfg.On)
+	}
+	if cfg.Settle > 0 {
+		d.settle = time.Duration(cfg.Settle)
+	}
+	if cfg.Cycles > 0 {
+		d.cycles = cfg.Cycles
+	}
+	return d
+}
+
+func (a *app) printCycle(result psu.CycleResult) {
+	if a.json {
+		r := cycleJSON{Time: time.Now(), Cycle: result.Cycle, Section: result.Section, Current: result.Current}
+		if result.Err != nil {
+			r.Error = result.Err.Error()
+		}
+		// JSON Lines, so long tests can be followed
+		_ = json.NewEncoder(a.stdout).Encode(r)
+		return
+	}
+	if result.Err != nil {
+		fmt.Fprintf(a.stdout, "cycle %d section %d: FAILED %v\n", result.Cycle, result.Section, result.Err)
+		return
+	}
+	fmt.Fprintf(a.stdout, "cycle %d section %d: %.3f A\n", result.Cycle, result.Section, result.Current)
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+// psuctl drives PSU from shell scripts and CI jobs
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"psu/pkg/config"
+	"psu/pkg/psu"
+)
+
+// Exit codes
+const (
+	exitOK = 0
+	// exitFailure - PSU couldn't be reached or failed to execute command
+	exitFailure = 1
+	// exitUsage - wrong arguments or configuration
+	exitUsage = 2
+	// exitTripped - protection of read section is tripped
+	exitTripped = 3
+)
+
+var (
+	errUsage   = errors.New("usage")
+	errTripped = errors.New("protection tripped")
+)
+
+const usage = `Usage: psuctl [-config file] [-json] <command> [arguments]
+
+Commands:
+  section [N...]                read sections (all configured by default)
+  on N...                       switch outputs on
+  off N...                      switch outputs off
+  toggle N...                   switch outputs to opposite state
+  watch [-interval 1s] [N...]   show readings live, until interrupted
+  cycle [-off 1s] [-on 1s] [-count 1] N...
+                                switch outputs off and on repeatedly, print current on power-up
+  raw CMD...                    send raw commands, print replies of queries
+  rules [-file rules.json]      run rules until interrupted, print fired rules
+  identify                      print identification of instrument
+  console [-transcript file]    interactive console with history and completion
+  tui [-interval 1s] [N...]     full-screen dashboard, outputs can be switched
+
+Exit codes: 0 - success, 1 - PSU failure, 2 - usage or configuration error, 3 - protection tripped
+`
+
+// app is state shared by commands
+type app struct {
+	cfg    config.Config
+	json   bool
+	stdout io.Writer
+	stderr io.Writer
+	access psu.Access
+}
+
+type command func(a *app, args []string) error
+
+// quiet keeps logs of PSU out of output, errors are reported by psuctl
+var quiet = psu.WithLogLevel("fatal")
+
+var commands = map[string]command{
+	"section":  sectionCommand,
+	"on":       stateCommand(func(bool) bool { return true }),
+	"off":      stateCommand(func(bool) bool { return false }),
+	"toggle":   stateCommand(func(state bool) bool { return !state }),
+	"watch":    watchCommand,
+	"cycle":    cycleCommand,
+	"rules":    rulesCommand,
+	"raw":      rawCommand,
+	"identify": identifyCommand,
+	"console":  consoleCommand,
+	"tui":      tuiCommand,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	return (&app{stdout: stdout, stderr: stderr}).run(args)
+}
+
+// run executes command given by args and returns exit code, access is used if already set
+func (a *app) run(args []string) int {
+	stderr := a.stderr
+	flags := flag.NewFlagSet("psuctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, usage) }
+	path := flags.String("config", "config.json", "path to configuration file")
+	flags.BoolVar(&a.json, "json", false, "print JSON instead of table")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", flags.Arg(0), usage)
+		return exitUsage
+	}
+
+	cfg, err := config.Load(*path)
+	if err != nil {
+		fmt.Fprintln(stderr, "error on reading config:", err)
+		return exitUsage
+	}
+	a.cfg = cfg
+
+	err = cmd(a, flags.Args()[1:])
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage):
+		// Bare errUsage means, flag package already explained problem
+		if err != errUsage {
+			fmt.Fprintln(stderr, err)
+		}
+		return exitUsage
+	case errors.Is(err, errTripped):
+		return exitTripped
+	default:
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+}
+
+// flags returns FlagSet of command, which also accepts -json after command name
+func (a *app) flags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+	flags.BoolVar(&a.json, "json", a.json, "print JSON instead of table")
+	return flags
+}
+
+// connect returns Access configured in config, connection is made once
+func (a *app) connect() (psu.Access, error) {
+	if a.access != nil {
+		return a.access, nil
+	}
+	access, _, err := a.cfg.Access(quiet)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	a.access = access
+	return access, nil
+}
+
+// sections parses section numbers, configured sections are used if args are empty and fallback is true
+func (a *app) sections(args []string, fallback bool) ([]int, error) {
+	if len(args) == 0 {
+		if fallback && len(a.cfg.Sections) > 0 {
+			return a.cfg.Sections, nil
+		}
+		return nil, fmt.Errorf("%w: section number required", errUsage)
+	}
+	sections := make([]int, len(args))
+	for i, arg := range args {
+		number, err := strconv.Atoi(arg)
+		if err != nil || number < 1 {
+			return nil, fmt.Errorf("%w: invalid section %q", errUsage, arg)
+		}
+		sections[i] = number
+	}
+	return sections, nil
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/psu"
+)
+
+type RunTestSuite struct {
+	suite.Suite
+	mock   *AccessMocker
+	config string
+}
+
+type AccessMocker struct {
+	mock.Mock
+}
+
+func TestRun(t *testing.T) {
+	suite.Run(t, new(RunTestSuite))
+}
+
+func (t *RunTestSuite) SetupTest() {
+	t.mock = new(AccessMocker)
+	t.config = filepath.Join(t.T().TempDir(), "config.json")
+	t.Require().Nil(os.WriteFile(t.config, []byte(`{"sections": [1, 2]}`), 0o644))
+}
+
+func (a *AccessMocker) Section(section int) (*psu.Section, error) {
+	args := a.Called(section)
+	return args.Get(0).(*psu.Section), args.Error(1)
+}
+
+func (a *AccessMocker) SetState(section int, value bool) (bool, error) {
+	args := a.Called(section, value)
+	return args.Bool(0), args.Error(1)
+}
+
+// run executes psuctl with fake access, config is added in front of args
+func (t *RunTestSuite) run(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	a := &app{stdout: &stdout, stderr: &stderr, access: t.mock}
+	code := a.run(append([]string{"-config", t.config}, args...))
+	return code, stdout.String(), stderr.String()
+}
+
+func section(state bool, trip psu.Trip) *psu.Section {
+	return &psu.Section{
+		State:         state,
+		ActualVoltage: "11.99",
+		SetVoltage:    "12.00",
+		ActualCurrent: "0.100",
+		SetCurrent:    "1.000",
+		Mode:          psu.ModeCV,
+		Trip:          trip,
+	}
+}
+
+func (t *RunTestSuite) TestExitCodes() {
+	errTimeout := errors.New("timeout")
+	args := []struct {
+		name  string
+		args  []string
+		setup func(m *AccessMocker)
+		code  int
+	}{
+		{name: "no command", args: nil, code: exitUsage},
+		{name: "unknown command", args: []string{"reboot"}, code: exitUsage},
+		{name: "unknown flag", args: []string{"-verbose", "section"}, code: exitUsage},
+		{name: "no section", args: []string{"on"}, code: exitUsage},
+		{name: "invalid section", args: []string{"off", "first"}, code: exitUsage},
+		{name: "not identifier", args: []string{"identify"}, code: exitUsage},
+		{
+			name: "read",
+			args: []string{"section"},
+			setup: func(m *AccessMocker) {
+				m.On("Section", 1).Return(section(true, 0), nil)
+				m.On("Section", 2).Return(section(false, 0), nil)
+			},
+			code: exitOK,
+		},
+		{
+			name: "read failed",
+			args: []string{"section", "2"},
+			setup: func(m *AccessMocker) {
+				m.On("Section", 2).Return((*psu.Section)(nil), errTimeout)
+			},
+			code: exitFailure,
+		},
+		{
+			name: "tripped",
+			args: []string{"section"},
+			setup: func(m *AccessMocker) {
+				m.On("Section", 1).Return(section(false, psu.TripOverCurrent), nil)
+				m.On("Section", 2).Return(section(false, 0), nil)
+			},
+			code: exitTripped,
+		},
+		{
+			name: "switched",
+			args: []string{"toggle", "1"},
+			setup: func(m *AccessMocker) {
+				m.On("Section", 1).Return(section(true, 0), nil)
+				m.On("SetState", 1, false).Return(false, nil)
+			},
+			code: exitOK,
+		},
+		{
+			name: "switch failed",
+			args: []string{"on", "1", "2"},
+			setup: func(m *AccessMocker) {
+				m.On("Section", 1).Return(section(false, 0), nil)
+				m.On("SetState", 1, true).Return(true, nil)
+				m.On("Section", 2).Return((*psu.Section)(nil), errTimeout)
+			},
+			code: exitFailure,
+		},
+	}
+	for _, arg := range args {
+		t.mock = new(AccessMocker)
+		if arg.setup != nil {
+			arg.setup(t.mock)
+		}
+		code, _, _ := t.run(arg.args...)
+		t.Equal(arg.code, code, arg.name)
+		t.mock.AssertExpectations(t.T())
+	}
+}
+
+func (t *RunTestSuite) TestMissingConfig() {
+	t.config = filepath.Join(t.T().TempDir(), "missing.json")
+	code, _, stderr := t.run("section")
+	t.Equal(exitUsage, code)
+	t.Contains(stderr, "error on reading config")
+}
+
+func (t *RunTestSuite) TestSectionJSON() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(section(true, psu.TripOverVoltage), nil)
+	t.mock.On("Section", 2).Return((*psu.Section)(nil), errors.New("timeout"))
+
+	code, stdout, _ := t.run("-json", "section")
+	// Failure is reported before trip
+	r.Equal(exitFailure, code)
+	var sections []sectionJSON
+	r.Nil(json.Unmarshal([]byte(stdout), &sections))
+	r.Len(sections, 2)
+	r.Equal(1, sections[0].Section.Section)
+	r.True(sections[0].State)
+	r.Equal(11.99, sections[0].ActualVoltage)
+	r.Equal(1.0, sections[0].SetCurrent)
+	r.Equal([]string{"OVP"}, sections[0].Trip)
+	r.Empty(sections[0].Error)
+	r.Equal(2, sections[1].Section.Section)
+	r.Equal("timeout", sections[1].Error)
+}
+
+func (t *RunTestSuite) TestSwitchedJSON() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(section(false, 0), nil)
+	t.mock.On("SetState", 1, true).Return(true, nil)
+
+	// -json is accepted after command too
+	code, stdout, _ := t.run("on", "-json", "1")
+	r.Equal(exitOK, code)
+	r.JSONEq(`[{"section": 1, "state": true}]`, stdout)
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"psu/pkg/api"
+)
+
+// sectionJSON is api.Section, Error is set if section couldn't be read
+type sectionJSON struct {
+	api.Section
+	Error string `json:"error,omitempty"`
+}
+
+type watchJSON struct {
+	Time     time.Time     `json:"time"`
+	Sections []sectionJSON `json:"sections"`
+}
+
+// clearScreen moves cursor to top left corner and clears terminal
+const clearScreen = "\033[H\033[2J"
+
+func (a *app) printReadings(readings []reading) error {
+	if a.json {
+		return a.encode(toJSON(readings))
+	}
+	return a.table(readings)
+}
+
+func (a *app) printWatch(now time.Time, readings []reading) error {
+	if a.json {
+		// JSON Lines, so output can be piped
+		return json.NewEncoder(a.stdout).Encode(watchJSON{Time: now, Sections: toJSON(readings)})
+	}
+	fmt.Fprint(a.stdout, clearScreen)
+	fmt.Fprintln(a.stdout, now.Format("2006-01-02 15:04:05"))
+	fmt.Fprintln(a.stdout)
+	return a.table(readings)
+}
+
+func (a *app) printSwitched(result []switched) error {
+	if a.json {
+		if result == nil {
+			result = []switched{}
+		}
+		return a.encode(result)
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SECTION\tSTATE")
+	for _, s := range result {
+		fmt.Fprintf(w, "%d\t%s\n", s.Section, onOff(s.State))
+	}
+	return w.Flush()
+}
+
+func (a *app) printRaw(result []exchange) error {
+	if a.json {
+		if result == nil {
+			result = []exchange{}
+		}
+		return a.encode(result)
+	}
+	for _, e := range result {
+		if e.Query {
+			fmt.Fprintln(a.stdout, e.Reply)
+		}
+	}
+	return nil
+}
+
+func (a *app) printIdentification(id string) error {
+	if a.json {
+		return a.encode(api.Identification{Identification: id})
+	}
+	_, err := fmt.Fprintln(a.stdout, id)
+	return err
+}
+
+// table prints readings, errors are printed in place of values
+func (a *app) table(readings []reading) error {
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SECTION\tSTATE\tVOLTAGE [V]\tCURRENT [A]\tMODE\tTRIP")
+	for _, r := range readings {
+		if r.err != nil {
+			fmt.Fprintf(w, "%d\t-\terror: %v\t\t\t\n", r.number, r.err)
+			continue
+		}
+		s := r.section
+		fmt.Fprintf(w, "%d\t%s\t%s / %s\t%s / %s\t%s\t%s\n", r.number, onOff(s.State),
+			s.ActualVoltage, s.SetVoltage, s.ActualCurrent, s.SetCurrent, s.Mode, s.Trip)
+	}
+	return w.Flush()
+}
+
+func (a *app) encode(v interface{}) error {
+	enc := json.NewEncoder(a.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func toJSON(readings []reading) []sectionJSON {
+	sections := make([]sectionJSON, len(readings))
+	for i, r := range readings {
+		sections[i] = sectionJSON{Section: api.Section{Section: r.number, Trip: []string{}}}
+		if r.err != nil {
+			sections[i].Error = r.err.Error()
+			continue
+		}
+		section, err := api.NewSection(r.number, r.section)
+		if err != nil {
+			sections[i].Error = err.Error()
+			continue
+		}
+		sections[i].Section = section
+	}
+	return sections
+}
+
+func onOff(state bool) string {
+	if state {
+		return "ON"
+	}
+	return "OFF"
+}
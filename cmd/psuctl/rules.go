@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"psu/pkg/psu"
+	"psu/pkg/rules"
+)
+
+type firingJSON struct {
+	rules.Firing
+	Error string `json:"error,omitempty"`
+}
+
+// rulesCommand runs rules until interrupted, each fired rule is printed
+func rulesCommand(a *app, args []string) error {
+	flags := a.flags("rules")
+	path := flags.String("file", a.cfg.Rules, "path to rules file")
+	interval := flags.Duration("interval", 500*time.Millisecond, "time between readings")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if *path == "" {
+		return fmt.Errorf("%w: rules file required", errUsage)
+	}
+	parsed, err := rules.Load(*path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	access, err := a.connect()
+	if err != nil {
+		return err
+	}
+
+	opts := []rules.Option{
+		rules.WithAccess(access),
+		rules.WithRules(parsed...),
+		rules.WithInterval(*interval),
+		rules.WithHandler(a.printFiring),
+	}
+	if a.cfg.DataLog != nil {
+		d, err := a.cfg.DataLogger(access)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+		r := &recorder{dataLogger: d}
+		defer r.StopRecording()
+		opts = append(opts, rules.WithRecorder(r))
+	}
+	engine, err := rules.New(opts...)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	// Fired rules are printed, but log marks and failures are still logged
+	rules.SetLogger(psu.NewDefaultZap(zapcore.ErrorLevel))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := engine.Run(ctx); !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+func (a *app) printFiring(f rules.Firing, err error) {
+	if a.json {
+		line := firingJSON{Firing: f}
+		if err != nil {
+			line.Error = err.Error()
+		}
+		// JSON Lines, as rules run until interrupted
+		_ = json.NewEncoder(a.stdout).Encode(line)
+		return
+	}
+	result := "done"
+	if err != nil {
+		result = "FAILED " + err.Error()
+	}
+	fmt.Fprintf(a.stdout, "%s rule %q: %s\n", f.Time.Format("2006-01-02 15:04:05"), f.Rule, result)
+}
+
+// recorder runs DataLogger in background, like record button of GUI
+type recorder struct {
+	dataLogger *psu.DataLogger
+	mtx        sync.Mutex
+	cancel     context.CancelFunc
+	// done is closed, once DataLogger flushed and closed file
+	done chan struct{}
+}
+
+func (r *recorder) StartRecording() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.cancel != nil {
+		return psu.ErrRunning
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	r.cancel, r.done = cancel, done
+	go func() {
+		defer close(done)
+		if err := r.dataLogger.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			fmt.Fprintln(os.Stderr, "data logger stopped:", err)
+		}
+	}()
+	return nil
+}
+
+func (r *recorder) StopRecording() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+		<-r.done
+		r.cancel, r.done = nil, nil
+	}
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"psu/pkg/tui"
+)
+
+// tuiCommand shows full-screen dashboard, for machines reachable only over SSH
+func tuiCommand(a *app, args []string) error {
+	flags := a.flags("tui")
+	interval := flags.Duration("interval", 1*time.Second, "time between readings")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	sections, err := a.sections(flags.Args(), true)
+	if err != nil {
+		return err
+	}
+	access, err := a.connect()
+	if err != nil {
+		return err
+	}
+	d, err := tui.New(
+		tui.WithAccess(access),
+		tui.WithSections(sections...),
+		tui.WithInterval(*interval),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return d.Run(ctx)
+}
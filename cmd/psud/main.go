@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+// psud serves REST/JSON API of single PSU
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"psu/pkg/api"
+	"psu/pkg/proxy"
+	"psu/pkg/psu"
+)
+
+type config struct {
+	Listen   string `json:"listen"`
+	LogLevel string `json:"logLevel"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Sections []int  `json:"sections"`
+	// Proxy is optional, it shares PSU with clients talking raw line protocol
+	Proxy *proxyConfig `json:"proxy"`
+}
+
+type proxyConfig struct {
+	Listen string `json:"listen"`
+	// Policy is applied to clients, which don't match any of Clients
+	proxy.Policy
+	Clients []proxy.Client `json:"clients"`
+}
+
+var log = psu.NewDefaultZap(zapcore.InfoLevel)
+
+func main() {
+	path := flag.String("config", "config.json", "path to configuration file")
+	flag.Parse()
+
+	file, err := os.ReadFile(*path)
+	if err != nil {
+		panic(err)
+	}
+
+	cfg := config{
+		Listen:   ":8080",
+		LogLevel: "info",
+	}
+	if err := json.Unmarshal(file, &cfg); err != nil {
+		panic(err)
+	}
+	level, err := zapcore.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		panic(err)
+	}
+	log = psu.NewDefaultZap(level)
+
+	p, err := psu.New(
+		psu.WithSocketConn(cfg.Host, cfg.Port),
+		psu.WithReadWriteDeadline(100*time.Millisecond),
+		psu.WithRetries(3),
+		psu.WithLogLevel(cfg.LogLevel))
+	if err != nil {
+		panic(err)
+	}
+	api.SetLogger(log)
+
+	// Many clients may ask for the same section at once
+	cache, err := psu.NewCache(psu.CacheWithPSU(p))
+	if err != nil {
+		panic(err)
+	}
+
+	server, err := api.NewServer(
+		api.WithAccess(identifiedCache{Cache: cache, Identifier: p}),
+		api.WithSections(cfg.Sections...),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	if cfg.Proxy != nil {
+		go serveProxy(cfg.Proxy, p)
+	}
+
+	log.Info("Listening on ", cfg.Listen)
+	if err := http.ListenAndServe(cfg.Listen, server); err != nil {
+		panic(err)
+	}
+}
+
+// identifiedCache allows to identify instrument hidden behind psu.Cache
+type identifiedCache struct {
+	*psu.Cache
+	psu.Identifier
+}
+
+func serveProxy(cfg *proxyConfig, p *psu.PSU) {
+	proxy.SetLogger(log)
+	px, err := proxy.New(
+		proxy.WithPSU(p),
+		proxy.WithPolicy(cfg.Policy),
+		proxy.WithClients(cfg.Clients...),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	l, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		panic(err)
+	}
+	log.Info("Proxy listening on ", cfg.Listen)
+	if err := px.Serve(context.Background(), l); err != nil {
+		panic(err)
+	}
+}
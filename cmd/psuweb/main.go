@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+// psuweb serves dashboard of single PSU for web browsers
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"psu/pkg/psu"
+	"psu/pkg/web"
+)
+
+type config struct {
+	Listen   string `json:"listen"`
+	LogLevel string `json:"logLevel"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Sections []int  `json:"sections"`
+}
+
+var log = psu.NewDefaultZap(zapcore.InfoLevel)
+
+func main() {
+	path := flag.String("config", "config.json", "path to configuration file")
+	flag.Parse()
+
+	file, err := os.ReadFile(*path)
+	if err != nil {
+		panic(err)
+	}
+
+	cfg := config{
+		Listen:   ":8081",
+		LogLevel: "info",
+	}
+	if err := json.Unmarshal(file, &cfg); err != nil {
+		panic(err)
+	}
+	level, err := zapcore.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		panic(err)
+	}
+	log = psu.NewDefaultZap(level)
+
+	p, err := psu.New(
+		psu.WithSocketConn(cfg.Host, cfg.Port),
+		psu.WithReadWriteDeadline(100*time.Millisecond),
+		psu.WithRetries(3),
+		psu.WithLogLevel(cfg.LogLevel))
+	if err != nil {
+		panic(err)
+	}
+	web.SetLogger(log)
+
+	d, err := web.New(
+		web.WithPSU(p),
+		web.WithSections(cfg.Sections...),
+		web.WithInterval(1*time.Second),
+	)
+	if err != nil {
+		panic(err)
+	}
+	go d.Run(context.Background())
+
+	log.Info("Listening on ", cfg.Listen)
+	if err := http.ListenAndServe(cfg.Listen, d); err != nil {
+		panic(err)
+	}
+}
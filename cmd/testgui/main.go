@@ -58,6 +58,10 @@ func (a *access) Section(section int) (*psu.Section, error) {
 	a.sections[section].SetVoltage = fmt(setVoltage)
 	a.sections[section].ActualCurrent = fmt(actualCurrent)
 	a.sections[section].SetCurrent = fmt(setCurrent)
+	a.sections[section].Mode = psu.ModeCV
+	if actualCurrent > 0.9*setCurrent {
+		a.sections[section].Mode = psu.ModeCC
+	}
 
 	return a.sections[section], nil
 
@@ -81,7 +85,8 @@ func main() {
 	a := newAcccess(0, 1)
 	v, err := psu.NewView(
 		psu.ViewWithAccess(a),
-		psu.ViewWithSections(0, 1))
+		psu.ViewWithSections(0, 1),
+		psu.ViewWithEnergy(5*time.Second))
 	if err != nil {
 		panic(err)
 	}
@@ -104,7 +109,7 @@ func main() {
 	w := gui.NewWindow("CPX400DP")
 	w.SetContent(ctn)
 
-	w.Resize(fyne.NewSize(280, 160))
+	w.Resize(fyne.NewSize(280, 190))
 	w.ShowAndRun()
 
 }
@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"psu/pkg/psu"
+)
+
+// Client is psu.Access, which talks to Server instead of instrument
+type Client struct {
+	url    string
+	client *http.Client
+}
+
+// StatusError is returned, when Server replies with status code other than 2xx
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+var (
+	ErrNoURL = errors.New("no server url")
+)
+
+var (
+	_ psu.Access     = (*Client)(nil)
+	_ psu.Identifier = (*Client)(nil)
+)
+
+var modes = []psu.Mode{psu.ModeCV, psu.ModeCC, psu.ModeUnregulated}
+
+func NewClient(opts ...ClientOption) (*Client, error) {
+	c := &Client{
+		url:    "",
+		client: &http.Client{Timeout: 2 * time.Second},
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.verify(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) Section(section int) (*psu.Section, error) {
+	var s Section
+	if err := c.do(http.MethodGet, "sections/"+strconv.Itoa(section), nil, &s); err != nil {
+		return nil, err
+	}
+	return s.toPSU(), nil
+}
+
+func (c *Client) SetState(section int, value bool) (bool, error) {
+	var s State
+	if err := c.do(http.MethodPut, "sections/"+strconv.Itoa(section)+"/state", State{State: value}, &s); err != nil {
+		return false, err
+	}
+	return s.State, nil
+}
+
+func (c *Client) Identify() (string, error) {
+	var idn Identification
+	if err := c.do(http.MethodGet, "identification", nil, &idn); err != nil {
+		return "", err
+	}
+	return idn.Identification, nil
+}
+
+func (c *Client) Status() (Status, error) {
+	var s Status
+	err := c.do(http.MethodGet, "status", nil, &s)
+	return s, err
+}
+
+func (c *Client) do(method, path string, body, reply interface{}) error {
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, c.url+prefix+path, &reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e Error
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+			e.Error = http.StatusText(resp.StatusCode)
+		}
+		return &StatusError{Code: resp.StatusCode, Message: e.Error}
+	}
+	return json.NewDecoder(resp.Body).Decode(reply)
+}
+
+func (c *Client) verify() error {
+	if c.url == "" {
+		return ErrNoURL
+	}
+	return nil
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("server replied %d: %s", e.Code, e.Message)
+}
+
+func (s Section) toPSU() *psu.Section {
+	section := &psu.Section{
+		State:         s.State,
+		ActualVoltage: strconv.FormatFloat(s.ActualVoltage, 'f', 2, 64),
+		SetVoltage:    strconv.FormatFloat(s.SetVoltage, 'f', 2, 64),
+		ActualCurrent: strconv.FormatFloat(s.ActualCurrent, 'f', 3, 64),
+		SetCurrent:    strconv.FormatFloat(s.SetCurrent, 'f', 3, 64),
+		Mode:          psu.ModeUnknown,
+	}
+	for _, mode := range modes {
+		if mode.String() == s.Mode {
+			section.Mode = mode
+		}
+	}
+	for _, trip := range trips {
+		for _, name := range s.Trip {
+			if trip.String() == name {
+				section.Trip |= trip
+			}
+		}
+	}
+	return section
+}
+
+// normalizeURL drops trailing slash, so paths can be simply appended
+func normalizeURL(url string) string {
+	return strings.TrimSuffix(url, "/")
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package api_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/api"
+	"psu/pkg/psu"
+)
+
+type ClientTestSuite struct {
+	suite.Suite
+	mock *IdentifierMocker
+	http *httptest.Server
+}
+
+func TestClient(t *testing.T) {
+	suite.Run(t, new(ClientTestSuite))
+}
+
+func (t *ClientTestSuite) SetupTest() {
+	t.mock = new(IdentifierMocker)
+	s, err := api.NewServer(api.WithAccess(t.mock), api.WithSections(1, 2))
+	t.Require().Nil(err)
+	t.http = httptest.NewServer(s)
+}
+
+func (t *ClientTestSuite) TearDownTest() {
+	t.http.Close()
+}
+
+func (t *ClientTestSuite) client() *api.Client {
+	c, err := api.NewClient(api.ClientWithURL(t.http.URL + "/"))
+	t.Require().Nil(err)
+	return c
+}
+
+func (t *ClientTestSuite) TestNew() {
+	c, err := api.NewClient()
+	t.Nil(c)
+	t.ErrorIs(err, api.ErrNoURL)
+}
+
+func (t *ClientTestSuite) TestSection() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(&psu.Section{
+		State:         true,
+		ActualVoltage: "11.95",
+		SetVoltage:    "12.00",
+		ActualCurrent: "0.100",
+		SetCurrent:    "1.000",
+		Mode:          psu.ModeCC,
+		Trip:          psu.TripOverVoltage | psu.TripHard,
+	}, nil)
+
+	s, err := t.client().Section(1)
+	r.Nil(err)
+	r.Equal(&psu.Section{
+		State:         true,
+		ActualVoltage: "11.95",
+		SetVoltage:    "12.00",
+		ActualCurrent: "0.100",
+		SetCurrent:    "1.000",
+		Mode:          psu.ModeCC,
+		Trip:          psu.TripOverVoltage | psu.TripHard,
+	}, s)
+}
+
+func (t *ClientTestSuite) TestSetState() {
+	r := t.Require()
+	t.mock.On("SetState", 2, false).Return(false, nil).Once()
+
+	state, err := t.client().SetState(2, false)
+	r.Nil(err)
+	r.False(state)
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *ClientTestSuite) TestIdentify() {
+	r := t.Require()
+	t.mock.On("Identify").Return("CPX400DP", nil)
+
+	idn, err := t.client().Identify()
+	r.Nil(err)
+	r.Equal("CPX400DP", idn)
+}
+
+func (t *ClientTestSuite) TestErrors() {
+	r := t.Require()
+	var nilSection *psu.Section
+	t.mock.On("Section", 1).Return(nilSection, errors.New("connection refused"))
+
+	var statusErr *api.StatusError
+	_, err := t.client().Section(1)
+	r.ErrorAs(err, &statusErr)
+	r.Equal(http.StatusBadGateway, statusErr.Code)
+	r.Equal("connection refused", statusErr.Message)
+
+	_, err = t.client().Section(3)
+	r.ErrorAs(err, &statusErr)
+	r.Equal(http.StatusNotFound, statusErr.Code)
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package api
+
+import (
+	"net/http"
+
+	"psu/pkg/psu"
+)
+
+type Option func(*Server) error
+
+func WithPSU(p *psu.PSU) Option {
+	return func(s *Server) error {
+		return WithAccess(p)(s)
+	}
+}
+
+func WithAccess(a psu.Access) Option {
+	return func(s *Server) error {
+		s.access = a
+		return nil
+	}
+}
+
+func WithSections(sections ...int) Option {
+	return func(s *Server) error {
+		s.sections = append(s.sections, sections...)
+		return nil
+	}
+}
+
+type ClientOption func(*Client) error
+
+// ClientWithURL sets address of Server, e.g. http://192.168.1.10:8080
+func ClientWithURL(url string) ClientOption {
+	return func(c *Client) error {
+		c.url = normalizeURL(url)
+		return nil
+	}
+}
+
+// ClientWithHTTPClient replaces default http.Client, which times out after 2 seconds
+func ClientWithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) error {
+		c.client = client
+		return nil
+	}
+}
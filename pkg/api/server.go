@@ -0,0 +1,256 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+// Package api provides REST/JSON access to psu.Access.
+//
+// Endpoints:
+//
+//	GET /api/v1/identification      - Identification, 501 if Access can't identify instrument
+//	GET /api/v1/status              - Status of all sections
+//	GET /api/v1/sections            - []Section, all sections
+//	GET /api/v1/sections/{n}        - Section
+//	GET /api/v1/sections/{n}/state  - State
+//	PUT /api/v1/sections/{n}/state  - State in body, switches output and returns read back State
+//
+// Each error is returned as Error with status code:
+// 400 on malformed request, 404 on unknown section, 405 on unsupported method,
+// 502 when instrument failed to respond and 504 on instrument timeout.
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"psu/pkg/psu"
+)
+
+const prefix = "/api/v1/"
+
+// Server handles REST requests with psu.Access
+type Server struct {
+	access   psu.Access
+	sections []int
+	mux      *http.ServeMux
+}
+
+var (
+	ErrNoSection        = errors.New("no such section")
+	ErrNotImplemented   = errors.New("not supported by instrument")
+	ErrInvalidSection   = errors.New("invalid section number")
+	ErrInvalidBody      = errors.New("invalid request body")
+	ErrMethodNotAllowed = errors.New("method not allowed")
+)
+
+var (
+	_ http.Handler = (*Server)(nil)
+)
+
+func NewServer(opts ...Option) (*Server, error) {
+	s := &Server{
+		access:   nil,
+		sections: nil,
+		mux:      http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.verify(); err != nil {
+		return nil, err
+	}
+
+	s.mux.HandleFunc(prefix+"identification", s.identification)
+	s.mux.HandleFunc(prefix+"status", s.status)
+	s.mux.HandleFunc(prefix+"sections", s.allSections)
+	s.mux.HandleFunc(prefix+"sections/", s.section)
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) identification(w http.ResponseWriter, r *http.Request) {
+	if !s.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	identifier, ok := s.access.(psu.Identifier)
+	if !ok {
+		s.error(w, http.StatusNotImplemented, ErrNotImplemented)
+		return
+	}
+	idn, err := identifier.Identify()
+	if err != nil {
+		s.instrumentError(w, err)
+		return
+	}
+	s.reply(w, http.StatusOK, Identification{Identification: idn})
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	if !s.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	status := Status{OK: true, Sections: make([]SectionStatus, len(s.sections))}
+	for i, number := range s.sections {
+		status.Sections[i] = SectionStatus{Section: number, Trip: []string{}}
+		section, err := s.access.Section(number)
+		if err != nil {
+			status.OK = false
+			status.Sections[i].Error = err.Error()
+			continue
+		}
+		status.Sections[i].State = section.State
+		status.Sections[i].Mode = section.Mode.String()
+		status.Sections[i].Trip = tripNames(section.Trip)
+		if section.Trip != 0 {
+			status.OK = false
+		}
+	}
+	s.reply(w, http.StatusOK, status)
+}
+
+func (s *Server) allSections(w http.ResponseWriter, r *http.Request) {
+	if !s.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	sections := make([]Section, len(s.sections))
+	for i, number := range s.sections {
+		section, err := s.readSection(number)
+		if err != nil {
+			s.instrumentError(w, err)
+			return
+		}
+		sections[i] = section
+	}
+	s.reply(w, http.StatusOK, sections)
+}
+
+// section handles /sections/{n} and /sections/{n}/state
+func (s *Server) section(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, prefix+"sections/"), "/")
+	if len(path) > 2 || (len(path) == 2 && path[1] != "state") {
+		s.error(w, http.StatusNotFound, errors.New("no such endpoint"))
+		return
+	}
+
+	number, err := strconv.Atoi(path[0])
+	if err != nil {
+		s.error(w, http.StatusBadRequest, ErrInvalidSection)
+		return
+	}
+	if !s.handles(number) {
+		s.error(w, http.StatusNotFound, ErrNoSection)
+		return
+	}
+
+	if len(path) == 2 {
+		s.state(w, r, number)
+		return
+	}
+
+	if !s.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	section, err := s.readSection(number)
+	if err != nil {
+		s.instrumentError(w, err)
+		return
+	}
+	s.reply(w, http.StatusOK, section)
+}
+
+func (s *Server) state(w http.ResponseWriter, r *http.Request, number int) {
+	if r.Method == http.MethodGet {
+		section, err := s.access.Section(number)
+		if err != nil {
+			s.instrumentError(w, err)
+			return
+		}
+		s.reply(w, http.StatusOK, State{State: section.State})
+		return
+	}
+
+	if !s.allowMethod(w, r, http.MethodPut) {
+		return
+	}
+	var body State
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		s.error(w, http.StatusBadRequest, ErrInvalidBody)
+		return
+	}
+	state, err := s.access.SetState(number, body.State)
+	if err != nil {
+		s.instrumentError(w, err)
+		return
+	}
+	s.reply(w, http.StatusOK, State{State: state})
+}
+
+func (s *Server) readSection(number int) (Section, error) {
+	section, err := s.access.Section(number)
+	if err != nil {
+		return Section{}, err
+	}
+	return NewSection(number, section)
+}
+
+func (s *Server) handles(number int) bool {
+	for _, section := range s.sections {
+		if section == number {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	s.error(w, http.StatusMethodNotAllowed, ErrMethodNotAllowed)
+	return false
+}
+
+// instrumentError maps errors of Access to status code
+func (s *Server) instrumentError(w http.ResponseWriter, err error) {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		s.error(w, http.StatusGatewayTimeout, err)
+		return
+	}
+	s.error(w, http.StatusBadGateway, err)
+}
+
+func (s *Server) error(w http.ResponseWriter, code int, err error) {
+	log.Debug("replying with ", code, ": ", err)
+	s.reply(w, code, Error{Error: err.Error()})
+}
+
+func (s *Server) reply(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Error("error on writing response: ", err)
+	}
+}
+
+func (s *Server) verify() error {
+	if s.access == nil {
+		return psu.ErrNoAccess
+	}
+	if len(s.sections) == 0 {
+		return psu.ErrNoSection
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package api_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/api"
+	"psu/pkg/psu"
+)
+
+type ServerTestSuite struct {
+	suite.Suite
+	mock *AccessMocker
+}
+
+type AccessMocker struct {
+	mock.Mock
+}
+
+type IdentifierMocker struct {
+	AccessMocker
+}
+
+type timeoutError struct{}
+
+func TestServer(t *testing.T) {
+	suite.Run(t, new(ServerTestSuite))
+}
+
+func (t *ServerTestSuite) SetupTest() {
+	t.mock = new(AccessMocker)
+}
+
+func (t *ServerTestSuite) server(a psu.Access) *api.Server {
+	s, err := api.NewServer(api.WithAccess(a), api.WithSections(1, 2))
+	t.Require().Nil(err)
+	return s
+}
+
+func (t *ServerTestSuite) do(a psu.Access, method, url, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	t.server(a).ServeHTTP(rec, req)
+	t.Equal("application/json", rec.Header().Get("Content-Type"))
+	return rec
+}
+
+func (t *ServerTestSuite) decode(rec *httptest.ResponseRecorder, v interface{}) {
+	t.Require().Nil(json.Unmarshal(rec.Body.Bytes(), v), rec.Body.String())
+}
+
+func (t *ServerTestSuite) TestNew() {
+	{
+		s, err := api.NewServer()
+		t.Nil(s)
+		t.ErrorIs(err, psu.ErrNoAccess)
+	}
+	{
+		s, err := api.NewServer(api.WithAccess(t.mock))
+		t.Nil(s)
+		t.ErrorIs(err, psu.ErrNoSection)
+	}
+}
+
+func (t *ServerTestSuite) TestSection() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(&psu.Section{
+		State:         true,
+		ActualVoltage: "11.95",
+		SetVoltage:    "12.00",
+		ActualCurrent: "0.100",
+		SetCurrent:    "1.000",
+		Mode:          psu.ModeCV,
+		Trip:          psu.TripOverCurrent,
+	}, nil)
+
+	rec := t.do(t.mock, http.MethodGet, "/api/v1/sections/1", "")
+	r.Equal(http.StatusOK, rec.Code)
+	var s api.Section
+	t.decode(rec, &s)
+	r.Equal(api.Section{
+		Section:       1,
+		State:         true,
+		ActualVoltage: 11.95,
+		SetVoltage:    12,
+		ActualCurrent: 0.1,
+		SetCurrent:    1,
+		Mode:          "CV",
+		Trip:          []string{"OCP"},
+	}, s)
+}
+
+func (t *ServerTestSuite) TestSections() {
+	r := t.Require()
+	section := &psu.Section{ActualVoltage: "1", SetVoltage: "2", ActualCurrent: "3", SetCurrent: "4"}
+	t.mock.On("Section", 1).Return(section, nil)
+	t.mock.On("Section", 2).Return(section, nil)
+
+	rec := t.do(t.mock, http.MethodGet, "/api/v1/sections", "")
+	r.Equal(http.StatusOK, rec.Code)
+	var s []api.Section
+	t.decode(rec, &s)
+	r.Len(s, 2)
+	r.Equal(1, s[0].Section)
+	r.Equal(2, s[1].Section)
+	r.Equal(4.0, s[1].SetCurrent)
+	r.Equal([]string{}, s[1].Trip)
+}
+
+func (t *ServerTestSuite) TestSetState() {
+	r := t.Require()
+	t.mock.On("SetState", 2, true).Return(true, nil).Once()
+
+	rec := t.do(t.mock, http.MethodPut, "/api/v1/sections/2/state", `{"state": true}`)
+	r.Equal(http.StatusOK, rec.Code)
+	var s api.State
+	t.decode(rec, &s)
+	r.True(s.State)
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *ServerTestSuite) TestIdentification() {
+	r := t.Require()
+	{
+		rec := t.do(t.mock, http.MethodGet, "/api/v1/identification", "")
+		r.Equal(http.StatusNotImplemented, rec.Code)
+	}
+	{
+		m := new(IdentifierMocker)
+		m.On("Identify").Return("THURLBY THANDAR, CPX400DP, 1, 1", nil)
+		rec := t.do(m, http.MethodGet, "/api/v1/identification", "")
+		r.Equal(http.StatusOK, rec.Code)
+		var idn api.Identification
+		t.decode(rec, &idn)
+		r.Equal("THURLBY THANDAR, CPX400DP, 1, 1", idn.Identification)
+	}
+}
+
+func (t *ServerTestSuite) TestStatus() {
+	r := t.Require()
+	var nilSection *psu.Section
+	t.mock.On("Section", 1).Return(&psu.Section{State: true, Mode: psu.ModeCC}, nil)
+	t.mock.On("Section", 2).Return(nilSection, errors.New("connection refused"))
+
+	rec := t.do(t.mock, http.MethodGet, "/api/v1/status", "")
+	r.Equal(http.StatusOK, rec.Code)
+	var s api.Status
+	t.decode(rec, &s)
+	r.False(s.OK)
+	r.Equal([]api.SectionStatus{
+		{Section: 1, State: true, Mode: "CC", Trip: []string{}},
+		{Section: 2, Trip: []string{}, Error: "connection refused"},
+	}, s.Sections)
+}
+
+func (t *ServerTestSuite) TestErrors() {
+	var nilSection *psu.Section
+	t.mock.On("Section", 1).Return(nilSection, errors.New("connection refused"))
+	t.mock.On("Section", 2).Return(nilSection, timeoutError{})
+
+	args := []struct {
+		name, method, url, body string
+		code                    int
+	}{
+		{name: "unknown section", method: http.MethodGet, url: "/api/v1/sections/3", code: http.StatusNotFound},
+		{name: "invalid section", method: http.MethodGet, url: "/api/v1/sections/abc", code: http.StatusBadRequest},
+		{name: "unknown endpoint", method: http.MethodGet, url: "/api/v1/sections/1/voltage", code: http.StatusNotFound},
+		{name: "method", method: http.MethodPost, url: "/api/v1/sections/1", code: http.StatusMethodNotAllowed},
+		{name: "body", method: http.MethodPut, url: "/api/v1/sections/1/state", body: `{"on": true}`, code: http.StatusBadRequest},
+		{name: "instrument", method: http.MethodGet, url: "/api/v1/sections/1", code: http.StatusBadGateway},
+		{name: "timeout", method: http.MethodGet, url: "/api/v1/sections/2", code: http.StatusGatewayTimeout},
+	}
+	for _, arg := range args {
+		rec := t.do(t.mock, arg.method, arg.url, arg.body)
+		t.Equal(arg.code, rec.Code, arg.name)
+		var e api.Error
+		t.decode(rec, &e)
+		t.NotEmpty(e.Error, arg.name)
+	}
+}
+
+func (a *AccessMocker) Section(section int) (*psu.Section, error) {
+	args := a.Called(section)
+	return args.Get(0).(*psu.Section), args.Error(1)
+}
+
+func (a *AccessMocker) SetState(section int, value bool) (bool, error) {
+	args := a.Called(section, value)
+	return args.Bool(0), args.Error(1)
+}
+
+func (i *IdentifierMocker) Identify() (string, error) {
+	args := i.Called()
+	return args.String(0), args.Error(1)
+}
+
+func (timeoutError) Error() string {
+	return "i/o timeout"
+}
+
+func (timeoutError) Timeout() bool {
+	return true
+}
+
+func (timeoutError) Temporary() bool {
+	return true
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package api
+
+import (
+	"psu/pkg/psu"
+)
+
+// Section is JSON representation of psu.Section
+type Section struct {
+	Section       int     `json:"section"`
+	State         bool    `json:"state"`
+	ActualVoltage float64 `json:"actual_voltage"`
+	SetVoltage    float64 `json:"set_voltage"`
+	ActualCurrent float64 `json:"actual_current"`
+	SetCurrent    float64 `json:"set_current"`
+	// Mode is one of: "CV", "CC", "UNREG", "-"
+	Mode string `json:"mode"`
+	// Trip lists tripped protections: "OVP", "OCP", "HARD"
+	Trip []string `json:"trip"`
+}
+
+// State is body of request and response of switching output
+type State struct {
+	State bool `json:"state"`
+}
+
+// Identification of instrument
+type Identification struct {
+	Identification string `json:"identification"`
+}
+
+// Status is summary of all sections handled by server
+type Status struct {
+	// OK is true, if all sections were read and none of them tripped
+	OK       bool            `json:"ok"`
+	Sections []SectionStatus `json:"sections"`
+}
+
+// SectionStatus is summary of single section, Error is set if section couldn't be read
+type SectionStatus struct {
+	Section int      `json:"section"`
+	State   bool     `json:"state"`
+	Mode    string   `json:"mode"`
+	Trip    []string `json:"trip"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Error is body of each response with status code other than 2xx
+type Error struct {
+	Error string `json:"error"`
+}
+
+var trips = []psu.Trip{psu.TripOverVoltage, psu.TripOverCurrent, psu.TripHard}
+
+// NewSection converts psu.Section read from section number
+func NewSection(number int, s *psu.Section) (Section, error) {
+	values, err := s.Values()
+	if err != nil {
+		return Section{}, err
+	}
+	return Section{
+		Section:       number,
+		State:         s.State,
+		ActualVoltage: values.ActualVoltage,
+		SetVoltage:    values.SetVoltage,
+		ActualCurrent: values.ActualCurrent,
+		SetCurrent:    values.SetCurrent,
+		Mode:          s.Mode.String(),
+		Trip:          tripNames(s.Trip),
+	}, nil
+}
+
+func tripNames(t psu.Trip) []string {
+	names := []string{}
+	for _, trip := range trips {
+		if t.Has(trip) {
+			names = append(names, trip.String())
+		}
+	}
+	return names
+}
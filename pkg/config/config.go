@@ -0,0 +1,239 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+// Package config reads config.json shared by GUI and psuctl.
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"psu/pkg/api"
+	"psu/pkg/notify"
+	"psu/pkg/psu"
+)
+
+type Config struct {
+	Host string `json:"host"`
+	Port string `json:"port"`
+	// Remote is url of psud server, if set Host and Port are ignored
+	Remote   string   `json:"remote"`
+	Sections []int    `json:"sections"`
+	Energy   bool     `json:"energy"`
+	DataLog  *DataLog `json:"datalog"`
+	// PowerCycle enables power cycle buttons in GUI and sets defaults of psuctl cycle
+	PowerCycle *PowerCycle `json:"powerCycle"`
+	// AutoOff is time output is switched on for by timer button of GUI, zero disables timed outputs
+	AutoOff Duration `json:"autoOff"`
+	// Limits are software limits enforced by GUI
+	Limits []Limit `json:"limits"`
+	// Rules is path of rules file run by GUI and psuctl rules
+	Rules string `json:"rules"`
+	// Webhooks are notified about events seen by GUI
+	Webhooks []Webhook `json:"webhooks"`
+	// Alerts is number of alerts kept by GUI, zero disables alerts
+	Alerts int `json:"alerts"`
+	// Charts enables charts of readings in GUI
+	Charts *Charts `json:"charts"`
+	// Setpoints enables changing set-points from GUI, within Envelopes
+	Setpoints *Setpoints `json:"setpoints"`
+}
+
+type DataLog struct {
+	Dir      string   `json:"dir"`
+	Format   string   `json:"format"`
+	Interval Duration `json:"interval"`
+	Flush    Duration `json:"flush"`
+	MaxSize  int64    `json:"maxSize"`
+	MaxAge   Duration `json:"maxAge"`
+}
+
+type PowerCycle struct {
+	Off    Duration `json:"off"`
+	On     Duration `json:"on"`
+	Settle Duration `json:"settle"`
+	Cycles int      `json:"cycles"`
+}
+
+type Limit struct {
+	Section    int      `json:"section"`
+	MaxCurrent float64  `json:"maxCurrent"`
+	MinVoltage float64  `json:"minVoltage"`
+	MaxVoltage float64  `json:"maxVoltage"`
+	MaxPower   float64  `json:"maxPower"`
+	For        Duration `json:"for"`
+}
+
+type Charts struct {
+	Window Duration `json:"window"`
+	Dir    string   `json:"dir"`
+}
+
+type Setpoints struct {
+	Envelopes []Envelope `json:"envelopes"`
+}
+
+type Envelope struct {
+	Section        int     `json:"section"`
+	MaxVoltage     float64 `json:"maxVoltage"`
+	MaxCurrent     float64 `json:"maxCurrent"`
+	MaxOverVoltage float64 `json:"maxOverVoltage"`
+	MaxOverCurrent float64 `json:"maxOverCurrent"`
+}
+
+type Webhook struct {
+	URL         string   `json:"url"`
+	Template    string   `json:"template"`
+	ContentType string   `json:"contentType"`
+	Secret      string   `json:"secret"`
+	Events      []string `json:"events"`
+}
+
+// Duration is time.Duration, which can be written as "1s" in config
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var text string
+	if err := json.Unmarshal(b, &text); err != nil {
+		return err
+	}
+	value, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(value)
+	return nil
+}
+
+// Load reads Config from file
+func Load(path string) (Config, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg := Config{}
+	if err := json.Unmarshal(file, &cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Access connects to psud server if Remote is configured, otherwise directly to PSU with opts
+func (c Config) Access(opts ...psu.Option) (psu.Access, psu.Model, error) {
+	if c.Remote != "" {
+		client, err := api.NewClient(api.ClientWithURL(c.Remote))
+		return client, psu.CPX400DP, err
+	}
+	p, err := c.PSU(opts...)
+	if err != nil {
+		return nil, psu.Model{}, err
+	}
+	return p, p.Model(), nil
+}
+
+// PSU connects directly to PSU, even if Remote is configured. Opts are applied after default ones.
+func (c Config) PSU(opts ...psu.Option) (*psu.PSU, error) {
+	return psu.New(append([]psu.Option{
+		psu.WithSocketConn(c.Host, c.Port),
+		psu.WithReadWriteDeadline(100 * time.Millisecond),
+		psu.WithRetries(3),
+	}, opts...)...)
+}
+
+// DataLogger creates DataLogger of sections configured by DataLog
+func (c Config) DataLogger(access psu.Access) (*psu.DataLogger, error) {
+	if c.DataLog == nil {
+		return nil, psu.ErrNoDataLogger
+	}
+	opts := []psu.DataLogOption{
+		psu.DataLogWithAccess(access),
+		psu.DataLogWithSections(c.Sections...),
+		psu.DataLogWithDir(c.DataLog.Dir),
+		psu.DataLogWithFlushInterval(time.Duration(c.DataLog.Flush)),
+		psu.DataLogWithRotation(c.DataLog.MaxSize, time.Duration(c.DataLog.MaxAge)),
+	}
+	if c.DataLog.Format != "" {
+		format, err := psu.ParseFormat(c.DataLog.Format)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, psu.DataLogWithFormat(format))
+	}
+	if c.DataLog.Interval > 0 {
+		opts = append(opts, psu.DataLogWithInterval(time.Duration(c.DataLog.Interval)))
+	}
+	return psu.NewDataLogger(opts...)
+}
+
+// CycleOptions returns options of PowerCycle, zero values are left to defaults
+func (c Config) CycleOptions() []psu.CycleOption {
+	if c.PowerCycle == nil {
+		return nil
+	}
+	var opts []psu.CycleOption
+	if c.PowerCycle.Off > 0 {
+		opts = append(opts, psu.CycleWithOffTime(time.Duration(c.PowerCycle.Off)))
+	}
+	if c.PowerCycle.On > 0 {
+		opts = append(opts, psu.CycleWithOnTime(time.Duration(c.PowerCycle.On)))
+	}
+	if c.PowerCycle.Settle > 0 {
+		opts = append(opts, psu.CycleWithSettle(time.Duration(c.PowerCycle.Settle)))
+	}
+	if c.PowerCycle.Cycles > 0 {
+		opts = append(opts, psu.CycleWithCycles(c.PowerCycle.Cycles))
+	}
+	return opts
+}
+
+// SoftLimits returns Limits as psu.Limit
+func (c Config) SoftLimits() []psu.Limit {
+	var limits []psu.Limit
+	for _, l := range c.Limits {
+		limits = append(limits, psu.Limit{
+			Section:    l.Section,
+			MaxCurrent: l.MaxCurrent,
+			MinVoltage: l.MinVoltage,
+			MaxVoltage: l.MaxVoltage,
+			MaxPower:   l.MaxPower,
+			For:        time.Duration(l.For),
+		})
+	}
+	return limits
+}
+
+// Targets returns Webhooks as notify.Target
+func (c Config) Targets() []notify.Target {
+	var targets []notify.Target
+	for _, w := range c.Webhooks {
+		targets = append(targets, notify.Target{
+			URL:         w.URL,
+			Template:    w.Template,
+			ContentType: w.ContentType,
+			Secret:      w.Secret,
+			Events:      w.Events,
+		})
+	}
+	return targets
+}
+
+// Envelopes returns Setpoints.Envelopes as psu.Envelope
+func (c Config) Envelopes() []psu.Envelope {
+	if c.Setpoints == nil {
+		return nil
+	}
+	var envelopes []psu.Envelope
+	for _, e := range c.Setpoints.Envelopes {
+		envelopes = append(envelopes, psu.Envelope{
+			Section:        e.Section,
+			MaxVoltage:     e.MaxVoltage,
+			MaxCurrent:     e.MaxCurrent,
+			MaxOverVoltage: e.MaxOverVoltage,
+			MaxOverCurrent: e.MaxOverCurrent,
+		})
+	}
+	return envelopes
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/api"
+	"psu/pkg/config"
+	"psu/pkg/notify"
+	"psu/pkg/psu"
+)
+
+type ConfigTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestConfig(t *testing.T) {
+	suite.Run(t, new(ConfigTestSuite))
+}
+
+func (t *ConfigTestSuite) SetupTest() {
+	t.dir = t.T().TempDir()
+}
+
+func (t *ConfigTestSuite) write(content string) string {
+	path := filepath.Join(t.dir, "config.json")
+	t.Require().Nil(os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func (t *ConfigTestSuite) TestLoad() {
+	r := t.Require()
+	cfg, err := config.Load(t.write(`{
+		"host": "192.168.212.121",
+		"port": "9221",
+		"sections": [1, 2],
+		"energy": true,
+		"datalog": {"dir": "logs", "format": "jsonl", "interval": "500ms", "flush": "0s", "maxSize": 1024, "maxAge": "1h"}
+	}`))
+	r.Nil(err)
+	r.Equal("192.168.212.121", cfg.Host)
+	r.Equal("9221", cfg.Port)
+	r.Equal([]int{1, 2}, cfg.Sections)
+	r.True(cfg.Energy)
+	r.Equal(&config.DataLog{
+		Dir:      "logs",
+		Format:   "jsonl",
+		Interval: config.Duration(500 * time.Millisecond),
+		MaxSize:  1024,
+		MaxAge:   config.Duration(time.Hour),
+	}, cfg.DataLog)
+}
+
+func (t *ConfigTestSuite) TestLoadErrors() {
+	_, err := config.Load(filepath.Join(t.dir, "missing.json"))
+	t.ErrorIs(err, os.ErrNotExist)
+
+	_, err = config.Load(t.write(`{"datalog": {"interval": "soon"}}`))
+	t.NotNil(err)
+}
+
+func (t *ConfigTestSuite) TestAccess() {
+	r := t.Require()
+	access, model, err := config.Config{Remote: "http://localhost:8080"}.Access()
+	r.Nil(err)
+	r.IsType(&api.Client{}, access)
+	r.Equal(psu.CPX400DP, model)
+
+	access, model, err = config.Config{Host: "localhost", Port: "9221"}.Access()
+	r.Nil(err)
+	r.IsType(&psu.PSU{}, access)
+	r.Equal(psu.CPX400DP, model)
+}
+
+func (t *ConfigTestSuite) TestDataLogger() {
+	r := t.Require()
+	_, err := config.Config{}.DataLogger(nil)
+	r.ErrorIs(err, psu.ErrNoDataLogger)
+
+	cfg := config.Config{Sections: []int{1}, DataLog: &config.DataLog{Dir: t.dir, Format: "xml"}}
+	_, err = cfg.DataLogger(new(psu.Cache))
+	r.ErrorIs(err, psu.ErrUnknownFormat)
+
+	cfg.DataLog.Format = "csv"
+	d, err := cfg.DataLogger(new(psu.Cache))
+	r.Nil(err)
+	r.NotNil(d)
+}
+
+func (t *ConfigTestSuite) TestCycleOptions() {
+	r := t.Require()
+	r.Nil(config.Config{}.CycleOptions())
+
+	cfg, err := config.Load(t.write(`{"powerCycle": {"off": "2s", "on": "5s", "cycles": 100}}`))
+	r.Nil(err)
+	opts := cfg.CycleOptions()
+	r.Len(opts, 3)
+	c, err := psu.NewPowerCycle(append(opts, psu.CycleWithAccess(new(psu.Cache)), psu.CycleWithSections(1))...)
+	r.Nil(err)
+	r.Equal(100, c.Cycles())
+}
+
+func (t *ConfigTestSuite) TestSoftLimits() {
+	r := t.Require()
+	r.Nil(config.Config{}.SoftLimits())
+
+	cfg, err := config.Load(t.write(`{"limits": [{"section": 1, "maxCurrent": 1.5, "minVoltage": 4.5, "for": "200ms"}, {"section": 2, "maxPower": 20}]}`))
+	r.Nil(err)
+	r.Equal([]psu.Limit{
+		{Section: 1, MaxCurrent: 1.5, MinVoltage: 4.5, For: 200 * time.Millisecond},
+		{Section: 2, MaxPower: 20},
+	}, cfg.SoftLimits())
+}
+
+func (t *ConfigTestSuite) TestTargets() {
+	r := t.Require()
+	r.Nil(config.Config{}.Targets())
+
+	cfg, err := config.Load(t.write(`{"webhooks": [{"url": "http://chat/hook", "template": "{{.Event}}", "secret": "s", "events": ["trip", "limit"]}]}`))
+	r.Nil(err)
+	r.Equal([]notify.Target{
+		{URL: "http://chat/hook", Template: "{{.Event}}", Secret: "s", Events: []string{"trip", "limit"}},
+	}, cfg.Targets())
+	n, err := notify.New(notify.WithTargets(cfg.Targets()...))
+	r.Nil(err)
+	r.NotNil(n)
+}
+
+func (t *ConfigTestSuite) TestEnvelopes() {
+	r := t.Require()
+	r.Nil(config.Config{}.Envelopes())
+
+	cfg, err := config.Load(t.write(`{"setpoints": {"envelopes": [{"section": 1, "maxVoltage": 15, "maxOverCurrent": 3}]}}`))
+	r.Nil(err)
+	r.NotNil(cfg.Setpoints)
+	r.Equal([]psu.Envelope{
+		{Section: 1, MaxVoltage: 15, MaxOverCurrent: 3},
+	}, cfg.Envelopes())
+}
@@ -0,0 +1,241 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+// Package mqtt bridges PSU with MQTT broker.
+//
+// Readings of each section are published as JSON (api.Section), state as "ON" / "OFF".
+// Output is switched by publishing "ON" / "OFF" to command topic.
+// Home Assistant discovery messages are retained, so switches and sensors appear on their own.
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"psu/pkg/api"
+	"psu/pkg/psu"
+)
+
+// Client is connection to MQTT broker used by Bridge
+type Client interface {
+	Publish(topic string, retained bool, payload []byte) error
+	Subscribe(topic string, handler func(topic string, payload []byte)) error
+}
+
+const (
+	PayloadOn      = "ON"
+	PayloadOff     = "OFF"
+	PayloadOnline  = "online"
+	PayloadOffline = "offline"
+)
+
+type Bridge struct {
+	access    psu.Access
+	client    Client
+	sections  []int
+	interval  time.Duration
+	topics    Topics
+	discovery string
+	device    Device
+
+	trigger    chan struct{}
+	rediscover chan struct{}
+	states     map[int]bool
+	online     *bool
+}
+
+var (
+	ErrNoClient        = errors.New("no MQTT client")
+	ErrInvalidInterval = errors.New("interval must be positive")
+	ErrInvalidTopic    = errors.New("invalid topic")
+	ErrInvalidPayload  = errors.New("payload must be ON or OFF")
+)
+
+func New(opts ...Option) (*Bridge, error) {
+	b := &Bridge{
+		access:     nil,
+		client:     nil,
+		sections:   nil,
+		interval:   5 * time.Second,
+		topics:     DefaultTopics("cpx400dp"),
+		discovery:  "homeassistant",
+		device:     Device{ID: "cpx400dp", Name: "CPX400DP", Model: "CPX400DP", Manufacturer: "Aim-TTi"},
+		trigger:    make(chan struct{}, 1),
+		rediscover: make(chan struct{}, 1),
+		states:     make(map[int]bool),
+	}
+	for _, opt := range opts {
+		if err := opt(b); err != nil {
+			return nil, err
+		}
+	}
+	if err := b.verify(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Run publishes discovery messages, then publishes readings until ctx is done.
+// Bridge is announced as offline before return.
+func (b *Bridge) Run(ctx context.Context) error {
+	for _, number := range b.sections {
+		number := number
+		if err := b.client.Subscribe(b.topics.command(number), func(_ string, payload []byte) {
+			b.command(number, payload)
+		}); err != nil {
+			return err
+		}
+	}
+	if b.discovery != "" {
+		// Home Assistant forgets entities without retained config on restart, announce them again
+		if err := b.client.Subscribe(b.discovery+"/status", func(_ string, payload []byte) {
+			if string(payload) == PayloadOnline {
+				signal(b.rediscover)
+			}
+		}); err != nil {
+			return err
+		}
+		b.announce()
+	}
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		b.poll()
+		select {
+		case <-ctx.Done():
+			b.setAvailability(false)
+			return nil
+		case <-ticker.C:
+		case <-b.trigger:
+		case <-b.rediscover:
+			b.announce()
+			b.publishAvailability()
+		}
+	}
+}
+
+// Refresh publishes readings as soon as possible
+func (b *Bridge) Refresh() {
+	signal(b.trigger)
+}
+
+func (b *Bridge) poll() {
+	online := true
+	for _, number := range b.sections {
+		s, err := b.access.Section(number)
+		if err != nil {
+			log.Debug("error on reading section ", number, ": ", err)
+			online = false
+			continue
+		}
+		section, err := api.NewSection(number, s)
+		if err != nil {
+			log.Debug("error on parsing section ", number, ": ", err)
+			online = false
+			continue
+		}
+		b.publishSection(section)
+	}
+	b.setAvailability(online)
+}
+
+func (b *Bridge) publishSection(section api.Section) {
+	data, err := json.Marshal(section)
+	if err != nil {
+		log.Error("error on encoding section: ", err)
+		return
+	}
+	b.publish(b.topics.readings(section.Section), false, data)
+
+	// Retained state is published only on change, so broker isn't flooded
+	if state, ok := b.states[section.Section]; ok && state == section.State {
+		return
+	}
+	if b.publish(b.topics.state(section.Section), true, []byte(statePayload(section.State))) {
+		b.states[section.Section] = section.State
+	}
+}
+
+func (b *Bridge) setAvailability(online bool) {
+	if b.online != nil && *b.online == online {
+		return
+	}
+	b.online = &online
+	b.publishAvailability()
+}
+
+func (b *Bridge) publishAvailability() {
+	if b.online == nil {
+		return
+	}
+	payload := PayloadOffline
+	if *b.online {
+		payload = PayloadOnline
+	}
+	b.publish(b.topics.Availability, true, []byte(payload))
+}
+
+func (b *Bridge) command(number int, payload []byte) {
+	state, err := parseState(payload)
+	if err != nil {
+		log.Error("section ", number, ": ", err, ", got: ", string(payload))
+		return
+	}
+	if _, err := b.access.SetState(number, state); err != nil {
+		log.Error("error on switching section ", number, ": ", err)
+	}
+	b.Refresh()
+}
+
+// publish returns true on success
+func (b *Bridge) publish(topic string, retained bool, payload []byte) bool {
+	if err := b.client.Publish(topic, retained, payload); err != nil {
+		log.Error("error on publishing to ", topic, ": ", err)
+		return false
+	}
+	return true
+}
+
+func (b *Bridge) verify() error {
+	if b.access == nil {
+		return psu.ErrNoAccess
+	}
+	if b.client == nil {
+		return ErrNoClient
+	}
+	if len(b.sections) == 0 {
+		return psu.ErrNoSection
+	}
+	return b.topics.verify()
+}
+
+func parseState(payload []byte) (bool, error) {
+	switch strings.ToUpper(strings.TrimSpace(string(payload))) {
+	case PayloadOn, "1", "TRUE":
+		return true, nil
+	case PayloadOff, "0", "FALSE":
+		return false, nil
+	default:
+		return false, ErrInvalidPayload
+	}
+}
+
+func statePayload(state bool) string {
+	if state {
+		return PayloadOn
+	}
+	return PayloadOff
+}
+
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
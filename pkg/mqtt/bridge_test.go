@@ -0,0 +1,316 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package mqtt_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/api"
+	"psu/pkg/mqtt"
+	"psu/pkg/psu"
+)
+
+type BridgeTestSuite struct {
+	suite.Suite
+	mock   *AccessMocker
+	client *ClientMocker
+}
+
+type AccessMocker struct {
+	mock.Mock
+}
+
+// ClientMocker acts as broker, which keeps retained messages
+type ClientMocker struct {
+	mtx       sync.Mutex
+	published []message
+	retained  map[string]string
+	handlers  map[string]func(topic string, payload []byte)
+}
+
+type message struct {
+	topic    string
+	retained bool
+	payload  string
+}
+
+func TestBridge(t *testing.T) {
+	suite.Run(t, new(BridgeTestSuite))
+}
+
+func (t *BridgeTestSuite) SetupTest() {
+	t.mock = new(AccessMocker)
+	t.client = &ClientMocker{
+		retained: make(map[string]string),
+		handlers: make(map[string]func(topic string, payload []byte)),
+	}
+}
+
+// run starts bridge, returned function stops it
+func (t *BridgeTestSuite) run(opts ...mqtt.Option) func() {
+	opts = append([]mqtt.Option{mqtt.WithAccess(t.mock), mqtt.WithClient(t.client), mqtt.WithSections(1, 2)}, opts...)
+	b, err := mqtt.New(opts...)
+	t.Require().Nil(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t.Nil(b.Run(ctx))
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (t *BridgeTestSuite) expectSections() {
+	t.mock.On("Section", 1).Return(&psu.Section{
+		State:         true,
+		ActualVoltage: "12.00",
+		SetVoltage:    "12.00",
+		ActualCurrent: "0.500",
+		SetCurrent:    "1.000",
+		Mode:          psu.ModeCV,
+	}, nil)
+	t.mock.On("Section", 2).Return(&psu.Section{
+		ActualVoltage: "0.00",
+		SetVoltage:    "5.00",
+		ActualCurrent: "0.000",
+		SetCurrent:    "1.000",
+		Trip:          psu.TripOverCurrent,
+	}, nil)
+}
+
+func (t *BridgeTestSuite) TestNew() {
+	args := []struct {
+		name string
+		opts []mqtt.Option
+		err  error
+	}{
+		{name: "no access", opts: nil, err: psu.ErrNoAccess},
+		{name: "no client", opts: []mqtt.Option{mqtt.WithAccess(t.mock)}, err: mqtt.ErrNoClient},
+		{name: "no section", opts: []mqtt.Option{mqtt.WithAccess(t.mock), mqtt.WithClient(t.client)}, err: psu.ErrNoSection},
+		{name: "interval", opts: []mqtt.Option{mqtt.WithInterval(0)}, err: mqtt.ErrInvalidInterval},
+		{name: "topic", opts: []mqtt.Option{mqtt.WithTopics(mqtt.Topics{Readings: "psu"})}, err: mqtt.ErrInvalidTopic},
+		{
+			name: "all good",
+			opts: []mqtt.Option{mqtt.WithAccess(t.mock), mqtt.WithClient(t.client), mqtt.WithSections(1)},
+			err:  nil,
+		},
+	}
+	for _, arg := range args {
+		b, err := mqtt.New(arg.opts...)
+		t.ErrorIs(err, arg.err, arg.name)
+		t.Equal(arg.err == nil, b != nil, arg.name)
+	}
+}
+
+func (t *BridgeTestSuite) TestPublish() {
+	r := t.Require()
+	t.expectSections()
+	stop := t.run(mqtt.WithInterval(time.Hour))
+	r.Eventually(func() bool {
+		return t.client.retainedValue("cpx400dp/2/state") != ""
+	}, time.Second, time.Millisecond)
+
+	r.Equal("ON", t.client.retainedValue("cpx400dp/1/state"))
+	r.Equal("OFF", t.client.retainedValue("cpx400dp/2/state"))
+	r.Equal("online", t.client.retainedValue("cpx400dp/availability"))
+
+	var section api.Section
+	r.Nil(json.Unmarshal([]byte(t.client.last("cpx400dp/1")), &section))
+	r.Equal(api.Section{
+		Section:       1,
+		State:         true,
+		ActualVoltage: 12,
+		SetVoltage:    12,
+		ActualCurrent: 0.5,
+		SetCurrent:    1,
+		Mode:          "CV",
+		Trip:          []string{},
+	}, section)
+	r.Nil(json.Unmarshal([]byte(t.client.last("cpx400dp/2")), &section))
+	r.Equal([]string{"OCP"}, section.Trip)
+
+	stop()
+	r.Equal("offline", t.client.retainedValue("cpx400dp/availability"))
+}
+
+func (t *BridgeTestSuite) TestOffline() {
+	r := t.Require()
+	var nilSection *psu.Section
+	t.mock.On("Section", 1).Return(nilSection, errors.New("timeout"))
+	t.mock.On("Section", 2).Return(&psu.Section{
+		ActualVoltage: "1.00",
+		SetVoltage:    "1.00",
+		ActualCurrent: "0.000",
+		SetCurrent:    "1.000",
+	}, nil)
+	stop := t.run(mqtt.WithInterval(time.Hour))
+	defer stop()
+
+	r.Eventually(func() bool {
+		return t.client.retainedValue("cpx400dp/availability") == "offline"
+	}, time.Second, time.Millisecond)
+	r.Equal("", t.client.last("cpx400dp/1"))
+	r.NotEqual("", t.client.last("cpx400dp/2"))
+}
+
+func (t *BridgeTestSuite) TestCommand() {
+	r := t.Require()
+	t.expectSections()
+	t.mock.On("SetState", 2, true).Return(true, nil).Once()
+	stop := t.run(mqtt.WithInterval(time.Hour))
+	defer stop()
+
+	r.Eventually(func() bool {
+		return t.client.retainedValue("cpx400dp/2/state") != ""
+	}, time.Second, time.Millisecond)
+	polls := t.client.count("cpx400dp/1")
+
+	// Invalid payload is ignored
+	t.client.receive("cpx400dp/2/set", "maybe")
+	t.client.receive("cpx400dp/2/set", "on")
+	t.mock.AssertExpectations(t.T())
+
+	// Readings are published right after switching
+	r.Eventually(func() bool {
+		return t.client.count("cpx400dp/1") > polls
+	}, time.Second, time.Millisecond)
+}
+
+func (t *BridgeTestSuite) TestDiscovery() {
+	r := t.Require()
+	t.expectSections()
+	stop := t.run(
+		mqtt.WithInterval(time.Hour),
+		mqtt.WithTopics(mqtt.DefaultTopics("lab/psu")),
+		mqtt.WithDevice(mqtt.Device{ID: "bench1"}),
+	)
+	defer stop()
+
+	r.Eventually(func() bool {
+		return t.client.retainedValue("homeassistant/switch/bench1/output2/config") != ""
+	}, time.Second, time.Millisecond)
+
+	var entity map[string]interface{}
+	r.Nil(json.Unmarshal([]byte(t.client.retainedValue("homeassistant/switch/bench1/output1/config")), &entity))
+	r.Equal("bench1_output1", entity["unique_id"])
+	r.Equal("lab/psu/1/state", entity["state_topic"])
+	r.Equal("lab/psu/1/set", entity["command_topic"])
+	r.Equal("lab/psu/availability", entity["availability_topic"])
+	r.Equal("CPX400DP", entity["device"].(map[string]interface{})["name"])
+
+	r.Nil(json.Unmarshal([]byte(t.client.retainedValue("homeassistant/sensor/bench1/output1_voltage/config")), &entity))
+	r.Equal("lab/psu/1", entity["state_topic"])
+	r.Equal("V", entity["unit_of_measurement"])
+	r.Equal("{{ value_json.actual_voltage }}", entity["value_template"])
+
+	for _, topic := range []string{
+		"homeassistant/sensor/bench1/output2_current/config",
+		"homeassistant/sensor/bench1/output2_power/config",
+		"homeassistant/sensor/bench1/output2_mode/config",
+		"homeassistant/binary_sensor/bench1/output2_trip/config",
+	} {
+		r.NotEqual("", t.client.retainedValue(topic), topic)
+	}
+
+	// Home Assistant restarted
+	announced := t.client.count("homeassistant/switch/bench1/output1/config")
+	t.client.receive("homeassistant/status", "online")
+	r.Eventually(func() bool {
+		return t.client.count("homeassistant/switch/bench1/output1/config") > announced
+	}, time.Second, time.Millisecond)
+}
+
+func (t *BridgeTestSuite) TestNoDiscovery() {
+	r := t.Require()
+	t.expectSections()
+	stop := t.run(mqtt.WithInterval(time.Hour), mqtt.WithDiscovery(""))
+	r.Eventually(func() bool {
+		return t.client.retainedValue("cpx400dp/2/state") != ""
+	}, time.Second, time.Millisecond)
+	stop()
+
+	for topic := range t.client.retained {
+		r.NotContains(topic, "homeassistant")
+	}
+}
+
+func (a *AccessMocker) Section(number int) (*psu.Section, error) {
+	args := a.Called(number)
+	return args.Get(0).(*psu.Section), args.Error(1)
+}
+
+func (a *AccessMocker) SetState(number int, state bool) (bool, error) {
+	args := a.Called(number, state)
+	return args.Bool(0), args.Error(1)
+}
+
+func (c *ClientMocker) Publish(topic string, retained bool, payload []byte) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.published = append(c.published, message{topic: topic, retained: retained, payload: string(payload)})
+	if retained {
+		c.retained[topic] = string(payload)
+	}
+	return nil
+}
+
+func (c *ClientMocker) Subscribe(topic string, handler func(topic string, payload []byte)) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.handlers[topic] = handler
+	return nil
+}
+
+// receive delivers message to subscriber, like broker does
+func (c *ClientMocker) receive(topic, payload string) {
+	c.mtx.Lock()
+	handler := c.handlers[topic]
+	c.mtx.Unlock()
+	if handler != nil {
+		handler(topic, []byte(payload))
+	}
+}
+
+func (c *ClientMocker) retainedValue(topic string) string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.retained[topic]
+}
+
+// last returns payload of the latest message published to topic
+func (c *ClientMocker) last(topic string) string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for i := len(c.published) - 1; i >= 0; i-- {
+		if c.published[i].topic == topic {
+			return c.published[i].payload
+		}
+	}
+	return ""
+}
+
+func (c *ClientMocker) count(topic string) int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	n := 0
+	for _, msg := range c.published {
+		if msg.topic == topic {
+			n++
+		}
+	}
+	return n
+}
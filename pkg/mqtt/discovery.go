@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Device groups entities of PSU in Home Assistant, ID must be unique among devices
+type Device struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Model        string `json:"model"`
+	Manufacturer string `json:"manufacturer"`
+}
+
+// entity is Home Assistant discovery payload, see https://www.home-assistant.io/integrations/mqtt/#mqtt-discovery
+type entity struct {
+	Name              string       `json:"name"`
+	UniqueID          string       `json:"unique_id"`
+	StateTopic        string       `json:"state_topic"`
+	CommandTopic      string       `json:"command_topic,omitempty"`
+	PayloadOn         string       `json:"payload_on,omitempty"`
+	PayloadOff        string       `json:"payload_off,omitempty"`
+	ValueTemplate     string       `json:"value_template,omitempty"`
+	UnitOfMeasurement string       `json:"unit_of_measurement,omitempty"`
+	DeviceClass       string       `json:"device_class,omitempty"`
+	StateClass        string       `json:"state_class,omitempty"`
+	AvailabilityTopic string       `json:"availability_topic"`
+	Device            deviceConfig `json:"device"`
+}
+
+type deviceConfig struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Model        string   `json:"model,omitempty"`
+	Manufacturer string   `json:"manufacturer,omitempty"`
+}
+
+// announce publishes retained discovery message of each entity
+func (b *Bridge) announce() {
+	for _, number := range b.sections {
+		for component, entities := range b.entities(number) {
+			for id, e := range entities {
+				data, err := json.Marshal(e)
+				if err != nil {
+					log.Error("error on encoding discovery: ", err)
+					continue
+				}
+				topic := fmt.Sprintf("%s/%s/%s/%s/config", b.discovery, component, b.device.ID, id)
+				b.publish(topic, true, data)
+			}
+		}
+	}
+}
+
+// entities returns entities of section by component and object id
+func (b *Bridge) entities(number int) map[string]map[string]entity {
+	device := deviceConfig{
+		Identifiers:  []string{b.device.ID},
+		Name:         b.device.Name,
+		Model:        b.device.Model,
+		Manufacturer: b.device.Manufacturer,
+	}
+	output := fmt.Sprintf("output%d", number)
+	name := fmt.Sprintf("Output %d", number)
+	sensor := func(suffix, title, template, unit, class string) entity {
+		return entity{
+			Name:              name + " " + title,
+			UniqueID:          b.device.ID + "_" + output + "_" + suffix,
+			StateTopic:        b.topics.readings(number),
+			ValueTemplate:     template,
+			UnitOfMeasurement: unit,
+			DeviceClass:       class,
+			AvailabilityTopic: b.topics.Availability,
+			Device:            device,
+		}
+	}
+	measurement := func(e entity) entity {
+		e.StateClass = "measurement"
+		return e
+	}
+
+	trip := sensor("trip", "trip", "{{ 'ON' if value_json.trip | length > 0 else 'OFF' }}", "", "problem")
+	trip.PayloadOn, trip.PayloadOff = PayloadOn, PayloadOff
+
+	return map[string]map[string]entity{
+		"switch": {
+			output: {
+				Name:              name,
+				UniqueID:          b.device.ID + "_" + output,
+				StateTopic:        b.topics.state(number),
+				CommandTopic:      b.topics.command(number),
+				PayloadOn:         PayloadOn,
+				PayloadOff:        PayloadOff,
+				AvailabilityTopic: b.topics.Availability,
+				Device:            device,
+			},
+		},
+		"sensor": {
+			output + "_voltage": measurement(sensor("voltage", "voltage", "{{ value_json.actual_voltage }}", "V", "voltage")),
+			output + "_current": measurement(sensor("current", "current", "{{ value_json.actual_current }}", "A", "current")),
+			output + "_power": measurement(sensor("power", "power",
+				"{{ (value_json.actual_voltage * value_json.actual_current) | round(2) }}", "W", "power")),
+			output + "_mode": sensor("mode", "mode", "{{ value_json.mode }}", "", ""),
+		},
+		"binary_sensor": {
+			output + "_trip": trip,
+		},
+	}
+}
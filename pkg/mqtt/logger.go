@@ -0,0 +1,18 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package mqtt
+
+import (
+	"go.uber.org/zap/zapcore"
+	"psu/pkg/psu"
+)
+
+var log psu.Logger = psu.NewDefaultZap(zapcore.DebugLevel)
+
+// SetLogger replaces default logger of package
+func SetLogger(l psu.Logger) {
+	log = l
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package mqtt
+
+import (
+	"time"
+
+	"psu/pkg/psu"
+)
+
+type Option func(*Bridge) error
+
+func WithPSU(p *psu.PSU) Option {
+	return func(b *Bridge) error {
+		return WithAccess(p)(b)
+	}
+}
+
+func WithAccess(a psu.Access) Option {
+	return func(b *Bridge) error {
+		b.access = a
+		return nil
+	}
+}
+
+func WithClient(c Client) Option {
+	return func(b *Bridge) error {
+		b.client = c
+		return nil
+	}
+}
+
+func WithSections(sections ...int) Option {
+	return func(b *Bridge) error {
+		b.sections = append(b.sections, sections...)
+		return nil
+	}
+}
+
+// WithInterval sets how often readings are published
+func WithInterval(t time.Duration) Option {
+	return func(b *Bridge) error {
+		if t <= 0 {
+			return ErrInvalidInterval
+		}
+		b.interval = t
+		return nil
+	}
+}
+
+// WithTopics overrides DefaultTopics("cpx400dp")
+func WithTopics(t Topics) Option {
+	return func(b *Bridge) error {
+		if err := t.verify(); err != nil {
+			return err
+		}
+		b.topics = t
+		return nil
+	}
+}
+
+// WithDiscovery sets Home Assistant discovery prefix ("homeassistant" by default), empty prefix disables discovery
+func WithDiscovery(prefix string) Option {
+	return func(b *Bridge) error {
+		b.discovery = prefix
+		return nil
+	}
+}
+
+// WithDevice sets Device announced in discovery messages, empty fields are left default
+func WithDevice(d Device) Option {
+	return func(b *Bridge) error {
+		if d.ID != "" {
+			b.device.ID = d.ID
+		}
+		if d.Name != "" {
+			b.device.Name = d.Name
+		}
+		if d.Model != "" {
+			b.device.Model = d.Model
+		}
+		if d.Manufacturer != "" {
+			b.device.Manufacturer = d.Manufacturer
+		}
+		return nil
+	}
+}
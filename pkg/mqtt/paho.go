@@ -0,0 +1,50 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package mqtt
+
+import (
+	"errors"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// PahoClient adapts connected paho client to Client
+type PahoClient struct {
+	client  paho.Client
+	qos     byte
+	timeout time.Duration
+}
+
+var (
+	ErrTimeout = errors.New("timeout waiting for broker")
+)
+
+var (
+	_ Client = (*PahoClient)(nil)
+)
+
+// NewPahoClient uses QoS 1, each operation waits for broker at most timeout
+func NewPahoClient(client paho.Client, timeout time.Duration) *PahoClient {
+	return &PahoClient{client: client, qos: 1, timeout: timeout}
+}
+
+func (p *PahoClient) Publish(topic string, retained bool, payload []byte) error {
+	return p.wait(p.client.Publish(topic, p.qos, retained, payload))
+}
+
+func (p *PahoClient) Subscribe(topic string, handler func(topic string, payload []byte)) error {
+	return p.wait(p.client.Subscribe(topic, p.qos, func(_ paho.Client, msg paho.Message) {
+		handler(msg.Topic(), msg.Payload())
+	}))
+}
+
+func (p *PahoClient) wait(token paho.Token) error {
+	if !token.WaitTimeout(p.timeout) {
+		return ErrTimeout
+	}
+	return token.Error()
+}
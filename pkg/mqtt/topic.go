@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package mqtt
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SectionPlaceholder is replaced with section number in topics
+const SectionPlaceholder = "{section}"
+
+// Topics used by Bridge, Readings, State and Command must contain SectionPlaceholder
+type Topics struct {
+	// Readings receives api.Section as JSON
+	Readings string `json:"readings"`
+	// State receives retained "ON" or "OFF"
+	State string `json:"state"`
+	// Command is subscribed for "ON" or "OFF"
+	Command string `json:"command"`
+	// Availability receives retained "online" or "offline"
+	Availability string `json:"availability"`
+}
+
+// DefaultTopics returns topics below base, e.g. base/1, base/1/state, base/1/set and base/availability
+func DefaultTopics(base string) Topics {
+	return Topics{
+		Readings:     base + "/" + SectionPlaceholder,
+		State:        base + "/" + SectionPlaceholder + "/state",
+		Command:      base + "/" + SectionPlaceholder + "/set",
+		Availability: base + "/availability",
+	}
+}
+
+func (t Topics) readings(section int) string {
+	return expand(t.Readings, section)
+}
+
+func (t Topics) state(section int) string {
+	return expand(t.State, section)
+}
+
+func (t Topics) command(section int) string {
+	return expand(t.Command, section)
+}
+
+func (t Topics) verify() error {
+	for _, topic := range []string{t.Readings, t.State, t.Command} {
+		if !strings.Contains(topic, SectionPlaceholder) {
+			return ErrInvalidTopic
+		}
+	}
+	if t.Availability == "" {
+		return ErrInvalidTopic
+	}
+	return nil
+}
+
+func expand(topic string, section int) string {
+	return strings.ReplaceAll(topic, SectionPlaceholder, strconv.Itoa(section))
+}
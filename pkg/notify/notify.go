@@ -0,0 +1,293 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+// Package notify posts psu.Events to HTTP webhooks.
+//
+// Each Target gets its own queue, so slow or broken endpoint doesn't delay others.
+// Body is JSON Payload or result of Target.Template, signed with HMAC-SHA256 if Target.Secret is set.
+// Failed deliveries are retried with exponential backoff.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+
+	"psu/pkg/psu"
+)
+
+const (
+	// SignatureHeader carries "sha256=" and hex encoded HMAC of body
+	SignatureHeader = "X-PSU-Signature"
+	// EventHeader carries name of event, e.g. "trip"
+	EventHeader = "X-PSU-Event"
+)
+
+// Target is webhook endpoint
+type Target struct {
+	URL string
+	// Template is text/template executed with Payload, JSON Payload is posted if empty
+	Template string
+	// ContentType of body, application/json by default
+	ContentType string
+	// Secret is key of HMAC signature, body isn't signed if empty
+	Secret string
+	// Events are names of posted events, e.g. "trip", all events are posted if empty
+	Events []string
+}
+
+// Payload is JSON body of webhook and data of Target.Template
+type Payload struct {
+	Event   string    `json:"event"`
+	Time    time.Time `json:"time"`
+	Section int       `json:"section,omitempty"`
+	State   bool      `json:"state"`
+	Local   bool      `json:"local,omitempty"`
+	Trip    string    `json:"trip,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+	Values  *Values   `json:"values,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+type Values struct {
+	Voltage    float64 `json:"voltage"`
+	SetVoltage float64 `json:"setVoltage"`
+	Current    float64 `json:"current"`
+	SetCurrent float64 `json:"setCurrent"`
+}
+
+// Notifier delivers events passed to Notify, until Run returns
+type Notifier struct {
+	targets []*target
+	client  *http.Client
+	retries int
+	// backoff is delay before the first retry, doubled up to maxBackoff
+	backoff, maxBackoff time.Duration
+	queue               int
+}
+
+// target is Target ready to deliver
+type target struct {
+	Target
+	template *template.Template
+	events   map[psu.EventKind]bool
+	queue    chan Payload
+}
+
+var (
+	ErrNoTargets      = errors.New("no webhook targets")
+	ErrInvalidTarget  = errors.New("invalid webhook target")
+	ErrInvalidRetries = errors.New("retries can't be negative")
+	ErrInvalidBackoff = errors.New("invalid backoff")
+	ErrInvalidQueue   = errors.New("queue must be positive")
+	ErrDelivery       = errors.New("webhook delivery failed")
+)
+
+func New(opts ...Option) (*Notifier, error) {
+	n := &Notifier{
+		targets:    nil,
+		client:     &http.Client{Timeout: 5 * time.Second},
+		retries:    3,
+		backoff:    1 * time.Second,
+		maxBackoff: 30 * time.Second,
+		queue:      100,
+	}
+	for _, opt := range opts {
+		if err := opt(n); err != nil {
+			return nil, err
+		}
+	}
+	if err := n.verify(); err != nil {
+		return nil, err
+	}
+	// Queue size is known, once all options are applied
+	for _, t := range n.targets {
+		t.queue = make(chan Payload, n.queue)
+	}
+	return n, nil
+}
+
+// Notify queues event for each interested target, it doesn't block.
+// Event is dropped, if queue of target is full.
+func (n *Notifier) Notify(e psu.Event) {
+	p := NewPayload(e)
+	for _, t := range n.targets {
+		if t.events != nil && !t.events[e.Kind] {
+			continue
+		}
+		select {
+		case t.queue <- p:
+		default:
+			log.Error("webhook ", t.URL, ": queue full, event ", p.Event, " dropped")
+		}
+	}
+}
+
+// Run delivers queued events until ctx is done
+func (n *Notifier) Run(ctx context.Context) error {
+	wg := sync.WaitGroup{}
+	for _, t := range n.targets {
+		wg.Add(1)
+		go func(t *target) {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case p := <-t.queue:
+					if err := n.deliver(ctx, t, p); err != nil && ctx.Err() == nil {
+						log.Error("webhook ", t.URL, ", event ", p.Event, ": ", err)
+					}
+				}
+			}
+		}(t)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// NewPayload converts event
+func NewPayload(e psu.Event) Payload {
+	p := Payload{
+		Event:   e.Kind.String(),
+		Time:    e.Time,
+		Section: e.Section,
+		State:   e.State,
+		Local:   e.Local,
+		Reason:  e.Reason,
+	}
+	if e.Trip != 0 {
+		p.Trip = e.Trip.String()
+	}
+	if e.Values != nil {
+		p.Values = &Values{
+			Voltage:    e.Values.ActualVoltage,
+			SetVoltage: e.Values.SetVoltage,
+			Current:    e.Values.ActualCurrent,
+			SetCurrent: e.Values.SetCurrent,
+		}
+	}
+	if e.Err != nil {
+		p.Error = e.Err.Error()
+	}
+	return p
+}
+
+// Sign returns value of SignatureHeader for body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliver posts payload, retrying on network errors and 5xx or 429 responses
+func (n *Notifier) deliver(ctx context.Context, t *target, p Payload) error {
+	body, err := t.body(p)
+	if err != nil {
+		return err
+	}
+	backoff := n.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := n.post(ctx, t, p.Event, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= n.retries {
+			return err
+		}
+		log.Debug("webhook ", t.URL, ": ", err, ", retry in ", backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > n.maxBackoff {
+			backoff = n.maxBackoff
+		}
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, t *target, event string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", t.ContentType)
+	req.Header.Set(EventHeader, event)
+	if t.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(t.Secret, body))
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("%w: %s", ErrDelivery, resp.Status)
+	}
+	return false, nil
+}
+
+func (t *target) body(p Payload) ([]byte, error) {
+	if t.template == nil {
+		return json.Marshal(p)
+	}
+	buf := bytes.Buffer{}
+	if err := t.template.Execute(&buf, p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func compile(t Target) (*target, error) {
+	if t.URL == "" {
+		return nil, fmt.Errorf("%w: no url", ErrInvalidTarget)
+	}
+	compiled := &target{Target: t}
+	if compiled.ContentType == "" {
+		compiled.ContentType = "application/json"
+	}
+	if t.Template != "" {
+		tmpl, err := template.New(t.URL).Funcs(template.FuncMap{"json": toJSON}).Parse(t.Template)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTarget, err)
+		}
+		compiled.template = tmpl
+	}
+	if len(t.Events) > 0 {
+		compiled.events = make(map[psu.EventKind]bool)
+		for _, name := range t.Events {
+			kind, err := psu.ParseEventKind(name)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidTarget, name)
+			}
+			compiled.events[kind] = true
+		}
+	}
+	return compiled, nil
+}
+
+// toJSON is available in templates as json, e.g. {"text": {{json .Reason}}}
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func (n *Notifier) verify() error {
+	if len(n.targets) == 0 {
+		return ErrNoTargets
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/notify"
+	"psu/pkg/psu"
+)
+
+type NotifyTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+	// status are returned by server in order, the last one is repeated
+	status   []int
+	mtx      sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	received chan struct{}
+}
+
+func TestNotify(t *testing.T) {
+	suite.Run(t, new(NotifyTestSuite))
+}
+
+func (t *NotifyTestSuite) SetupTest() {
+	t.status = []int{http.StatusOK}
+	t.requests, t.bodies = nil, nil
+	t.received = make(chan struct{}, 10)
+	t.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		t.mtx.Lock()
+		t.requests = append(t.requests, r)
+		t.bodies = append(t.bodies, body)
+		status := t.status[0]
+		if len(t.status) > 1 {
+			t.status = t.status[1:]
+		}
+		t.mtx.Unlock()
+		w.WriteHeader(status)
+		t.received <- struct{}{}
+	}))
+}
+
+func (t *NotifyTestSuite) TearDownTest() {
+	t.server.Close()
+}
+
+func (t *NotifyTestSuite) run(opts ...notify.Option) (*notify.Notifier, context.CancelFunc) {
+	n, err := notify.New(append([]notify.Option{notify.WithBackoff(time.Millisecond, 5*time.Millisecond)}, opts...)...)
+	t.Require().Nil(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_ = n.Run(ctx)
+	}()
+	return n, cancel
+}
+
+func (t *NotifyTestSuite) wait(count int) {
+	for i := 0; i < count; i++ {
+		select {
+		case <-t.received:
+		case <-time.After(time.Second):
+			t.FailNow("webhook not received")
+		}
+	}
+}
+
+func (t *NotifyTestSuite) TestNew() {
+	args := []struct {
+		name string
+		opts []notify.Option
+		err  error
+	}{
+		{name: "no targets", opts: nil, err: notify.ErrNoTargets},
+		{name: "no url", opts: []notify.Option{notify.WithTargets(notify.Target{})}, err: notify.ErrInvalidTarget},
+		{name: "bad template", opts: []notify.Option{notify.WithTargets(notify.Target{URL: "http://x", Template: "{{.Event"})}, err: notify.ErrInvalidTarget},
+		{name: "unknown event", opts: []notify.Option{notify.WithTargets(notify.Target{URL: "http://x", Events: []string{"reboot"}})}, err: notify.ErrInvalidTarget},
+		{name: "retries", opts: []notify.Option{notify.WithRetries(-1)}, err: notify.ErrInvalidRetries},
+		{name: "backoff", opts: []notify.Option{notify.WithBackoff(time.Second, time.Millisecond)}, err: notify.ErrInvalidBackoff},
+		{name: "queue", opts: []notify.Option{notify.WithQueue(0)}, err: notify.ErrInvalidQueue},
+	}
+	for _, arg := range args {
+		n, err := notify.New(arg.opts...)
+		t.Nil(n, arg.name)
+		t.ErrorIs(err, arg.err, arg.name)
+	}
+}
+
+func (t *NotifyTestSuite) TestPayload() {
+	r := t.Require()
+	n, cancel := t.run(notify.WithTargets(notify.Target{URL: t.server.URL, Secret: "secret"}))
+	defer cancel()
+
+	now := time.Now().Truncate(time.Second)
+	n.Notify(psu.Event{
+		Kind:    psu.EventTripped,
+		Time:    now,
+		Section: 2,
+		Trip:    psu.TripOverVoltage,
+		Reason:  "OVP",
+		Values:  &psu.Values{ActualVoltage: 12.5, SetVoltage: 12},
+	})
+	t.wait(1)
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	req, body := t.requests[0], t.bodies[0]
+	r.Equal(http.MethodPost, req.Method)
+	r.Equal("application/json", req.Header.Get("Content-Type"))
+	r.Equal("trip", req.Header.Get(notify.EventHeader))
+	r.Equal(notify.Sign("secret", body), req.Header.Get(notify.SignatureHeader))
+	r.NotEqual(notify.Sign("other", body), req.Header.Get(notify.SignatureHeader))
+
+	var p notify.Payload
+	r.Nil(json.Unmarshal(body, &p))
+	r.Equal("trip", p.Event)
+	r.True(now.Equal(p.Time))
+	r.Equal(2, p.Section)
+	r.Equal("OVP", p.Trip)
+	r.NotNil(p.Values)
+	r.Equal(12.5, p.Values.Voltage)
+}
+
+func (t *NotifyTestSuite) TestTemplate() {
+	r := t.Require()
+	n, cancel := t.run(notify.WithTargets(notify.Target{
+		URL:         t.server.URL,
+		Template:    `{"text": {{json (printf "CH%d %s: %s" .Section .Event .Error)}}}`,
+		ContentType: "application/vnd.chat+json",
+	}))
+	defer cancel()
+
+	n.Notify(psu.Event{Kind: psu.EventLimit, Section: 1, Err: errors.New("not \"switched\"")})
+	t.wait(1)
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	r.Equal("application/vnd.chat+json", t.requests[0].Header.Get("Content-Type"))
+	r.Empty(t.requests[0].Header.Get(notify.SignatureHeader))
+	var msg struct {
+		Text string `json:"text"`
+	}
+	r.Nil(json.Unmarshal(t.bodies[0], &msg))
+	r.Equal(`CH1 limit: not "switched"`, msg.Text)
+}
+
+func (t *NotifyTestSuite) TestRetry() {
+	t.status = []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK}
+	n, cancel := t.run(notify.WithTargets(notify.Target{URL: t.server.URL}))
+	defer cancel()
+
+	n.Notify(psu.Event{Kind: psu.EventConnectionLost})
+	t.wait(3)
+	select {
+	case <-t.received:
+		t.Fail("delivered event posted again")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func (t *NotifyTestSuite) TestGiveUp() {
+	args := []struct {
+		name    string
+		status  int
+		retries int
+		posts   int
+	}{
+		{name: "retries exhausted", status: http.StatusInternalServerError, retries: 2, posts: 3},
+		{name: "client error isn't retried", status: http.StatusBadRequest, retries: 2, posts: 1},
+	}
+	for _, arg := range args {
+		t.status = []int{arg.status}
+		n, cancel := t.run(notify.WithTargets(notify.Target{URL: t.server.URL}), notify.WithRetries(arg.retries))
+		n.Notify(psu.Event{Kind: psu.EventSwitched})
+		t.wait(arg.posts)
+		select {
+		case <-t.received:
+			t.Fail("unexpected post", arg.name)
+		case <-time.After(30 * time.Millisecond):
+		}
+		cancel()
+	}
+}
+
+func (t *NotifyTestSuite) TestFilter() {
+	r := t.Require()
+	n, cancel := t.run(notify.WithTargets(notify.Target{URL: t.server.URL, Events: []string{"trip", "connection_lost"}}))
+	defer cancel()
+
+	n.Notify(psu.Event{Kind: psu.EventSwitched})
+	n.Notify(psu.Event{Kind: psu.EventConnectionLost})
+	t.wait(1)
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	r.Len(t.requests, 1)
+	r.Equal("connection_lost", t.requests[0].Header.Get(notify.EventHeader))
+}
+
+func (t *NotifyTestSuite) TestWatcher() {
+	access := &fakeAccess{section: &psu.Section{State: false}}
+	n, cancel := t.run(notify.WithTargets(notify.Target{URL: t.server.URL}))
+	defer cancel()
+	w, err := psu.NewWatcher(psu.WatcherWithAccess(access), psu.WatcherWithHandler(n.Notify))
+	t.Require().Nil(err)
+
+	_, _ = w.Section(1)
+	access.section = &psu.Section{State: true}
+	_, _ = w.Section(1)
+	t.wait(1)
+
+	var p notify.Payload
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.Require().Nil(json.Unmarshal(t.bodies[0], &p))
+	t.Equal("switched", p.Event)
+	t.True(p.State)
+}
+
+type fakeAccess struct {
+	section *psu.Section
+}
+
+func (f *fakeAccess) Section(int) (*psu.Section, error) {
+	return f.section, nil
+}
+
+func (f *fakeAccess) SetState(_ int, value bool) (bool, error) {
+	return value, nil
+}
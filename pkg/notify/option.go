@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package notify
+
+import (
+	"net/http"
+	"time"
+)
+
+type Option func(*Notifier) error
+
+// WithTargets adds webhook endpoints, templates and event names are verified at once
+func WithTargets(targets ...Target) Option {
+	return func(n *Notifier) error {
+		for _, t := range targets {
+			compiled, err := compile(t)
+			if err != nil {
+				return err
+			}
+			n.targets = append(n.targets, compiled)
+		}
+		return nil
+	}
+}
+
+// WithHTTPClient sets client used to post events
+func WithHTTPClient(c *http.Client) Option {
+	return func(n *Notifier) error {
+		n.client = c
+		return nil
+	}
+}
+
+// WithRetries sets how many times failed delivery is retried, 3 by default
+func WithRetries(retries int) Option {
+	return func(n *Notifier) error {
+		if retries < 0 {
+			return ErrInvalidRetries
+		}
+		n.retries = retries
+		return nil
+	}
+}
+
+// WithBackoff sets delay before the first retry, doubled by each next one up to max. 1 s and 30 s by default.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(n *Notifier) error {
+		if initial <= 0 || max < initial {
+			return ErrInvalidBackoff
+		}
+		n.backoff, n.maxBackoff = initial, max
+		return nil
+	}
+}
+
+// WithQueue sets how many events may wait for delivery to each target, 100 by default
+func WithQueue(size int) Option {
+	return func(n *Notifier) error {
+		if size <= 0 {
+			return ErrInvalidQueue
+		}
+		n.queue = size
+		return nil
+	}
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package proxy
+
+import (
+	"psu/pkg/psu"
+)
+
+type Option func(*Proxy) error
+
+func WithPSU(p *psu.PSU) Option {
+	return func(proxy *Proxy) error {
+		return WithUpstream(p)(proxy)
+	}
+}
+
+func WithUpstream(u Upstream) Option {
+	return func(proxy *Proxy) error {
+		proxy.upstream = u
+		return nil
+	}
+}
+
+// WithPolicy sets Policy of clients, which don't match any of WithClients
+func WithPolicy(p Policy) Option {
+	return func(proxy *Proxy) error {
+		proxy.policy = p
+		return nil
+	}
+}
+
+// WithClients sets Policy per client address, the first matching is used
+func WithClients(clients ...Client) Option {
+	return func(proxy *Proxy) error {
+		for _, client := range clients {
+			if err := client.parse(); err != nil {
+				return err
+			}
+			proxy.clients = append(proxy.clients, client)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package proxy
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"psu/pkg/psu"
+)
+
+// Policy decides, which commands client may send.
+// Allow and Deny are case-insensitive prefixes of command header, e.g. "OP" matches "OP1 1", "OP2?" and "OPALL 0".
+type Policy struct {
+	// ReadOnly client may send only queries
+	ReadOnly bool `json:"readOnly"`
+	// Allow, if not empty, lists the only commands client may send
+	Allow []string `json:"allow"`
+	// Deny takes precedence over Allow
+	Deny []string `json:"deny"`
+}
+
+// Client binds Policy to clients connecting from Addr: single IP or CIDR network
+type Client struct {
+	Addr string `json:"addr"`
+	Policy
+	network *net.IPNet
+}
+
+var (
+	ErrInvalidAddr = errors.New("invalid client address")
+)
+
+// Allowed returns true, if each of ';' separated commands is allowed
+func (p Policy) Allowed(line string) bool {
+	for _, cmd := range strings.Split(line, ";") {
+		cmd = strings.TrimSpace(cmd)
+		if cmd == "" {
+			continue
+		}
+		if !p.allowed(cmd) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p Policy) allowed(cmd string) bool {
+	if p.ReadOnly && !psu.IsQuery(cmd) {
+		return false
+	}
+	header := strings.ToUpper(strings.Fields(cmd)[0])
+	if matches(header, p.Deny) {
+		return false
+	}
+	return len(p.Allow) == 0 || matches(header, p.Allow)
+}
+
+func matches(header string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(header, strings.ToUpper(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) parse() error {
+	if _, network, err := net.ParseCIDR(c.Addr); err == nil {
+		c.network = network
+		return nil
+	}
+	ip := net.ParseIP(c.Addr)
+	if ip == nil {
+		return ErrInvalidAddr
+	}
+	bits := 8 * net.IPv6len
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = 8 * net.IPv4len
+	}
+	c.network = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	return nil
+}
+
+func (c *Client) contains(ip net.IP) bool {
+	return c.network.Contains(ip)
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+// Package proxy shares single instrument connection between many clients talking raw line protocol.
+// Each line sent by client is forwarded to instrument as single exchange, so replies can't be mixed.
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+
+	"psu/pkg/psu"
+)
+
+// Upstream exchanges raw commands with instrument, implemented by psu.PSU
+type Upstream interface {
+	Raw(cmd string) (string, error)
+}
+
+type Proxy struct {
+	upstream Upstream
+	policy   Policy
+	clients  []Client
+}
+
+// denied is replied to query, which is not allowed, so client doesn't wait for timeout
+const denied = "ERR command not allowed"
+
+var (
+	ErrNoUpstream = errors.New("no upstream")
+)
+
+var (
+	_ Upstream = (*psu.PSU)(nil)
+)
+
+func New(opts ...Option) (*Proxy, error) {
+	p := &Proxy{
+		upstream: nil,
+		policy:   Policy{},
+		clients:  nil,
+	}
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.verify(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Serve accepts clients on l, until ctx is done
+func (p *Proxy) Serve(ctx context.Context, l net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		if err := l.Close(); err != nil {
+			log.Error("error on closing listener: ", err)
+		}
+	}()
+
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.handle(ctx, conn)
+		}()
+	}
+}
+
+// Policy returns Policy applied to client connecting from addr
+func (p *Proxy) Policy(addr net.Addr) Policy {
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ip = a.IP
+	default:
+		host, _, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			return p.policy
+		}
+		ip = net.ParseIP(host)
+	}
+	for _, client := range p.clients {
+		if ip != nil && client.contains(ip) {
+			return client.Policy
+		}
+	}
+	return p.policy
+}
+
+func (p *Proxy) handle(ctx context.Context, conn net.Conn) {
+	addr := conn.RemoteAddr()
+	policy := p.Policy(addr)
+	log.Debug("client connected: ", addr)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		_ = conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		reply, err := p.exchange(policy, line)
+		if err != nil {
+			// Client won't get any reply, just like instrument didn't respond
+			log.Error("error on forwarding ", line, " from ", addr, ": ", err)
+			continue
+		}
+		if !psu.IsQuery(line) {
+			continue
+		}
+		if _, err := conn.Write([]byte(reply + "\r\n")); err != nil {
+			log.Error("error on writing to ", addr, ": ", err)
+			return
+		}
+	}
+	log.Debug("client disconnected: ", addr)
+}
+
+// exchange forwards line to upstream, if policy allows
+func (p *Proxy) exchange(policy Policy, line string) (string, error) {
+	if !policy.Allowed(line) {
+		log.Debug("not allowed command: ", line)
+		return denied, nil
+	}
+	return p.upstream.Raw(line)
+}
+
+func (p *Proxy) verify() error {
+	if p.upstream == nil {
+		return ErrNoUpstream
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package proxy_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/proxy"
+)
+
+type ProxyTestSuite struct {
+	suite.Suite
+	mock *UpstreamMocker
+}
+
+type UpstreamMocker struct {
+	mock.Mock
+}
+
+func TestProxy(t *testing.T) {
+	suite.Run(t, new(ProxyTestSuite))
+}
+
+func (t *ProxyTestSuite) SetupTest() {
+	t.mock = new(UpstreamMocker)
+}
+
+// serve starts proxy and returns its address
+func (t *ProxyTestSuite) serve(opts ...proxy.Option) string {
+	r := t.Require()
+	p, err := proxy.New(append([]proxy.Option{proxy.WithUpstream(t.mock)}, opts...)...)
+	r.Nil(err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	r.Nil(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t.Nil(p.Serve(ctx, l))
+	}()
+	t.T().Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return l.Addr().String()
+}
+
+func (t *ProxyTestSuite) dial(addr string) (net.Conn, *bufio.Reader) {
+	conn, err := net.Dial("tcp", addr)
+	t.Require().Nil(err)
+	t.Require().Nil(conn.SetDeadline(time.Now().Add(time.Second)))
+	t.T().Cleanup(func() { _ = conn.Close() })
+	return conn, bufio.NewReader(conn)
+}
+
+func (t *ProxyTestSuite) TestNew() {
+	p, err := proxy.New()
+	t.Nil(p)
+	t.ErrorIs(err, proxy.ErrNoUpstream)
+
+	p, err = proxy.New(proxy.WithUpstream(t.mock), proxy.WithClients(proxy.Client{Addr: "localhost"}))
+	t.Nil(p)
+	t.ErrorIs(err, proxy.ErrInvalidAddr)
+}
+
+func (t *ProxyTestSuite) TestForward() {
+	r := t.Require()
+	t.mock.On("Raw", "OP1 1").Return("", nil).Once()
+	t.mock.On("Raw", "V1?").Return("V1 12.00", nil).Once()
+	addr := t.serve()
+
+	conn, reader := t.dial(addr)
+	_, err := conn.Write([]byte("OP1 1\r\nV1?\r\n"))
+	r.Nil(err)
+	reply, err := reader.ReadString('\n')
+	r.Nil(err)
+	r.Equal("V1 12.00\r\n", reply)
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *ProxyTestSuite) TestManyClients() {
+	const clients = 10
+	for i := 0; i < clients; i++ {
+		cmd := "V" + strconv.Itoa(i) + "?"
+		t.mock.On("Raw", cmd).Return(strings.TrimSuffix(cmd, "?")+" 1.00", nil)
+	}
+	addr := t.serve()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conn, reader := t.dial(addr)
+			for j := 0; j < 5; j++ {
+				_, err := conn.Write([]byte("V" + strconv.Itoa(i) + "?\n"))
+				t.Nil(err)
+				reply, err := reader.ReadString('\n')
+				t.Nil(err)
+				t.Equal("V"+strconv.Itoa(i)+" 1.00\r\n", reply)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (t *ProxyTestSuite) TestClientPolicy() {
+	r := t.Require()
+	t.mock.On("Raw", "OP1?").Return("0", nil).Once()
+	addr := t.serve(
+		// Others may only identify instrument
+		proxy.WithPolicy(proxy.Policy{Allow: []string{"*IDN"}}),
+		proxy.WithClients(proxy.Client{Addr: "127.0.0.0/8", Policy: proxy.Policy{ReadOnly: true}}),
+	)
+
+	conn, reader := t.dial(addr)
+	// Write is silently dropped
+	_, err := conn.Write([]byte("OP1 1\r\nOP1?\r\nOP1 1;OP1?\r\n"))
+	r.Nil(err)
+	reply, err := reader.ReadString('\n')
+	r.Nil(err)
+	r.Equal("0\r\n", reply)
+
+	reply, err = reader.ReadString('\n')
+	r.Nil(err)
+	r.Equal("ERR command not allowed\r\n", reply)
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *ProxyTestSuite) TestPolicy() {
+	args := []struct {
+		name    string
+		policy  proxy.Policy
+		line    string
+		allowed bool
+	}{
+		{name: "default", policy: proxy.Policy{}, line: "OP1 1", allowed: true},
+		{name: "read only query", policy: proxy.Policy{ReadOnly: true}, line: "OP1?", allowed: true},
+		{name: "read only write", policy: proxy.Policy{ReadOnly: true}, line: "OP1 1", allowed: false},
+		{name: "deny", policy: proxy.Policy{Deny: []string{"op"}}, line: "OPALL 0", allowed: false},
+		{name: "deny other", policy: proxy.Policy{Deny: []string{"OP"}}, line: "V1?", allowed: true},
+		{name: "allow", policy: proxy.Policy{Allow: []string{"V", "I"}}, line: "I2O?", allowed: true},
+		{name: "not allowed", policy: proxy.Policy{Allow: []string{"V", "I"}}, line: "OP1 0", allowed: false},
+		{name: "deny precedence", policy: proxy.Policy{Allow: []string{"V"}, Deny: []string{"V1"}}, line: "V1 5", allowed: false},
+		{name: "deny query", policy: proxy.Policy{Allow: []string{"V"}, Deny: []string{"V1?"}}, line: "V1?", allowed: false},
+		{name: "compound", policy: proxy.Policy{Deny: []string{"OP"}}, line: "V1?;OP1 1", allowed: false},
+	}
+	for _, arg := range args {
+		t.Equal(arg.allowed, arg.policy.Allowed(arg.line), arg.name)
+	}
+}
+
+func (u *UpstreamMocker) Raw(cmd string) (string, error) {
+	args := u.Called(cmd)
+	return args.String(0), args.Error(1)
+}
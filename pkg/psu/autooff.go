@@ -0,0 +1,236 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AutoOff switches outputs off, once their time is up. Timers run independently of any View refresh,
+// so outputs are switched off also when GUI is in background.
+type AutoOff struct {
+	access  Access
+	retry   time.Duration
+	handler func(section int, err error)
+
+	mtx    sync.Mutex
+	timers map[int]*autoOffTimer
+	closed bool
+}
+
+type autoOffTimer struct {
+	deadline time.Time
+	timer    *time.Timer
+}
+
+var (
+	ErrNotArmed = errors.New("auto-off not armed")
+	ErrClosed   = errors.New("closed")
+)
+
+func NewAutoOff(opts ...AutoOffOption) (*AutoOff, error) {
+	a := &AutoOff{
+		access:  nil,
+		retry:   1 * time.Second,
+		handler: nil,
+		timers:  make(map[int]*autoOffTimer),
+	}
+	for _, opt := range opts {
+		if err := opt(a); err != nil {
+			return nil, err
+		}
+	}
+	if err := a.verify(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Start switches section on and arms switching off after d.
+// Output is switched off again, if it can't be armed, so it is never left on without countdown.
+func (a *AutoOff) Start(section int, d time.Duration) error {
+	if d <= 0 {
+		return ErrInvalidPeriod
+	}
+	a.mtx.Lock()
+	closed := a.closed
+	a.mtx.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	state, err := a.access.SetState(section, true)
+	if err != nil {
+		return err
+	}
+	if !state {
+		return ErrNotSwitched
+	}
+	if err := a.Arm(section, d); err != nil {
+		// AutoOff was closed in the meantime
+		state, offErr := a.access.SetState(section, false)
+		if offErr == nil && state {
+			offErr = ErrNotSwitched
+		}
+		if offErr != nil {
+			log.Error("section ", section, " couldn't be armed and is NOT switched off: ", offErr)
+			return fmt.Errorf("%w, output not switched off: %v", err, offErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Arm switches section off after d, already armed timer is replaced. Output isn't switched on.
+func (a *AutoOff) Arm(section int, d time.Duration) error {
+	if d <= 0 {
+		return ErrInvalidPeriod
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if a.closed {
+		return ErrClosed
+	}
+	a.stop(section)
+	a.schedule(section, time.Now().Add(d))
+	log.Debug("section ", section, " switches off in ", d)
+	return nil
+}
+
+// Extend postpones switching off by d
+func (a *AutoOff) Extend(section int, d time.Duration) error {
+	if d <= 0 {
+		return ErrInvalidPeriod
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	t, ok := a.timers[section]
+	if !ok {
+		return ErrNotArmed
+	}
+	deadline := t.deadline
+	if now := time.Now(); deadline.Before(now) {
+		// Switching off is being retried
+		deadline = now
+	}
+	a.stop(section)
+	a.schedule(section, deadline.Add(d))
+	return nil
+}
+
+// Cancel disarms timer, output is left on
+func (a *AutoOff) Cancel(section int) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.stop(section)
+}
+
+// Remaining returns time left until section is switched off, false if not armed
+func (a *AutoOff) Remaining(section int) (time.Duration, bool) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	t, ok := a.timers[section]
+	if !ok {
+		return 0, false
+	}
+	remaining := time.Until(t.deadline)
+	if remaining < 0 {
+		// Switching off is being retried
+		remaining = 0
+	}
+	return remaining, true
+}
+
+// Close disarms all timers and switches outputs, which were still armed, off at once.
+// Nothing is left on by mistake, when its owner goes away. Handler isn't called.
+// Error of the first output, which couldn't be switched off, is returned.
+func (a *AutoOff) Close() error {
+	a.mtx.Lock()
+	var armed []int
+	for section := range a.timers {
+		armed = append(armed, section)
+		a.stop(section)
+	}
+	a.closed = true
+	a.mtx.Unlock()
+
+	sort.Ints(armed)
+	var first error
+	for _, section := range armed {
+		state, err := a.access.SetState(section, false)
+		if err == nil && state {
+			err = ErrNotSwitched
+		}
+		if err != nil {
+			log.Error("section ", section, " was armed on close and is NOT switched off: ", err)
+			if first == nil {
+				first = fmt.Errorf("section %d: %w", section, err)
+			}
+			continue
+		}
+		log.Error("section ", section, " was armed on close, switched off")
+	}
+	return first
+}
+
+// schedule must be called with mtx locked
+func (a *AutoOff) schedule(section int, deadline time.Time) {
+	t := &autoOffTimer{deadline: deadline}
+	t.timer = time.AfterFunc(time.Until(deadline), func() {
+		a.expire(section, t)
+	})
+	a.timers[section] = t
+}
+
+// stop must be called with mtx locked
+func (a *AutoOff) stop(section int) {
+	if t, ok := a.timers[section]; ok {
+		t.timer.Stop()
+		delete(a.timers, section)
+	}
+}
+
+// expire switches section off, failed attempt is retried until it succeeds or timer is disarmed
+func (a *AutoOff) expire(section int, t *autoOffTimer) {
+	a.mtx.Lock()
+	if a.timers[section] != t {
+		// Timer was replaced or disarmed in the meantime
+		a.mtx.Unlock()
+		return
+	}
+	a.mtx.Unlock()
+
+	state, err := a.access.SetState(section, false)
+	if err == nil && state {
+		err = ErrNotSwitched
+	}
+
+	a.mtx.Lock()
+	if a.timers[section] == t {
+		if err != nil {
+			log.Error("auto-off of section ", section, " failed, retrying: ", err)
+			t.timer.Reset(a.retry)
+		} else {
+			log.Debug("section ", section, " switched off by auto-off")
+			delete(a.timers, section)
+		}
+	}
+	a.mtx.Unlock()
+
+	if a.handler != nil {
+		a.handler(section, err)
+	}
+}
+
+func (a *AutoOff) verify() error {
+	if a.access == nil {
+		return ErrNoAccess
+	}
+	return nil
+}
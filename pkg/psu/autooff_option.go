@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"time"
+)
+
+type AutoOffOption func(*AutoOff) error
+
+func AutoOffWithPSU(p *PSU) AutoOffOption {
+	return func(a *AutoOff) error {
+		return AutoOffWithAccess(p)(a)
+	}
+}
+
+func AutoOffWithAccess(access Access) AutoOffOption {
+	return func(a *AutoOff) error {
+		a.access = access
+		return nil
+	}
+}
+
+// AutoOffWithRetry sets time between attempts to switch off, when previous one failed. 1 s by default.
+func AutoOffWithRetry(t time.Duration) AutoOffOption {
+	return func(a *AutoOff) error {
+		if t <= 0 {
+			return ErrInvalidPeriod
+		}
+		a.retry = t
+		return nil
+	}
+}
+
+// AutoOffWithHandler sets function called after each attempt to switch section off, err is nil on success
+func AutoOffWithHandler(handler func(section int, err error)) AutoOffOption {
+	return func(a *AutoOff) error {
+		a.handler = handler
+		return nil
+	}
+}
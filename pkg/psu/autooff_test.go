@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/psu"
+)
+
+type AutoOffTestSuite struct {
+	suite.Suite
+	mock *AccessMocker
+	off  chan error
+}
+
+func TestAutoOff(t *testing.T) {
+	suite.Run(t, new(AutoOffTestSuite))
+}
+
+func (t *AutoOffTestSuite) SetupTest() {
+	t.mock = new(AccessMocker)
+	t.off = make(chan error, 10)
+}
+
+func (t *AutoOffTestSuite) autoOff() *psu.AutoOff {
+	a, err := psu.NewAutoOff(
+		psu.AutoOffWithAccess(t.mock),
+		psu.AutoOffWithRetry(5*time.Millisecond),
+		psu.AutoOffWithHandler(func(section int, err error) {
+			t.off <- err
+		}),
+	)
+	t.Require().Nil(err)
+	return a
+}
+
+func (t *AutoOffTestSuite) TestNew() {
+	_, err := psu.NewAutoOff()
+	t.ErrorIs(err, psu.ErrNoAccess)
+	_, err = psu.NewAutoOff(psu.AutoOffWithAccess(t.mock), psu.AutoOffWithRetry(0))
+	t.ErrorIs(err, psu.ErrInvalidPeriod)
+}
+
+func (t *AutoOffTestSuite) TestStart() {
+	r := t.Require()
+	t.mock.On("SetState", 1, true).Return(true, nil).Once()
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+	a := t.autoOff()
+	defer a.Close()
+
+	start := time.Now()
+	r.Nil(a.Start(1, 20*time.Millisecond))
+	remaining, ok := a.Remaining(1)
+	r.True(ok)
+	r.InDelta(20*time.Millisecond, remaining, float64(10*time.Millisecond))
+
+	r.Nil(<-t.off)
+	r.GreaterOrEqual(time.Since(start), 20*time.Millisecond)
+	_, ok = a.Remaining(1)
+	r.False(ok)
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *AutoOffTestSuite) TestStartFails() {
+	t.mock.On("SetState", 1, true).Return(false, nil).Once()
+	a := t.autoOff()
+	t.ErrorIs(a.Start(1, time.Second), psu.ErrNotSwitched)
+	t.ErrorIs(a.Start(1, 0), psu.ErrInvalidPeriod)
+	_, ok := a.Remaining(1)
+	t.False(ok)
+}
+
+func (t *AutoOffTestSuite) TestStartClosed() {
+	r := t.Require()
+	a := t.autoOff()
+	r.Nil(a.Close())
+	r.ErrorIs(a.Start(1, time.Second), psu.ErrClosed)
+	t.mock.AssertNotCalled(t.T(), "SetState", mock.Anything, mock.Anything)
+
+	// Closed while output is being switched on
+	a = t.autoOff()
+	t.mock.On("SetState", 1, true).Return(true, nil).Once().Run(func(mock.Arguments) {
+		r.Nil(a.Close())
+	})
+	t.mock.On("SetState", 1, false).Return(true, nil).Once()
+	err := a.Start(1, time.Second)
+	r.ErrorIs(err, psu.ErrClosed)
+	r.ErrorContains(err, "not switched off")
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *AutoOffTestSuite) TestExtendAndCancel() {
+	r := t.Require()
+	a := t.autoOff()
+	defer a.Close()
+
+	r.ErrorIs(a.Extend(1, time.Second), psu.ErrNotArmed)
+	r.Nil(a.Arm(1, time.Minute))
+	r.Nil(a.Extend(1, time.Hour))
+	remaining, ok := a.Remaining(1)
+	r.True(ok)
+	r.Greater(remaining, time.Hour)
+
+	a.Cancel(1)
+	_, ok = a.Remaining(1)
+	r.False(ok)
+	// Output is never touched
+	t.mock.AssertNotCalled(t.T(), "SetState", 1, false)
+}
+
+func (t *AutoOffTestSuite) TestRetry() {
+	r := t.Require()
+	t.mock.On("SetState", 2, false).Return(false, errors.New("timeout")).Once()
+	t.mock.On("SetState", 2, false).Return(true, nil).Once()
+	t.mock.On("SetState", 2, false).Return(false, nil).Once()
+	a := t.autoOff()
+	defer a.Close()
+
+	r.Nil(a.Arm(2, time.Millisecond))
+	r.EqualError(<-t.off, "timeout")
+	// Timer stays armed until output is off
+	_, ok := a.Remaining(2)
+	r.True(ok)
+	r.ErrorIs(<-t.off, psu.ErrNotSwitched)
+	r.Nil(<-t.off)
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *AutoOffTestSuite) TestClose() {
+	r := t.Require()
+	errSwitch := errors.New("timeout")
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+	t.mock.On("SetState", 2, false).Return(false, errSwitch).Once()
+	a := t.autoOff()
+	r.Nil(a.Arm(1, time.Hour))
+	r.Nil(a.Arm(2, time.Hour))
+	r.Nil(a.Arm(3, time.Hour))
+	a.Cancel(3)
+
+	// Armed outputs are switched off at once, failure is reported
+	err := a.Close()
+	r.ErrorIs(err, errSwitch)
+	r.ErrorContains(err, "section 2")
+	r.ErrorIs(a.Arm(1, time.Millisecond), psu.ErrClosed)
+	_, ok := a.Remaining(1)
+	r.False(ok)
+	t.mock.AssertExpectations(t.T())
+	t.mock.AssertNotCalled(t.T(), "SetState", 3, false)
+	// Handler isn't called on close
+	r.Empty(t.off)
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache is an Access decorator. It keeps Section results for configured TTL and merges
+// concurrent requests for the same section into single exchange with underlying Access.
+type Cache struct {
+	access  Access
+	ttl     time.Duration
+	mtx     sync.Mutex
+	entries map[int]*cacheEntry
+}
+
+type cacheEntry struct {
+	section   *Section
+	err       error
+	timestamp time.Time
+	// done is closed, when request to underlying Access is finished
+	done chan struct{}
+}
+
+var (
+	_ Access = (*Cache)(nil)
+)
+
+func NewCache(opts ...CacheOption) (*Cache, error) {
+	c := &Cache{
+		access:  nil,
+		ttl:     250 * time.Millisecond,
+		entries: make(map[int]*cacheEntry),
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.verify(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Cache) Section(section int) (*Section, error) {
+	c.mtx.Lock()
+	if e, ok := c.entries[section]; ok {
+		select {
+		case <-e.done:
+			if time.Since(e.timestamp) < c.ttl {
+				c.mtx.Unlock()
+				log.Debug("cache hit, section ", section)
+				return copySection(e.section), nil
+			}
+		default:
+			// Someone is already asking for the same section, just wait for the result
+			c.mtx.Unlock()
+			<-e.done
+			return copySection(e.section), e.err
+		}
+	}
+	e := &cacheEntry{done: make(chan struct{})}
+	c.entries[section] = e
+	c.mtx.Unlock()
+
+	s, err := c.access.Section(section)
+
+	c.mtx.Lock()
+	e.section, e.err, e.timestamp = s, err, time.Now()
+	close(e.done)
+	// Errors are shared only with concurrent callers, never cached
+	if err != nil && c.entries[section] == e {
+		delete(c.entries, section)
+	}
+	c.mtx.Unlock()
+
+	return copySection(s), err
+}
+
+func (c *Cache) SetState(section int, value bool) (bool, error) {
+	state, err := c.access.SetState(section, value)
+	c.Invalidate(section)
+	return state, err
+}
+
+// Invalidate drops cached data for section. Requests already in progress won't be cached.
+func (c *Cache) Invalidate(section int) {
+	c.mtx.Lock()
+	delete(c.entries, section)
+	c.mtx.Unlock()
+}
+
+func (c *Cache) verify() error {
+	if c.access == nil {
+		return ErrNoAccess
+	}
+	return nil
+}
+
+// copySection protects cached data from modification by callers
+func copySection(s *Section) *Section {
+	if s == nil {
+		return nil
+	}
+	cp := *s
+	return &cp
+}
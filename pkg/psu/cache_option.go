@@ -0,0 +1,40 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"errors"
+	"time"
+)
+
+type CacheOption func(*Cache) error
+
+var (
+	ErrInvalidTTL = errors.New("ttl must be positive")
+)
+
+func CacheWithPSU(p *PSU) CacheOption {
+	return func(cache *Cache) error {
+		return CacheWithAccess(p)(cache)
+	}
+}
+
+func CacheWithAccess(a Access) CacheOption {
+	return func(cache *Cache) error {
+		cache.access = a
+		return nil
+	}
+}
+
+func CacheWithTTL(ttl time.Duration) CacheOption {
+	return func(cache *Cache) error {
+		if ttl <= 0 {
+			return ErrInvalidTTL
+		}
+		cache.ttl = ttl
+		return nil
+	}
+}
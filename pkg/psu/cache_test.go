@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/psu"
+)
+
+type CacheTestSuite struct {
+	suite.Suite
+	mock *AccessMocker
+}
+
+func TestCache(t *testing.T) {
+	suite.Run(t, new(CacheTestSuite))
+}
+
+func (t *CacheTestSuite) SetupTest() {
+	t.mock = new(AccessMocker)
+}
+
+func (t *CacheTestSuite) cache(ttl time.Duration) *psu.Cache {
+	c, err := psu.NewCache(psu.CacheWithAccess(t.mock), psu.CacheWithTTL(ttl))
+	t.Require().Nil(err)
+	t.Require().NotNil(c)
+	return c
+}
+
+func (t *CacheTestSuite) TestNew() {
+	{
+		c, err := psu.NewCache()
+		t.Nil(c)
+		t.ErrorIs(err, psu.ErrNoAccess)
+	}
+	{
+		c, err := psu.NewCache(psu.CacheWithAccess(t.mock), psu.CacheWithTTL(0))
+		t.Nil(c)
+		t.ErrorIs(err, psu.ErrInvalidTTL)
+	}
+	{
+		c, err := psu.NewCache(psu.CacheWithAccess(t.mock))
+		t.NotNil(c)
+		t.Nil(err)
+	}
+}
+
+func (t *CacheTestSuite) TestTTL() {
+	r := t.Require()
+	s := &psu.Section{State: true, ActualVoltage: "1"}
+	t.mock.On("Section", 1).Return(s, nil).Twice()
+
+	c := t.cache(20 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		v, err := c.Section(1)
+		r.Nil(err)
+		r.EqualValues(s, v)
+	}
+	t.mock.AssertNumberOfCalls(t.T(), "Section", 1)
+
+	<-time.After(30 * time.Millisecond)
+	v, err := c.Section(1)
+	r.Nil(err)
+	r.EqualValues(s, v)
+	t.mock.AssertNumberOfCalls(t.T(), "Section", 2)
+}
+
+func (t *CacheTestSuite) TestReturnsCopy() {
+	r := t.Require()
+	s := &psu.Section{ActualVoltage: "1"}
+	t.mock.On("Section", 1).Return(s, nil).Once()
+
+	c := t.cache(time.Hour)
+	v, err := c.Section(1)
+	r.Nil(err)
+	v.ActualVoltage = "2"
+
+	v, err = c.Section(1)
+	r.Nil(err)
+	r.Equal("1", v.ActualVoltage)
+}
+
+func (t *CacheTestSuite) TestErrorNotCached() {
+	r := t.Require()
+	s := &psu.Section{ActualVoltage: "1"}
+	var nilSection *psu.Section
+	t.mock.On("Section", 1).Return(nilSection, errors.New("timeout")).Once()
+	t.mock.On("Section", 1).Return(s, nil).Once()
+
+	c := t.cache(time.Hour)
+	v, err := c.Section(1)
+	r.NotNil(err)
+	r.Nil(v)
+
+	v, err = c.Section(1)
+	r.Nil(err)
+	r.EqualValues(s, v)
+}
+
+func (t *CacheTestSuite) TestCoalesce() {
+	s := &psu.Section{ActualVoltage: "1"}
+	t.mock.On("Section", 1).Return(s, nil).Once().WaitUntil(time.After(50 * time.Millisecond))
+
+	c := t.cache(time.Hour)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.Section(1)
+			t.Nil(err)
+			t.EqualValues(s, v)
+		}()
+	}
+	wg.Wait()
+	t.mock.AssertNumberOfCalls(t.T(), "Section", 1)
+}
+
+func (t *CacheTestSuite) TestSetStateInvalidates() {
+	r := t.Require()
+	off := &psu.Section{State: false}
+	on := &psu.Section{State: true}
+	t.mock.On("Section", 1).Return(off, nil).Once()
+	t.mock.On("Section", 2).Return(off, nil).Once()
+	t.mock.On("SetState", 1, true).Return(true, nil).Once()
+	t.mock.On("Section", 1).Return(on, nil).Once()
+
+	c := t.cache(time.Hour)
+	for _, section := range []int{1, 2} {
+		v, err := c.Section(section)
+		r.Nil(err)
+		r.False(v.State)
+	}
+
+	state, err := c.SetState(1, true)
+	r.Nil(err)
+	r.True(state)
+
+	v, err := c.Section(1)
+	r.Nil(err)
+	r.True(v.State)
+
+	// Other sections are still cached
+	v, err = c.Section(2)
+	r.Nil(err)
+	r.False(v.State)
+	t.mock.AssertExpectations(t.T())
+}
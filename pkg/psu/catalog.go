@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CommandInfo describes command understood by PSU
+type CommandInfo struct {
+	// Syntax of command, <N> stands for section number, other <...> for argument
+	Syntax      string
+	Description string
+	// Register names bits of reply, nil if reply isn't bit register
+	Register Register
+}
+
+// Bit of status register
+type Bit struct {
+	Mask uint64
+	Name string
+}
+
+// Register names bits of status register
+type Register []Bit
+
+var (
+	// LimitStatusRegister is replied to LSR<N>? and LSE<N>?
+	LimitStatusRegister = Register{
+		{Mask: lsrConstantVoltage, Name: "CV"},
+		{Mask: lsrConstantCurrent, Name: "CC"},
+		{Mask: lsrOverVoltageTrip, Name: "OVP"},
+		{Mask: lsrOverCurrentTrip, Name: "OCP"},
+		{Mask: lsrPowerLimit, Name: "PLIM"},
+		{Mask: lsrHardTrip, Name: "HARD"},
+	}
+	// EventStatusRegister is replied to *ESR? and *ESE?
+	EventStatusRegister = Register{
+		{Mask: 1 << 0, Name: "OPC"},
+		{Mask: 1 << 2, Name: "QYE"},
+		{Mask: 1 << 3, Name: "DDE"},
+		{Mask: 1 << 4, Name: "EXE"},
+		{Mask: 1 << 5, Name: "CME"},
+		{Mask: 1 << 7, Name: "PON"},
+	}
+	// StatusByte is replied to *STB? and *SRE?
+	StatusByte = Register{
+		{Mask: 1 << 0, Name: "LIM1"},
+		{Mask: 1 << 1, Name: "LIM2"},
+		{Mask: 1 << 4, Name: "MAV"},
+		{Mask: 1 << 5, Name: "ESB"},
+		{Mask: 1 << 6, Name: "MSS"},
+	}
+)
+
+// catalog lists commands of command.go and other commands useful for debugging
+var catalog = []CommandInfo{
+	{Syntax: "V<N> <V>", Description: "set voltage"},
+	{Syntax: "V<N>?", Description: "voltage set-point"},
+	{Syntax: "V<N>O?", Description: "actual voltage"},
+	{Syntax: "I<N> <A>", Description: "set current limit"},
+	{Syntax: "I<N>?", Description: "current set-point"},
+	{Syntax: "I<N>O?", Description: "actual current"},
+	{Syntax: "OVP<N> <V>", Description: "set over voltage protection trip point"},
+	{Syntax: "OVP<N>?", Description: "over voltage protection trip point"},
+	{Syntax: "OCP<N> <A>", Description: "set over current protection trip point"},
+	{Syntax: "OCP<N>?", Description: "over current protection trip point"},
+	{Syntax: "OP<N> <0|1>", Description: "switch output off or on"},
+	{Syntax: "OP<N>?", Description: "output state"},
+	{Syntax: "OPALL <0|1>", Description: "switch all outputs off or on"},
+	{Syntax: "LSR<N>?", Description: "limit status register, cleared by query", Register: LimitStatusRegister},
+	{Syntax: "LSE<N> <value>", Description: "set limit status enable register"},
+	{Syntax: "LSE<N>?", Description: "limit status enable register", Register: LimitStatusRegister},
+	{Syntax: "TRIPRST", Description: "reset trips, which can be reset remotely"},
+	{Syntax: "*IDN?", Description: "identification"},
+	{Syntax: "*RST", Description: "reset to default settings"},
+	{Syntax: "*CLS", Description: "clear status"},
+	{Syntax: "*ESR?", Description: "standard event status register", Register: EventStatusRegister},
+	{Syntax: "*ESE <value>", Description: "set standard event status enable register"},
+	{Syntax: "*ESE?", Description: "standard event status enable register", Register: EventStatusRegister},
+	{Syntax: "*STB?", Description: "status byte", Register: StatusByte},
+	{Syntax: "*SRE <value>", Description: "set service request enable register"},
+	{Syntax: "*SRE?", Description: "service request enable register", Register: StatusByte},
+	{Syntax: "*OPC?", Description: "operation complete"},
+	{Syntax: "EER?", Description: "execution error number"},
+	{Syntax: "QER?", Description: "query error number"},
+	{Syntax: "IFLOCK", Description: "request interface lock"},
+	{Syntax: "IFLOCK?", Description: "interface lock status"},
+	{Syntax: "IFUNLOCK", Description: "release interface lock"},
+	{Syntax: "LOCAL", Description: "go to local, release lock"},
+}
+
+var placeholder = regexp.MustCompile(`<[^>]*>`)
+
+// Commands returns known commands
+func Commands() []CommandInfo {
+	return append([]CommandInfo(nil), catalog...)
+}
+
+// Header returns command without argument, e.g. OP1 for "OP1 1"
+func (c CommandInfo) Header() string {
+	header, _, _ := strings.Cut(c.Syntax, " ")
+	return header
+}
+
+// HasArgument returns true, if command expects argument
+func (c CommandInfo) HasArgument() bool {
+	return strings.Contains(c.Syntax, " ")
+}
+
+// Expand returns header with <N> replaced by section
+func (c CommandInfo) Expand(section int) string {
+	return strings.ReplaceAll(c.Header(), "<N>", strconv.Itoa(section))
+}
+
+// Matches returns true, if cmd is this command (case-insensitive)
+func (c CommandInfo) Matches(cmd string) bool {
+	header, _, _ := strings.Cut(strings.TrimSpace(cmd), " ")
+	pattern := "(?i)^" + placeholder.ReplaceAllString(regexp.QuoteMeta(c.Header()), `[0-9]+`) + "$"
+	return regexp.MustCompile(pattern).MatchString(header)
+}
+
+// Lookup returns info of cmd
+func Lookup(cmd string) (CommandInfo, bool) {
+	for _, info := range catalog {
+		if info.Matches(cmd) {
+			return info, true
+		}
+	}
+	return CommandInfo{}, false
+}
+
+// Decode returns names of bits set in value, unknown bits are named by number, e.g. BIT5
+func (r Register) Decode(value uint64) []string {
+	var names []string
+	for bit := 0; bit < 64; bit++ {
+		mask := uint64(1) << bit
+		if value&mask == 0 {
+			continue
+		}
+		name := "BIT" + strconv.Itoa(bit)
+		for _, b := range r {
+			if b.Mask == mask {
+				name = b.Name
+				break
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// DecodeReply returns names of bits set in reply to register query cmd, ok is false if reply isn't register
+func DecodeReply(cmd, reply string) (names []string, ok bool) {
+	info, found := Lookup(cmd)
+	if !found || info.Register == nil {
+		return nil, false
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(reply), 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	return info.Register.Decode(value), true
+}
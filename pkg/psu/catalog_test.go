@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"psu/pkg/psu"
+)
+
+func TestLookup(t *testing.T) {
+	args := []struct {
+		cmd    string
+		syntax string
+		found  bool
+	}{
+		{cmd: "V1?", syntax: "V<N>?", found: true},
+		{cmd: "v2o?", syntax: "V<N>O?", found: true},
+		{cmd: "OP1 1", syntax: "OP<N> <0|1>", found: true},
+		{cmd: "OPALL 0", syntax: "OPALL <0|1>", found: true},
+		{cmd: "LSR12?", syntax: "LSR<N>?", found: true},
+		{cmd: "*idn?", syntax: "*IDN?", found: true},
+		{cmd: "VX?", found: false},
+		{cmd: "LSR?", found: false},
+	}
+	for _, arg := range args {
+		info, found := psu.Lookup(arg.cmd)
+		require.Equal(t, arg.found, found, arg.cmd)
+		require.Equal(t, arg.syntax, info.Syntax, arg.cmd)
+	}
+}
+
+func TestCommandInfo(t *testing.T) {
+	info, _ := psu.Lookup("OP1 1")
+	require.Equal(t, "OP<N>", info.Header())
+	require.Equal(t, "OP2", info.Expand(2))
+	require.True(t, info.HasArgument())
+
+	info, _ = psu.Lookup("*IDN?")
+	require.Equal(t, "*IDN?", info.Expand(1))
+	require.False(t, info.HasArgument())
+}
+
+func TestDecodeReply(t *testing.T) {
+	args := []struct {
+		cmd   string
+		reply string
+		names []string
+		ok    bool
+	}{
+		{cmd: "LSR1?", reply: "1", names: []string{"CV"}, ok: true},
+		{cmd: "LSR2?", reply: "74", names: []string{"CC", "OCP", "HARD"}, ok: true},
+		{cmd: "LSR1?", reply: "32", names: []string{"BIT5"}, ok: true},
+		{cmd: "LSR1?", reply: "0", names: nil, ok: true},
+		{cmd: "*ESR?", reply: "129", names: []string{"OPC", "PON"}, ok: true},
+		{cmd: "*STB?", reply: "17", names: []string{"LIM1", "MAV"}, ok: true},
+		{cmd: "V1?", reply: "V1 12.00", ok: false},
+		{cmd: "LSR1?", reply: "garbage", ok: false},
+	}
+	for _, arg := range args {
+		names, ok := psu.DecodeReply(arg.cmd, arg.reply)
+		require.Equal(t, arg.ok, ok, arg.cmd)
+		require.Equal(t, arg.names, names, arg.cmd)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	commands := psu.Commands()
+	require.NotEmpty(t, commands)
+	// Each command must be found by its own header
+	for _, info := range commands {
+		found, ok := psu.Lookup(info.Expand(1))
+		require.True(t, ok, info.Syntax)
+		require.Equal(t, info.Syntax, found.Syntax)
+	}
+}
@@ -0,0 +1,309 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// ChartFormat is format of exported Chart
+type ChartFormat int
+
+const (
+	ChartPNG ChartFormat = iota
+	ChartSVG
+)
+
+// Chart plots actual voltage and current of samples against their set-points, voltage above current
+type Chart struct {
+	Title    string
+	From, To time.Time
+	Samples  []Sample
+}
+
+var (
+	ErrChartSize = errors.New("chart size is too small")
+)
+
+var (
+	chartBackground = color.RGBA{R: 0x1e, G: 0x1e, B: 0x1e, A: 0xff}
+	chartGrid       = color.RGBA{R: 0x44, G: 0x44, B: 0x44, A: 0xff}
+	chartText       = color.RGBA{R: 0xdd, G: 0xdd, B: 0xdd, A: 0xff}
+	chartSetPoint   = color.RGBA{R: 0x99, G: 0x99, B: 0x99, A: 0xff}
+	chartVoltage    = color.RGBA{R: 0xff, G: 0x98, B: 0x00, A: 0xff}
+	chartCurrent    = color.RGBA{R: 0x21, G: 0x96, B: 0xf3, A: 0xff}
+)
+
+const (
+	chartMinWidth, chartMinHeight = 120, 100
+	// chartLeft is room for labels of y axis, chartPad is room for title or time labels
+	chartLeft, chartRight, chartPad = 48, 8, 16
+	chartGridLines                  = 4
+)
+
+// chartPanel is plot of single quantity, in pixels
+type chartPanel struct {
+	title                    string
+	left, right, top, bottom float64
+	max                      float64
+	lines                    []chartLine
+}
+
+type chartLine struct {
+	color    color.RGBA
+	dashed   bool
+	segments [][]chartPoint
+}
+
+type chartPoint struct {
+	x, y float64
+}
+
+// Image renders chart, e.g. for canvas.Raster
+func (c Chart) Image(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(chartBackground), image.Point{}, draw.Src)
+	if width < chartMinWidth || height < chartMinHeight {
+		return img
+	}
+	for _, p := range c.panels(width, height) {
+		for i := 0; i <= chartGridLines; i++ {
+			y := p.gridY(i)
+			drawLine(img, chartPoint{p.left, y}, chartPoint{p.right, y}, chartGrid, false)
+			drawText(img, 2, int(y)+4, p.gridLabel(i), chartText)
+		}
+		drawText(img, chartLeft, int(p.top)-4, p.title, chartText)
+		for _, l := range p.lines {
+			for _, segment := range l.segments {
+				for i := 1; i < len(segment); i++ {
+					drawLine(img, segment[i-1], segment[i], l.color, l.dashed)
+				}
+			}
+		}
+	}
+	from, to := c.timeLabels()
+	drawText(img, chartLeft, height-4, from, chartText)
+	drawText(img, width-chartRight-len(to)*7, height-4, to, chartText)
+	return img
+}
+
+// WritePNG encodes chart as PNG image
+func (c Chart) WritePNG(w io.Writer, width, height int) error {
+	if width < chartMinWidth || height < chartMinHeight {
+		return ErrChartSize
+	}
+	return png.Encode(w, c.Image(width, height))
+}
+
+// WriteSVG encodes chart as SVG image
+func (c Chart) WriteSVG(w io.Writer, width, height int) error {
+	if width < chartMinWidth || height < chartMinHeight {
+		return ErrChartSize
+	}
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace" font-size="12">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(b, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", hexColor(chartBackground))
+	for _, p := range c.panels(width, height) {
+		for i := 0; i <= chartGridLines; i++ {
+			y := p.gridY(i)
+			fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`+"\n", p.left, y, p.right, y, hexColor(chartGrid))
+			svgText(b, 2, y+4, p.gridLabel(i))
+		}
+		svgText(b, chartLeft, p.top-4, p.title)
+		for _, l := range p.lines {
+			dash := ""
+			if l.dashed {
+				dash = ` stroke-dasharray="4 4"`
+			}
+			for _, segment := range l.segments {
+				points := make([]string, len(segment))
+				for i, point := range segment {
+					points[i] = fmt.Sprintf("%.1f,%.1f", point.x, point.y)
+				}
+				fmt.Fprintf(b, `<polyline points="%s" fill="none" stroke="%s"%s/>`+"\n", strings.Join(points, " "), hexColor(l.color), dash)
+			}
+		}
+	}
+	from, to := c.timeLabels()
+	svgText(b, chartLeft, float64(height-4), from)
+	fmt.Fprintf(b, `<text x="%d" y="%d" fill="%s" text-anchor="end">%s</text>`+"\n", width-chartRight, height-4, hexColor(chartText), to)
+	fmt.Fprintln(b, "</svg>")
+	return b.Flush()
+}
+
+// Write encodes chart in format
+func (c Chart) Write(w io.Writer, format ChartFormat, width, height int) error {
+	if format == ChartSVG {
+		return c.WriteSVG(w, width, height)
+	}
+	return c.WritePNG(w, width, height)
+}
+
+// Extension returns file extension of format, with dot
+func (f ChartFormat) Extension() string {
+	if f == ChartSVG {
+		return ".svg"
+	}
+	return ".png"
+}
+
+// panels returns voltage and current plots
+func (c Chart) panels(width, height int) []*chartPanel {
+	title := c.Title
+	if title != "" {
+		title += " "
+	}
+	quantities := []struct {
+		title string
+		color color.RGBA
+		value func(Values) (actual, set float64)
+	}{
+		{title: title + "voltage [V]", color: chartVoltage, value: func(v Values) (float64, float64) { return v.ActualVoltage, v.SetVoltage }},
+		{title: title + "current [A]", color: chartCurrent, value: func(v Values) (float64, float64) { return v.ActualCurrent, v.SetCurrent }},
+	}
+	half := float64(height) / 2
+	span := c.To.Sub(c.From).Seconds()
+	panels := make([]*chartPanel, len(quantities))
+	for i, q := range quantities {
+		p := &chartPanel{
+			title:  q.title,
+			left:   chartLeft,
+			right:  float64(width - chartRight),
+			top:    float64(i)*half + chartPad,
+			bottom: float64(i+1)*half - chartPad,
+			// Empty chart still has scale
+			max: 0.1,
+		}
+		for _, s := range c.Samples {
+			if !s.Lost {
+				actual, set := q.value(s.Values)
+				p.max = math.Max(p.max, math.Max(actual, set))
+			}
+		}
+		p.max *= 1.1
+
+		actual := chartLine{color: q.color}
+		set := chartLine{color: chartSetPoint, dashed: true}
+		var segment, setSegment []chartPoint
+		flush := func() {
+			if len(segment) > 0 {
+				actual.segments = append(actual.segments, segment)
+				set.segments = append(set.segments, setSegment)
+			}
+			segment, setSegment = nil, nil
+		}
+		for _, s := range c.Samples {
+			if s.Lost {
+				flush()
+				continue
+			}
+			x := p.left
+			if span > 0 {
+				x += s.Time.Sub(c.From).Seconds() / span * (p.right - p.left)
+			}
+			a, sp := q.value(s.Values)
+			segment = append(segment, chartPoint{x, p.y(a)})
+			setSegment = append(setSegment, chartPoint{x, p.y(sp)})
+		}
+		flush()
+		// Set-point is drawn first, so actual value is on top
+		p.lines = []chartLine{set, actual}
+		panels[i] = p
+	}
+	return panels
+}
+
+func (p *chartPanel) y(value float64) float64 {
+	value = math.Max(0, math.Min(value, p.max))
+	return p.bottom - value/p.max*(p.bottom-p.top)
+}
+
+func (p *chartPanel) gridY(i int) float64 {
+	return p.y(p.max * float64(i) / chartGridLines)
+}
+
+func (p *chartPanel) gridLabel(i int) string {
+	return fmt.Sprintf("%.2f", p.max*float64(i)/chartGridLines)
+}
+
+func (c Chart) timeLabels() (string, string) {
+	return c.From.Format("15:04:05"), c.To.Format("15:04:05")
+}
+
+// drawLine draws line by Bresenham's algorithm, dashed line skips every other 4 pixels
+func drawLine(img *image.RGBA, from, to chartPoint, c color.RGBA, dashed bool) {
+	x0, y0 := int(math.Round(from.x)), int(math.Round(from.y))
+	x1, y1 := int(math.Round(to.x)), int(math.Round(to.y))
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for step := 0; ; step++ {
+		if !dashed || (step/4)%2 == 0 {
+			img.SetRGBA(x0, y0, c)
+		}
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func drawText(img *image.RGBA, x, y int, text string, c color.RGBA) {
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+func svgText(w io.Writer, x, y float64, text string) {
+	fmt.Fprintf(w, `<text x="%.1f" y="%.1f" fill="%s">`, x, y, hexColor(chartText))
+	_ = xml.EscapeText(w, []byte(text))
+	fmt.Fprintln(w, "</text>")
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/psu"
+)
+
+type ChartTestSuite struct {
+	suite.Suite
+	chart psu.Chart
+}
+
+func TestChart(t *testing.T) {
+	suite.Run(t, new(ChartTestSuite))
+}
+
+func (t *ChartTestSuite) SetupTest() {
+	start := time.Date(2023, 5, 2, 10, 0, 0, 0, time.UTC)
+	values := psu.Values{ActualVoltage: 11.9, SetVoltage: 12, ActualCurrent: 0.5, SetCurrent: 1}
+	t.chart = psu.Chart{
+		Title: "Output 1 <main>",
+		From:  start,
+		To:    start.Add(time.Minute),
+		Samples: []psu.Sample{
+			{Time: start.Add(10 * time.Second), Values: values},
+			{Time: start.Add(20 * time.Second), Values: values},
+			{Time: start.Add(30 * time.Second), Lost: true},
+			{Time: start.Add(40 * time.Second), Values: values},
+			{Time: start.Add(50 * time.Second), Values: values},
+		},
+	}
+}
+
+func (t *ChartTestSuite) TestPNG() {
+	r := t.Require()
+	buf := bytes.Buffer{}
+	r.Nil(t.chart.Write(&buf, psu.ChartPNG, 400, 300))
+	img, err := png.Decode(&buf)
+	r.Nil(err)
+	r.Equal(400, img.Bounds().Dx())
+	r.Equal(300, img.Bounds().Dy())
+
+	// Empty chart is drawn too
+	r.Nil(psu.Chart{}.WritePNG(io.Discard, 400, 300))
+	r.ErrorIs(t.chart.WritePNG(io.Discard, 10, 10), psu.ErrChartSize)
+}
+
+func (t *ChartTestSuite) TestSVG() {
+	r := t.Require()
+	buf := bytes.Buffer{}
+	r.Nil(t.chart.Write(&buf, psu.ChartSVG, 400, 300))
+	svg := buf.String()
+
+	// Gap splits each of 4 lines in two
+	r.Equal(8, strings.Count(svg, "<polyline"))
+	r.Contains(svg, "Output 1 &lt;main&gt; voltage [V]")
+	r.Contains(svg, "10:01:00")
+
+	// Well-formed XML
+	decoder := xml.NewDecoder(&buf)
+	for {
+		_, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		r.Nil(err)
+	}
+	r.ErrorIs(t.chart.WriteSVG(io.Discard, 10, 10), psu.ErrChartSize)
+}
+
+func (t *ChartTestSuite) TestExtension() {
+	t.Equal(".png", psu.ChartPNG.Extension())
+	t.Equal(".svg", psu.ChartSVG.Extension())
+}
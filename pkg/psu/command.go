@@ -29,6 +29,15 @@ var (
 	_ commander = (*setCurrentType)(nil)
 	_ commander = (*getStateType)(nil)
 	_ commander = (*setStateType)(nil)
+	_ commander = (*limitStatusType)(nil)
+	_ commander = (*writeVoltageType)(nil)
+	_ commander = (*writeCurrentType)(nil)
+	_ commander = (*overVoltageType)(nil)
+	_ commander = (*overCurrentType)(nil)
+	_ commander = (*writeOverVoltageType)(nil)
+	_ commander = (*writeOverCurrentType)(nil)
+	_ commander = (*identifyType)(nil)
+	_ commander = (*rawType)(nil)
 )
 
 type actualVoltageType struct {
@@ -56,6 +65,45 @@ type setStateType struct {
 	value   bool
 }
 
+type limitStatusType struct {
+	section string
+}
+
+type overVoltageType struct {
+	section string
+}
+
+type overCurrentType struct {
+	section string
+}
+
+type writeOverVoltageType struct {
+	section string
+	value   string
+}
+
+type writeOverCurrentType struct {
+	section string
+	value   string
+}
+
+type identifyType struct {
+}
+
+type rawType struct {
+	cmd string
+}
+
+type writeVoltageType struct {
+	section string
+	value   string
+}
+
+type writeCurrentType struct {
+	section string
+	value   string
+}
+
 func (*setStateType) Parse(reply []string) (string, error) {
 	panic("shouldn't be called")
 }
@@ -146,3 +194,130 @@ func (*actualVoltageType) WriteOnly() bool {
 func (a *actualVoltageType) Command() command {
 	return command("V" + a.section + "O?")
 }
+
+func (*limitStatusType) Parse(reply []string) (string, error) {
+	if len(reply) != 1 {
+		return "", ErrUnexpectedLen
+	}
+	return reply[0], nil
+}
+
+func (*limitStatusType) WriteOnly() bool {
+	return false
+}
+
+func (l *limitStatusType) Command() command {
+	return command("LSR" + l.section + "?")
+}
+
+func (*writeVoltageType) Parse(reply []string) (string, error) {
+	panic("shouldn't be called")
+}
+
+func (*writeVoltageType) WriteOnly() bool {
+	return true
+}
+
+func (w *writeVoltageType) Command() command {
+	return command("V" + w.section + " " + w.value)
+}
+
+func (*writeCurrentType) Parse(reply []string) (string, error) {
+	panic("shouldn't be called")
+}
+
+func (*writeCurrentType) WriteOnly() bool {
+	return true
+}
+
+func (w *writeCurrentType) Command() command {
+	return command("I" + w.section + " " + w.value)
+}
+
+// Parse of reply, e.g. "VP1 66.00"
+func (*overVoltageType) Parse(reply []string) (string, error) {
+	if len(reply) != 2 {
+		return "", ErrUnexpectedLen
+	}
+	return reply[1], nil
+}
+
+func (*overVoltageType) WriteOnly() bool {
+	return false
+}
+
+func (o *overVoltageType) Command() command {
+	return command("OVP" + o.section + "?")
+}
+
+// Parse of reply, e.g. "CP1 22.00"
+func (*overCurrentType) Parse(reply []string) (string, error) {
+	if len(reply) != 2 {
+		return "", ErrUnexpectedLen
+	}
+	return reply[1], nil
+}
+
+func (*overCurrentType) WriteOnly() bool {
+	return false
+}
+
+func (o *overCurrentType) Command() command {
+	return command("OCP" + o.section + "?")
+}
+
+func (*writeOverVoltageType) Parse(reply []string) (string, error) {
+	panic("shouldn't be called")
+}
+
+func (*writeOverVoltageType) WriteOnly() bool {
+	return true
+}
+
+func (w *writeOverVoltageType) Command() command {
+	return command("OVP" + w.section + " " + w.value)
+}
+
+func (*writeOverCurrentType) Parse(reply []string) (string, error) {
+	panic("shouldn't be called")
+}
+
+func (*writeOverCurrentType) WriteOnly() bool {
+	return true
+}
+
+func (w *writeOverCurrentType) Command() command {
+	return command("OCP" + w.section + " " + w.value)
+}
+
+func (*identifyType) Parse(reply []string) (string, error) {
+	if len(reply) == 0 {
+		return "", ErrUnexpectedLen
+	}
+	return strings.Join(reply, " "), nil
+}
+
+func (*identifyType) WriteOnly() bool {
+	return false
+}
+
+func (*identifyType) Command() command {
+	return "*IDN?"
+}
+
+func (*rawType) Parse(reply []string) (string, error) {
+	return strings.Join(reply, " "), nil
+}
+
+func (r *rawType) WriteOnly() bool {
+	return !IsQuery(r.cmd)
+}
+
+func (r *rawType) Command() command {
+	return command(r.cmd)
+}
+
+// IsQuery returns true, if instrument replies to cmd
+func IsQuery(cmd string) bool {
+	return strings.Contains(cmd, "?")
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// PowerCycle switches sections off and on repeatedly, e.g. to test whether DUT survives reboots.
+// Each cycle switches sections off, waits off time, switches them on, measures current and waits on time.
+type PowerCycle struct {
+	access   Access
+	sections []int
+	off, on  time.Duration
+	// settle passes between switching on and measuring current
+	settle        time.Duration
+	cycles        int
+	stopOnFailure bool
+	handler       func(CycleResult)
+}
+
+// CycleResult is outcome of single cycle of single section
+type CycleResult struct {
+	// Cycle is counted from 1
+	Cycle   int
+	Section int
+	// Current measured on power-up
+	Current float64
+	Err     error
+}
+
+// CycleSummary is returned by PowerCycle.Run
+type CycleSummary struct {
+	// Cycles is number of completed cycles
+	Cycles int
+	// Failures is number of failed results
+	Failures int
+}
+
+var (
+	ErrInvalidCycle = errors.New("invalid power cycle")
+)
+
+func NewPowerCycle(opts ...CycleOption) (*PowerCycle, error) {
+	c := &PowerCycle{
+		access:        nil,
+		sections:      nil,
+		off:           1 * time.Second,
+		on:            1 * time.Second,
+		settle:        0,
+		cycles:        1,
+		stopOnFailure: false,
+		handler:       nil,
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.verify(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Cycles returns number of cycles done by Run
+func (c *PowerCycle) Cycles() int {
+	return c.cycles
+}
+
+// Run executes cycles until all are done or ctx is done. Failures are reported to handler and counted,
+// unless stopOnFailure is set, in which case first failure is returned. Outputs are left as they are on return.
+func (c *PowerCycle) Run(ctx context.Context) (CycleSummary, error) {
+	summary := CycleSummary{}
+	for cycle := 1; cycle <= c.cycles; cycle++ {
+		results, err := c.cycle(ctx, cycle)
+		for _, result := range results {
+			c.report(result)
+			if result.Err != nil {
+				summary.Failures++
+				if c.stopOnFailure && err == nil {
+					err = result.Err
+				}
+			}
+		}
+		if err != nil {
+			return summary, err
+		}
+		summary.Cycles++
+	}
+	log.Debug("power cycle done: ", summary.Cycles, " cycles, ", summary.Failures, " failures")
+	return summary, nil
+}
+
+// cycle returns result of each section, error is returned only when ctx is done
+func (c *PowerCycle) cycle(ctx context.Context, cycle int) ([]CycleResult, error) {
+	results := make([]CycleResult, len(c.sections))
+	for i, section := range c.sections {
+		results[i] = CycleResult{Cycle: cycle, Section: section}
+		results[i].Err = c.switchTo(section, false)
+	}
+	if err := sleep(ctx, c.off); err != nil {
+		return nil, err
+	}
+
+	for i, section := range c.sections {
+		if results[i].Err != nil {
+			// Section which didn't switch off, didn't power up either
+			continue
+		}
+		results[i].Err = c.switchTo(section, true)
+	}
+	if err := sleep(ctx, c.settle); err != nil {
+		return nil, err
+	}
+	for i, section := range c.sections {
+		if results[i].Err == nil {
+			results[i].Current, results[i].Err = c.measure(section)
+		}
+	}
+	return results, sleep(ctx, c.on-c.settle)
+}
+
+func (c *PowerCycle) switchTo(section int, state bool) error {
+	switched, err := c.access.SetState(section, state)
+	if err != nil {
+		return err
+	}
+	if switched != state {
+		return ErrNotSwitched
+	}
+	return nil
+}
+
+func (c *PowerCycle) measure(section int) (float64, error) {
+	s, err := c.access.Section(section)
+	if err != nil {
+		return 0, err
+	}
+	if s.Trip != 0 {
+		return 0, fmt.Errorf("%w: %v", ErrTripped, s.Trip)
+	}
+	return strconv.ParseFloat(s.ActualCurrent, 64)
+}
+
+func (c *PowerCycle) report(result CycleResult) {
+	if result.Err != nil {
+		log.Error("power cycle ", result.Cycle, " of section ", result.Section, " failed: ", result.Err)
+	} else {
+		log.Debug("power cycle ", result.Cycle, " of section ", result.Section, ": ", result.Current, " A on power-up")
+	}
+	if c.handler != nil {
+		c.handler(result)
+	}
+}
+
+func (c *PowerCycle) verify() error {
+	if c.access == nil {
+		return ErrNoAccess
+	}
+	if len(c.sections) == 0 {
+		return ErrNoSection
+	}
+	for _, section := range c.sections {
+		if section < 1 {
+			return ErrInvalidCycle
+		}
+	}
+	if c.settle > c.on {
+		// Settle is part of on time, output would stay on longer
+		return fmt.Errorf("%w: settle %v is longer than on time %v", ErrInvalidCycle, c.settle, c.on)
+	}
+	return nil
+}
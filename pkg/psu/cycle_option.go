@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"time"
+)
+
+type CycleOption func(*PowerCycle) error
+
+func CycleWithPSU(p *PSU) CycleOption {
+	return func(c *PowerCycle) error {
+		return CycleWithAccess(p)(c)
+	}
+}
+
+func CycleWithAccess(a Access) CycleOption {
+	return func(c *PowerCycle) error {
+		c.access = a
+		return nil
+	}
+}
+
+// CycleWithSections sets sections switched together in each cycle
+func CycleWithSections(sections ...int) CycleOption {
+	return func(c *PowerCycle) error {
+		c.sections = append(c.sections, sections...)
+		return nil
+	}
+}
+
+// CycleWithOffTime sets how long outputs stay off, 1 s by default
+func CycleWithOffTime(t time.Duration) CycleOption {
+	return func(c *PowerCycle) error {
+		if t <= 0 {
+			return ErrInvalidPeriod
+		}
+		c.off = t
+		return nil
+	}
+}
+
+// CycleWithOnTime sets how long outputs stay on, 1 s by default
+func CycleWithOnTime(t time.Duration) CycleOption {
+	return func(c *PowerCycle) error {
+		if t <= 0 {
+			return ErrInvalidPeriod
+		}
+		c.on = t
+		return nil
+	}
+}
+
+// CycleWithSettle sets time between switching on and measuring current, which is counted into on time,
+// so it can't be longer than on time
+func CycleWithSettle(t time.Duration) CycleOption {
+	return func(c *PowerCycle) error {
+		if t < 0 {
+			return ErrInvalidPeriod
+		}
+		c.settle = t
+		return nil
+	}
+}
+
+// CycleWithCycles sets number of cycles, 1 by default
+func CycleWithCycles(n int) CycleOption {
+	return func(c *PowerCycle) error {
+		if n < 1 {
+			return ErrInvalidCycle
+		}
+		c.cycles = n
+		return nil
+	}
+}
+
+// CycleWithStopOnFailure stops Run on the first failure, by default failures are only counted
+func CycleWithStopOnFailure(stop bool) CycleOption {
+	return func(c *PowerCycle) error {
+		c.stopOnFailure = stop
+		return nil
+	}
+}
+
+// CycleWithHandler sets function called with result of each section after each cycle
+func CycleWithHandler(handler func(CycleResult)) CycleOption {
+	return func(c *PowerCycle) error {
+		c.handler = handler
+		return nil
+	}
+}
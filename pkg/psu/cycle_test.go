@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/psu"
+)
+
+type CycleTestSuite struct {
+	suite.Suite
+	mock *AccessMocker
+}
+
+func TestCycle(t *testing.T) {
+	suite.Run(t, new(CycleTestSuite))
+}
+
+func (t *CycleTestSuite) SetupTest() {
+	t.mock = new(AccessMocker)
+}
+
+func (t *CycleTestSuite) cycle(opts ...psu.CycleOption) (*psu.PowerCycle, *[]psu.CycleResult) {
+	results := new([]psu.CycleResult)
+	opts = append([]psu.CycleOption{
+		psu.CycleWithAccess(t.mock),
+		psu.CycleWithOffTime(time.Millisecond),
+		psu.CycleWithOnTime(time.Millisecond),
+		psu.CycleWithHandler(func(result psu.CycleResult) {
+			*results = append(*results, result)
+		}),
+	}, opts...)
+	c, err := psu.NewPowerCycle(opts...)
+	t.Require().Nil(err)
+	return c, results
+}
+
+func (t *CycleTestSuite) TestNew() {
+	args := []struct {
+		name string
+		opts []psu.CycleOption
+		err  error
+	}{
+		{name: "no access", opts: nil, err: psu.ErrNoAccess},
+		{name: "no sections", opts: []psu.CycleOption{psu.CycleWithAccess(t.mock)}, err: psu.ErrNoSection},
+		{name: "invalid section", opts: []psu.CycleOption{psu.CycleWithAccess(t.mock), psu.CycleWithSections(0)}, err: psu.ErrInvalidCycle},
+		{name: "cycles", opts: []psu.CycleOption{psu.CycleWithCycles(0)}, err: psu.ErrInvalidCycle},
+		{name: "off time", opts: []psu.CycleOption{psu.CycleWithOffTime(0)}, err: psu.ErrInvalidPeriod},
+		{name: "on time", opts: []psu.CycleOption{psu.CycleWithOnTime(0)}, err: psu.ErrInvalidPeriod},
+		{name: "settle", opts: []psu.CycleOption{psu.CycleWithSettle(-1)}, err: psu.ErrInvalidPeriod},
+		{
+			name: "settle longer than on",
+			opts: []psu.CycleOption{psu.CycleWithAccess(t.mock), psu.CycleWithSections(1), psu.CycleWithOnTime(time.Second), psu.CycleWithSettle(2 * time.Second)},
+			err:  psu.ErrInvalidCycle,
+		},
+		{name: "all good", opts: []psu.CycleOption{psu.CycleWithAccess(t.mock), psu.CycleWithSections(1, 2)}, err: nil},
+	}
+	for _, arg := range args {
+		c, err := psu.NewPowerCycle(arg.opts...)
+		t.ErrorIs(err, arg.err, arg.name)
+		t.Equal(arg.err == nil, c != nil, arg.name)
+	}
+}
+
+func (t *CycleTestSuite) TestRun() {
+	r := t.Require()
+	t.mock.On("SetState", 1, false).Return(false, nil).Times(3)
+	t.mock.On("SetState", 2, false).Return(false, nil).Times(3)
+	t.mock.On("SetState", 1, true).Return(true, nil).Times(3)
+	t.mock.On("SetState", 2, true).Return(true, nil).Times(3)
+	t.mock.On("Section", 1).Return(section("12.00", "0.250"), nil).Times(3)
+	t.mock.On("Section", 2).Return(section("5.00", "1.500"), nil).Times(3)
+
+	c, results := t.cycle(psu.CycleWithSections(1, 2), psu.CycleWithCycles(3), psu.CycleWithSettle(time.Millisecond))
+	summary, err := c.Run(context.Background())
+	r.Nil(err)
+	r.Equal(psu.CycleSummary{Cycles: 3, Failures: 0}, summary)
+	r.Len(*results, 6)
+	r.Equal(psu.CycleResult{Cycle: 3, Section: 2, Current: 1.5}, (*results)[5])
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *CycleTestSuite) TestFailuresCounted() {
+	r := t.Require()
+	tripped := section("0.00", "0.000")
+	tripped.Trip = psu.TripOverCurrent
+	t.mock.On("SetState", 1, false).Return(false, nil)
+	t.mock.On("SetState", 1, true).Return(true, nil)
+	t.mock.On("Section", 1).Return(tripped, nil).Once()
+	t.mock.On("Section", 1).Return(section("12.00", "0.250"), nil)
+
+	c, results := t.cycle(psu.CycleWithSections(1), psu.CycleWithCycles(2))
+	summary, err := c.Run(context.Background())
+	r.Nil(err)
+	r.Equal(psu.CycleSummary{Cycles: 2, Failures: 1}, summary)
+	r.ErrorIs((*results)[0].Err, psu.ErrTripped)
+	r.Nil((*results)[1].Err)
+}
+
+func (t *CycleTestSuite) TestStopOnFailure() {
+	r := t.Require()
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+	t.mock.On("SetState", 2, false).Return(true, nil).Once()
+	t.mock.On("SetState", 1, true).Return(false, errors.New("timeout")).Once()
+
+	c, results := t.cycle(psu.CycleWithSections(1, 2), psu.CycleWithCycles(5), psu.CycleWithStopOnFailure(true))
+	summary, err := c.Run(context.Background())
+	// First failure is returned, but all results of cycle are reported
+	r.EqualError(err, "timeout")
+	r.Equal(psu.CycleSummary{Cycles: 0, Failures: 2}, summary)
+	r.Len(*results, 2)
+	r.ErrorIs((*results)[1].Err, psu.ErrNotSwitched)
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *CycleTestSuite) TestCancel() {
+	r := t.Require()
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+
+	c, results := t.cycle(psu.CycleWithSections(1), psu.CycleWithOffTime(time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	summary, err := c.Run(ctx)
+	r.ErrorIs(err, context.DeadlineExceeded)
+	r.Equal(0, summary.Cycles)
+	r.Empty(*results)
+	t.mock.AssertExpectations(t.T())
+}
@@ -0,0 +1,331 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Format of DataLogger files
+type Format int
+
+const (
+	FormatCSV Format = iota
+	FormatJSONL
+)
+
+// Record is single row written by DataLogger
+type Record struct {
+	Time          time.Time `json:"time"`
+	Section       int       `json:"section"`
+	State         bool      `json:"state"`
+	SetVoltage    string    `json:"set_voltage"`
+	ActualVoltage string    `json:"actual_voltage"`
+	SetCurrent    string    `json:"set_current"`
+	ActualCurrent string    `json:"actual_current"`
+	Mode          string    `json:"mode"`
+	// Error is set, when readings couldn't be taken
+	Error string `json:"error,omitempty"`
+}
+
+// DataLogger periodically takes Section readings and writes them to CSV or JSON Lines files
+type DataLogger struct {
+	access   Access
+	sections []int
+	interval time.Duration
+	// flush is interval of flushing buffered rows to file, zero flushes every row
+	flush  time.Duration
+	dir    string
+	prefix string
+	format Format
+	// maxSize and maxAge trigger file rotation, zero disables
+	maxSize int64
+	maxAge  time.Duration
+
+	mtx     sync.Mutex
+	running bool
+	file    *os.File
+	writer  *bufio.Writer
+	written int64
+	opened  time.Time
+}
+
+var (
+	ErrUnknownFormat = errors.New("unknown format")
+	ErrInvalidPeriod = errors.New("period must be positive")
+	ErrInvalidSize   = errors.New("size can't be negative")
+	ErrNoDirectory   = errors.New("no directory for log files")
+	ErrRunning       = errors.New("already running")
+)
+
+var csvHeader = []string{"time", "section", "state", "set_voltage", "actual_voltage", "set_current", "actual_current", "mode", "error"}
+
+func NewDataLogger(opts ...DataLogOption) (*DataLogger, error) {
+	d := &DataLogger{
+		access:   nil,
+		sections: nil,
+		interval: 1 * time.Second,
+		flush:    10 * time.Second,
+		dir:      "",
+		prefix:   "psu",
+		format:   FormatCSV,
+		maxSize:  0,
+		maxAge:   0,
+	}
+	for _, opt := range opts {
+		if err := opt(d); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.verify(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ParseFormat returns Format based on its name: "csv" or "jsonl"
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "csv":
+		return FormatCSV, nil
+	case "jsonl", "json":
+		return FormatJSONL, nil
+	default:
+		return FormatCSV, fmt.Errorf("%w: %s", ErrUnknownFormat, name)
+	}
+}
+
+func (f Format) String() string {
+	switch f {
+	case FormatJSONL:
+		return "jsonl"
+	default:
+		return "csv"
+	}
+}
+
+// Run takes readings until ctx is done. Each Run starts with new file.
+func (d *DataLogger) Run(ctx context.Context) error {
+	d.mtx.Lock()
+	if d.running {
+		d.mtx.Unlock()
+		return ErrRunning
+	}
+	d.running = true
+	d.mtx.Unlock()
+
+	defer func() {
+		d.mtx.Lock()
+		d.running = false
+		d.mtx.Unlock()
+	}()
+
+	if err := os.MkdirAll(d.dir, 0o755); err != nil {
+		return err
+	}
+	if err := d.open(); err != nil {
+		return err
+	}
+	defer d.close()
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	var flush <-chan time.Time
+	if d.flush > 0 {
+		flushTicker := time.NewTicker(d.flush)
+		defer flushTicker.Stop()
+		flush = flushTicker.C
+	}
+
+	if err := d.sample(); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := d.sample(); err != nil {
+				return err
+			}
+		case <-flush:
+			if err := d.writer.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Running returns true, if Run is in progress
+func (d *DataLogger) Running() bool {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return d.running
+}
+
+// File returns path to the file written currently, empty if not running
+func (d *DataLogger) File() string {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.file == nil {
+		return ""
+	}
+	return d.file.Name()
+}
+
+func (d *DataLogger) sample() error {
+	for _, section := range d.sections {
+		record := Record{Time: time.Now(), Section: section}
+		if s, err := d.access.Section(section); err != nil {
+			record.Error = err.Error()
+		} else {
+			record.State = s.State
+			record.SetVoltage = s.SetVoltage
+			record.ActualVoltage = s.ActualVoltage
+			record.SetCurrent = s.SetCurrent
+			record.ActualCurrent = s.ActualCurrent
+			record.Mode = s.Mode.String()
+		}
+		if err := d.write(record); err != nil {
+			return err
+		}
+	}
+	if d.flush == 0 {
+		return d.writer.Flush()
+	}
+	return nil
+}
+
+func (d *DataLogger) write(record Record) error {
+	if d.rotationNeeded() {
+		d.close()
+		if err := d.open(); err != nil {
+			return err
+		}
+	}
+	row, err := d.encode(record)
+	if err != nil {
+		return err
+	}
+	n, err := d.writer.Write(row)
+	d.written += int64(n)
+	return err
+}
+
+func (d *DataLogger) rotationNeeded() bool {
+	if d.maxSize > 0 && d.written >= d.maxSize {
+		return true
+	}
+	if d.maxAge > 0 && time.Since(d.opened) >= d.maxAge {
+		return true
+	}
+	return false
+}
+
+func (d *DataLogger) encode(record Record) ([]byte, error) {
+	if d.format == FormatJSONL {
+		row, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		return append(row, '\n'), nil
+	}
+	return d.csvRow([]string{
+		record.Time.Format(time.RFC3339Nano),
+		strconv.FormatInt(int64(record.Section), 10),
+		strconv.FormatBool(record.State),
+		record.SetVoltage,
+		record.ActualVoltage,
+		record.SetCurrent,
+		record.ActualCurrent,
+		record.Mode,
+		record.Error,
+	})
+}
+
+func (d *DataLogger) csvRow(fields []string) ([]byte, error) {
+	buf := strings.Builder{}
+	w := csv.NewWriter(&buf)
+	if err := w.Write(fields); err != nil {
+		return nil, err
+	}
+	w.Flush()
+	return []byte(buf.String()), w.Error()
+}
+
+func (d *DataLogger) open() error {
+	opened := time.Now()
+	base := filepath.Join(d.dir, d.prefix+"-"+opened.Format("20060102-150405.000"))
+	name := base + "." + d.format.String()
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	// Rotation may happen more than once per millisecond
+	for i := 1; errors.Is(err, os.ErrExist); i++ {
+		name = base + "-" + strconv.Itoa(i) + "." + d.format.String()
+		file, err = os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	}
+	if err != nil {
+		return err
+	}
+	log.Debug("Logging data to ", file.Name())
+
+	d.mtx.Lock()
+	d.file = file
+	d.mtx.Unlock()
+	d.writer = bufio.NewWriter(file)
+	d.written = 0
+	d.opened = opened
+
+	if d.format == FormatCSV {
+		header, err := d.csvRow(csvHeader)
+		if err != nil {
+			return err
+		}
+		n, err := d.writer.Write(header)
+		d.written += int64(n)
+		return err
+	}
+	return nil
+}
+
+func (d *DataLogger) close() {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.file == nil {
+		return
+	}
+	if err := d.writer.Flush(); err != nil {
+		log.Error("error on flushing data log: ", err)
+	}
+	if err := d.file.Close(); err != nil {
+		log.Error("error on closing data log: ", err)
+	}
+	d.file = nil
+}
+
+func (d *DataLogger) verify() error {
+	if d.access == nil {
+		return ErrNoAccess
+	}
+	if len(d.sections) == 0 {
+		return ErrNoSection
+	}
+	if d.dir == "" {
+		return ErrNoDirectory
+	}
+	return nil
+}
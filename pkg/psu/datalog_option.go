@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"time"
+)
+
+type DataLogOption func(*DataLogger) error
+
+func DataLogWithPSU(p *PSU) DataLogOption {
+	return func(d *DataLogger) error {
+		return DataLogWithAccess(p)(d)
+	}
+}
+
+func DataLogWithAccess(a Access) DataLogOption {
+	return func(d *DataLogger) error {
+		d.access = a
+		return nil
+	}
+}
+
+func DataLogWithSections(sections ...int) DataLogOption {
+	return func(d *DataLogger) error {
+		d.sections = append(d.sections, sections...)
+		return nil
+	}
+}
+
+// DataLogWithDir sets directory of log files, it is created if needed
+func DataLogWithDir(dir string) DataLogOption {
+	return func(d *DataLogger) error {
+		d.dir = dir
+		return nil
+	}
+}
+
+// DataLogWithPrefix sets prefix of log file names
+func DataLogWithPrefix(prefix string) DataLogOption {
+	return func(d *DataLogger) error {
+		d.prefix = prefix
+		return nil
+	}
+}
+
+func DataLogWithFormat(f Format) DataLogOption {
+	return func(d *DataLogger) error {
+		d.format = f
+		return nil
+	}
+}
+
+// DataLogWithInterval sets interval between readings
+func DataLogWithInterval(t time.Duration) DataLogOption {
+	return func(d *DataLogger) error {
+		if t <= 0 {
+			return ErrInvalidPeriod
+		}
+		d.interval = t
+		return nil
+	}
+}
+
+// DataLogWithFlushInterval sets how often rows are flushed to file, zero flushes after each reading
+func DataLogWithFlushInterval(t time.Duration) DataLogOption {
+	return func(d *DataLogger) error {
+		if t < 0 {
+			return ErrInvalidPeriod
+		}
+		d.flush = t
+		return nil
+	}
+}
+
+// DataLogWithRotation starts new file, when current one exceeds maxSize bytes or is older than maxAge.
+// Zero disables particular limit.
+func DataLogWithRotation(maxSize int64, maxAge time.Duration) DataLogOption {
+	return func(d *DataLogger) error {
+		if maxSize < 0 {
+			return ErrInvalidSize
+		}
+		if maxAge < 0 {
+			return ErrInvalidPeriod
+		}
+		d.maxSize = maxSize
+		d.maxAge = maxAge
+		return nil
+	}
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/psu"
+)
+
+type DataLoggerTestSuite struct {
+	suite.Suite
+	mock *AccessMocker
+	dir  string
+}
+
+func TestDataLogger(t *testing.T) {
+	suite.Run(t, new(DataLoggerTestSuite))
+}
+
+func (t *DataLoggerTestSuite) SetupTest() {
+	t.mock = new(AccessMocker)
+	t.dir = t.T().TempDir()
+}
+
+func (t *DataLoggerTestSuite) run(d *psu.DataLogger, duration time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	t.Require().Nil(d.Run(ctx))
+}
+
+func (t *DataLoggerTestSuite) files() []string {
+	files, err := filepath.Glob(filepath.Join(t.dir, "*"))
+	t.Require().Nil(err)
+	return files
+}
+
+func (t *DataLoggerTestSuite) TestNew() {
+	args := []struct {
+		name string
+		opts []psu.DataLogOption
+		err  error
+	}{
+		{name: "no access", opts: nil, err: psu.ErrNoAccess},
+		{name: "no section", opts: []psu.DataLogOption{psu.DataLogWithAccess(t.mock)}, err: psu.ErrNoSection},
+		{name: "no dir", opts: []psu.DataLogOption{psu.DataLogWithAccess(t.mock), psu.DataLogWithSections(1)}, err: psu.ErrNoDirectory},
+		{name: "interval", opts: []psu.DataLogOption{psu.DataLogWithInterval(0)}, err: psu.ErrInvalidPeriod},
+		{name: "rotation", opts: []psu.DataLogOption{psu.DataLogWithRotation(-1, 0)}, err: psu.ErrInvalidSize},
+		{
+			name: "all good",
+			opts: []psu.DataLogOption{psu.DataLogWithAccess(t.mock), psu.DataLogWithSections(1), psu.DataLogWithDir(t.dir)},
+			err:  nil,
+		},
+	}
+	for _, arg := range args {
+		d, err := psu.NewDataLogger(arg.opts...)
+		t.ErrorIs(err, arg.err, arg.name)
+		t.Equal(arg.err == nil, d != nil, arg.name)
+	}
+}
+
+func (t *DataLoggerTestSuite) TestCSV() {
+	r := t.Require()
+	var nilSection *psu.Section
+	t.mock.On("Section", 1).Return(&psu.Section{
+		State:         true,
+		ActualVoltage: "1.00",
+		SetVoltage:    "2.00",
+		ActualCurrent: "0.100",
+		SetCurrent:    "0.200",
+		Mode:          psu.ModeCC,
+	}, nil)
+	t.mock.On("Section", 2).Return(nilSection, errors.New("timeout"))
+
+	d, err := psu.NewDataLogger(
+		psu.DataLogWithAccess(t.mock),
+		psu.DataLogWithSections(1, 2),
+		psu.DataLogWithDir(t.dir),
+		psu.DataLogWithInterval(time.Hour),
+	)
+	r.Nil(err)
+	t.run(d, 20*time.Millisecond)
+
+	files := t.files()
+	r.Len(files, 1)
+	r.Equal(".csv", filepath.Ext(files[0]))
+
+	f, err := os.Open(files[0])
+	r.Nil(err)
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	r.Nil(err)
+	r.Len(rows, 3)
+	r.Equal([]string{"time", "section", "state", "set_voltage", "actual_voltage", "set_current", "actual_current", "mode", "error"}, rows[0])
+	r.Equal([]string{"1", "true", "2.00", "1.00", "0.200", "0.100", "CC", ""}, rows[1][1:])
+	r.Equal([]string{"2", "false", "", "", "", "", "", "timeout"}, rows[2][1:])
+}
+
+func (t *DataLoggerTestSuite) TestJSONL() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(&psu.Section{
+		State:         true,
+		ActualVoltage: "1.00",
+		SetVoltage:    "2.00",
+		ActualCurrent: "0.100",
+		SetCurrent:    "0.200",
+		Mode:          psu.ModeCV,
+	}, nil)
+
+	d, err := psu.NewDataLogger(
+		psu.DataLogWithAccess(t.mock),
+		psu.DataLogWithSections(1),
+		psu.DataLogWithDir(t.dir),
+		psu.DataLogWithFormat(psu.FormatJSONL),
+		psu.DataLogWithInterval(10*time.Millisecond),
+		psu.DataLogWithFlushInterval(0),
+	)
+	r.Nil(err)
+	t.run(d, 35*time.Millisecond)
+
+	files := t.files()
+	r.Len(files, 1)
+	r.Equal(".jsonl", filepath.Ext(files[0]))
+
+	f, err := os.Open(files[0])
+	r.Nil(err)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	rows := 0
+	for scanner.Scan() {
+		var record psu.Record
+		r.Nil(json.Unmarshal(scanner.Bytes(), &record))
+		r.Equal(1, record.Section)
+		r.Equal("CV", record.Mode)
+		r.Equal("0.100", record.ActualCurrent)
+		rows++
+	}
+	r.GreaterOrEqual(rows, 3)
+}
+
+func (t *DataLoggerTestSuite) TestRotation() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(&psu.Section{ActualVoltage: "1.00"}, nil)
+
+	d, err := psu.NewDataLogger(
+		psu.DataLogWithAccess(t.mock),
+		psu.DataLogWithSections(1),
+		psu.DataLogWithDir(t.dir),
+		psu.DataLogWithFormat(psu.FormatJSONL),
+		psu.DataLogWithInterval(5*time.Millisecond),
+		// Each row exceeds limit
+		psu.DataLogWithRotation(1, 0),
+	)
+	r.Nil(err)
+	t.run(d, 30*time.Millisecond)
+	r.Greater(len(t.files()), 2)
+	r.False(d.Running())
+	r.Equal("", d.File())
+}
+
+func (t *DataLoggerTestSuite) TestParseFormat() {
+	r := t.Require()
+	f, err := psu.ParseFormat("CSV")
+	r.Nil(err)
+	r.Equal(psu.FormatCSV, f)
+
+	f, err = psu.ParseFormat("jsonl")
+	r.Nil(err)
+	r.Equal(psu.FormatJSONL, f)
+
+	_, err = psu.ParseFormat("xml")
+	r.ErrorIs(err, psu.ErrUnknownFormat)
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"sync"
+	"time"
+)
+
+// Energy is power usage of single output
+type Energy struct {
+	// Power is computed from the latest readings, in W
+	Power float64
+	// Energy is integrated power, in Wh
+	Energy float64
+	// Charge is integrated current, in Ah
+	Charge float64
+	// Duration is time covered by integration, gaps are not included
+	Duration time.Duration
+}
+
+// Accumulator integrates actual voltage and current of successive Section readings.
+// Readings more than maxGap apart (or separated by lost connection) are not integrated.
+type Accumulator struct {
+	mtx     sync.Mutex
+	maxGap  time.Duration
+	running bool
+	last    *sample
+	energy  Energy
+}
+
+type sample struct {
+	timestamp time.Time
+	voltage   float64
+	current   float64
+}
+
+// NewAccumulator returns running Accumulator
+func NewAccumulator(maxGap time.Duration) *Accumulator {
+	return &Accumulator{
+		maxGap:  maxGap,
+		running: true,
+		last:    nil,
+	}
+}
+
+// Add integrates readings taken at timestamp
+func (a *Accumulator) Add(timestamp time.Time, s *Section) error {
+	values, err := s.Values()
+	if err != nil {
+		a.Lost()
+		return err
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	next := &sample{timestamp: timestamp, voltage: values.ActualVoltage, current: values.ActualCurrent}
+	a.energy.Power = next.voltage * next.current
+	if !a.running {
+		return nil
+	}
+
+	if a.last != nil {
+		dt := next.timestamp.Sub(a.last.timestamp)
+		if dt > 0 && dt <= a.maxGap {
+			// Trapezoidal rule
+			hours := dt.Hours()
+			a.energy.Energy += (a.last.voltage*a.last.current + next.voltage*next.current) / 2 * hours
+			a.energy.Charge += (a.last.current + next.current) / 2 * hours
+			a.energy.Duration += dt
+		}
+	}
+	a.last = next
+	return nil
+}
+
+// Lost should be called, when readings couldn't be taken - integration restarts with the next readings
+func (a *Accumulator) Lost() {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.last = nil
+	a.energy.Power = 0
+}
+
+func (a *Accumulator) Start() {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.running = true
+}
+
+// Stop pauses integration, accumulated values are kept
+func (a *Accumulator) Stop() {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.running = false
+	a.last = nil
+}
+
+func (a *Accumulator) Running() bool {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.running
+}
+
+// Reset zeroes accumulated values
+func (a *Accumulator) Reset() {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.energy = Energy{Power: a.energy.Power}
+}
+
+func (a *Accumulator) Energy() Energy {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.energy
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/psu"
+)
+
+type AccumulatorTestSuite struct {
+	suite.Suite
+	now time.Time
+}
+
+func TestAccumulator(t *testing.T) {
+	suite.Run(t, new(AccumulatorTestSuite))
+}
+
+func (t *AccumulatorTestSuite) SetupTest() {
+	t.now = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (t *AccumulatorTestSuite) add(a *psu.Accumulator, after time.Duration, voltage, current string) {
+	t.now = t.now.Add(after)
+	s := &psu.Section{ActualVoltage: voltage, SetVoltage: "0", ActualCurrent: current, SetCurrent: "0"}
+	t.Require().Nil(a.Add(t.now, s))
+}
+
+func (t *AccumulatorTestSuite) TestIntegrate() {
+	r := t.Require()
+	a := psu.NewAccumulator(2 * time.Second)
+	t.add(a, 0, "10", "1")
+	t.add(a, time.Second, "10", "1")
+	t.add(a, time.Second, "10", "3")
+
+	e := a.Energy()
+	r.InDelta(30, e.Power, 1e-9)
+	// 10 Ws + 20 Ws
+	r.InDelta(30.0/3600, e.Energy, 1e-9)
+	// 1 As + 2 As
+	r.InDelta(3.0/3600, e.Charge, 1e-9)
+	r.Equal(2*time.Second, e.Duration)
+}
+
+func (t *AccumulatorTestSuite) TestGap() {
+	r := t.Require()
+	a := psu.NewAccumulator(2 * time.Second)
+	t.add(a, 0, "10", "1")
+	t.add(a, time.Second, "10", "1")
+	// Too long, skipped
+	t.add(a, 10*time.Second, "10", "1")
+	t.add(a, time.Second, "10", "1")
+
+	e := a.Energy()
+	r.InDelta(20.0/3600, e.Energy, 1e-9)
+	r.Equal(2*time.Second, e.Duration)
+}
+
+func (t *AccumulatorTestSuite) TestLost() {
+	r := t.Require()
+	a := psu.NewAccumulator(time.Minute)
+	t.add(a, 0, "10", "1")
+	a.Lost()
+	t.add(a, time.Second, "10", "1")
+	r.Zero(a.Energy().Energy)
+
+	t.add(a, time.Second, "10", "1")
+	r.InDelta(10.0/3600, a.Energy().Energy, 1e-9)
+
+	// Unparsable readings are treated as lost connection
+	r.NotNil(a.Add(t.now, &psu.Section{ActualVoltage: "err"}))
+	t.add(a, time.Second, "10", "1")
+	r.InDelta(10.0/3600, a.Energy().Energy, 1e-9)
+}
+
+func (t *AccumulatorTestSuite) TestStartStopReset() {
+	r := t.Require()
+	a := psu.NewAccumulator(time.Minute)
+	r.True(a.Running())
+	t.add(a, 0, "10", "1")
+	t.add(a, time.Second, "10", "1")
+
+	a.Stop()
+	r.False(a.Running())
+	t.add(a, time.Second, "10", "1")
+	t.add(a, time.Second, "10", "2")
+	r.InDelta(10.0/3600, a.Energy().Energy, 1e-9)
+	// Power is always up-to-date
+	r.InDelta(20, a.Energy().Power, 1e-9)
+
+	a.Start()
+	t.add(a, time.Second, "10", "1")
+	t.add(a, time.Second, "10", "1")
+	r.InDelta(20.0/3600, a.Energy().Energy, 1e-9)
+
+	a.Reset()
+	e := a.Energy()
+	r.Zero(e.Energy)
+	r.Zero(e.Charge)
+	r.Zero(e.Duration)
+	t.add(a, time.Second, "10", "1")
+	r.InDelta(10.0/3600, a.Energy().Energy, 1e-9)
+}
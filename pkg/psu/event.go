@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// EventKind tells what happened to PSU
+type EventKind int
+
+const (
+	// EventSwitched - output state changed
+	EventSwitched EventKind = iota
+	// EventTripped - protection tripped
+	EventTripped
+	// EventLimit - software limit exceeded, see Limiter
+	EventLimit
+	EventConnectionLost
+	EventConnectionRestored
+)
+
+// Event is produced by Watcher and Limiter, Section is zero for connection events
+type Event struct {
+	Kind    EventKind
+	Time    time.Time
+	Section int
+	// State of output, set by EventSwitched
+	State bool
+	// Local is true, if output was switched through Watcher, not e.g. from front panel
+	Local bool
+	// Trip is set by EventTripped
+	Trip Trip
+	// Reason describes event, e.g. exceeded limit
+	Reason string
+	// Values are readings, which triggered event, if available
+	Values *Values
+	// Err is set by EventConnectionLost and by EventLimit, if output couldn't be switched off
+	Err error
+}
+
+var (
+	ErrUnknownEvent = errors.New("unknown event")
+)
+
+var eventNames = map[EventKind]string{
+	EventSwitched:           "switched",
+	EventTripped:            "trip",
+	EventLimit:              "limit",
+	EventConnectionLost:     "connection_lost",
+	EventConnectionRestored: "connection_restored",
+}
+
+func (k EventKind) String() string {
+	if name, ok := eventNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseEventKind returns EventKind of name returned by EventKind.String
+func ParseEventKind(name string) (EventKind, error) {
+	for kind, n := range eventNames {
+		if strings.EqualFold(n, name) {
+			return kind, nil
+		}
+	}
+	return 0, ErrUnknownEvent
+}
+
+// Event converts LimitEvent
+func (e LimitEvent) Event() Event {
+	values := e.Values
+	return Event{
+		Kind:    EventLimit,
+		Time:    e.Time,
+		Section: e.Section,
+		Reason:  e.Reason,
+		Values:  &values,
+		Err:     e.Err,
+	}
+}
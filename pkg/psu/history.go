@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"sync"
+	"time"
+)
+
+// Sample is reading of single section, Lost marks gap, e.g. lost connection
+type Sample struct {
+	Time   time.Time
+	Values Values
+	Lost   bool
+}
+
+// History keeps readings of single section, which are not older than maxAge
+type History struct {
+	mtx     sync.Mutex
+	maxAge  time.Duration
+	samples []Sample
+}
+
+func NewHistory(maxAge time.Duration) *History {
+	return &History{
+		maxAge:  maxAge,
+		samples: nil,
+	}
+}
+
+// Add keeps readings taken at timestamp
+func (h *History) Add(timestamp time.Time, s *Section) error {
+	values, err := s.Values()
+	if err != nil {
+		h.Lost(timestamp)
+		return err
+	}
+	h.add(Sample{Time: timestamp, Values: values})
+	return nil
+}
+
+// Lost marks, that readings weren't available at timestamp
+func (h *History) Lost(timestamp time.Time) {
+	h.add(Sample{Time: timestamp, Lost: true})
+}
+
+// Samples returns copy of samples taken between from and to, inclusive
+func (h *History) Samples(from, to time.Time) []Sample {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	var samples []Sample
+	for _, s := range h.samples {
+		if !s.Time.Before(from) && !s.Time.After(to) {
+			samples = append(samples, s)
+		}
+	}
+	return samples
+}
+
+func (h *History) add(s Sample) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.samples = append(h.samples, s)
+	oldest := s.Time.Add(-h.maxAge)
+	i := 0
+	for i < len(h.samples) && h.samples[i].Time.Before(oldest) {
+		i++
+	}
+	if i > 0 {
+		// Drop references to old samples, so backing array doesn't grow forever
+		h.samples = append([]Sample{}, h.samples[i:]...)
+	}
+}
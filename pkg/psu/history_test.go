@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/psu"
+)
+
+type HistoryTestSuite struct {
+	suite.Suite
+}
+
+func TestHistory(t *testing.T) {
+	suite.Run(t, new(HistoryTestSuite))
+}
+
+func (t *HistoryTestSuite) TestSamples() {
+	r := t.Require()
+	h := psu.NewHistory(time.Minute)
+	start := time.Date(2023, 5, 2, 10, 0, 0, 0, time.UTC)
+
+	r.Nil(h.Add(start, section("5.00", "1.000")))
+	h.Lost(start.Add(time.Second))
+	r.NotNil(h.Add(start.Add(2*time.Second), &psu.Section{ActualVoltage: "err"}))
+	r.Nil(h.Add(start.Add(3*time.Second), section("5.10", "1.100")))
+
+	samples := h.Samples(start, start.Add(time.Minute))
+	r.Len(samples, 4)
+	r.Equal(5.0, samples[0].Values.ActualVoltage)
+	r.True(samples[1].Lost)
+	// Readings, which can't be parsed, are gap too
+	r.True(samples[2].Lost)
+	r.False(samples[3].Lost)
+
+	samples = h.Samples(start.Add(time.Second), start.Add(2*time.Second))
+	r.Len(samples, 2)
+}
+
+func (t *HistoryTestSuite) TestMaxAge() {
+	r := t.Require()
+	h := psu.NewHistory(10 * time.Second)
+	start := time.Date(2023, 5, 2, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		r.Nil(h.Add(start.Add(time.Duration(i)*time.Second), section(strconv.Itoa(i), "1.000")))
+	}
+	samples := h.Samples(start, start.Add(time.Hour))
+	r.Len(samples, 11)
+	r.Equal(19.0, samples[0].Values.ActualVoltage)
+	r.Equal(29.0, samples[10].Values.ActualVoltage)
+}
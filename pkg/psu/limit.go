@@ -0,0 +1,202 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Limit is software limit of section, zero value disables particular limit
+type Limit struct {
+	Section    int
+	MaxCurrent float64
+	MinVoltage float64
+	MaxVoltage float64
+	MaxPower   float64
+	// For is how long limit must be exceeded, before output is switched off
+	For time.Duration
+}
+
+// LimitEvent is recorded, when Limiter switched output off
+type LimitEvent struct {
+	Time    time.Time
+	Section int
+	// Reason describes exceeded limit, e.g. "current 2.100 A > 2.000 A"
+	Reason string
+	// Values are readings, which triggered event
+	Values Values
+	// Err is set, if output couldn't be switched off
+	Err error
+}
+
+// Limiter watches readings of sections and switches output off, when its Limit is exceeded for Limit.For.
+// Limits are checked only while output is on.
+type Limiter struct {
+	access   Access
+	limits   []Limit
+	interval time.Duration
+	handler  func(LimitEvent)
+	// since holds time each section exceeds its limit since
+	since map[int]time.Time
+
+	mtx    sync.Mutex
+	events []LimitEvent
+}
+
+// maxEvents is number of events kept by Limiter
+const maxEvents = 100
+
+var (
+	ErrInvalidLimit = errors.New("invalid limit")
+)
+
+func NewLimiter(opts ...LimiterOption) (*Limiter, error) {
+	l := &Limiter{
+		access:   nil,
+		limits:   nil,
+		interval: 200 * time.Millisecond,
+		handler:  nil,
+		since:    make(map[int]time.Time),
+	}
+	for _, opt := range opts {
+		if err := opt(l); err != nil {
+			return nil, err
+		}
+	}
+	if err := l.verify(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Run checks limits every interval, until ctx is done
+func (l *Limiter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	for {
+		for _, limit := range l.limits {
+			l.check(limit, time.Now())
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Limits returns configured limits
+func (l *Limiter) Limits() []Limit {
+	return append([]Limit(nil), l.limits...)
+}
+
+// Events returns recorded events, the oldest first
+func (l *Limiter) Events() []LimitEvent {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return append([]LimitEvent(nil), l.events...)
+}
+
+func (l *Limiter) check(limit Limit, now time.Time) {
+	s, err := l.access.Section(limit.Section)
+	if err != nil {
+		// Limit can't be exceeded for longer than For, unless it is read. Reading after outage starts over.
+		log.Debug("limiter: error on reading section ", limit.Section, ": ", err)
+		delete(l.since, limit.Section)
+		return
+	}
+	if !s.State {
+		delete(l.since, limit.Section)
+		return
+	}
+	values, err := s.Values()
+	if err != nil {
+		log.Debug("limiter: invalid reading of section ", limit.Section, ": ", err)
+		delete(l.since, limit.Section)
+		return
+	}
+	reason := limit.Exceeded(values)
+	if reason == "" {
+		delete(l.since, limit.Section)
+		return
+	}
+	since, ok := l.since[limit.Section]
+	if !ok {
+		since = now
+		l.since[limit.Section] = now
+	}
+	if now.Sub(since) < limit.For {
+		return
+	}
+
+	delete(l.since, limit.Section)
+	event := LimitEvent{Time: now, Section: limit.Section, Reason: reason, Values: values}
+	state, err := l.access.SetState(limit.Section, false)
+	if err == nil && state {
+		err = ErrNotSwitched
+	}
+	event.Err = err
+	if err != nil {
+		log.Error("limiter: section ", limit.Section, " exceeded ", reason, ", but wasn't switched off: ", err)
+	} else {
+		log.Error("limiter: section ", limit.Section, " switched off, ", reason)
+	}
+	l.record(event)
+}
+
+func (l *Limiter) record(event LimitEvent) {
+	l.mtx.Lock()
+	l.events = append(l.events, event)
+	if len(l.events) > maxEvents {
+		l.events = l.events[len(l.events)-maxEvents:]
+	}
+	l.mtx.Unlock()
+	if l.handler != nil {
+		l.handler(event)
+	}
+}
+
+// Exceeded returns description of the first exceeded limit, empty if none is exceeded
+func (limit Limit) Exceeded(v Values) string {
+	switch {
+	case limit.MaxCurrent > 0 && v.ActualCurrent > limit.MaxCurrent:
+		return fmt.Sprintf("current %.3f A > %.3f A", v.ActualCurrent, limit.MaxCurrent)
+	case limit.MaxVoltage > 0 && v.ActualVoltage > limit.MaxVoltage:
+		return fmt.Sprintf("voltage %.2f V > %.2f V", v.ActualVoltage, limit.MaxVoltage)
+	case limit.MinVoltage > 0 && v.ActualVoltage < limit.MinVoltage:
+		return fmt.Sprintf("voltage %.2f V < %.2f V", v.ActualVoltage, limit.MinVoltage)
+	case limit.MaxPower > 0 && v.ActualVoltage*v.ActualCurrent > limit.MaxPower:
+		return fmt.Sprintf("power %.2f W > %.2f W", v.ActualVoltage*v.ActualCurrent, limit.MaxPower)
+	}
+	return ""
+}
+
+func (limit Limit) verify() error {
+	if limit.Section < 1 || limit.For < 0 {
+		return ErrInvalidLimit
+	}
+	if limit.MaxCurrent < 0 || limit.MinVoltage < 0 || limit.MaxVoltage < 0 || limit.MaxPower < 0 {
+		return ErrInvalidLimit
+	}
+	if limit.MaxVoltage > 0 && limit.MinVoltage > limit.MaxVoltage {
+		return ErrInvalidLimit
+	}
+	return nil
+}
+
+func (l *Limiter) verify() error {
+	if l.access == nil {
+		return ErrNoAccess
+	}
+	if len(l.limits) == 0 {
+		return ErrInvalidLimit
+	}
+	return nil
+}
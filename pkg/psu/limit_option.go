@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"time"
+)
+
+type LimiterOption func(*Limiter) error
+
+func LimiterWithPSU(p *PSU) LimiterOption {
+	return func(l *Limiter) error {
+		return LimiterWithAccess(p)(l)
+	}
+}
+
+func LimiterWithAccess(a Access) LimiterOption {
+	return func(l *Limiter) error {
+		l.access = a
+		return nil
+	}
+}
+
+// LimiterWithLimits adds limits, single section may have only one Limit
+func LimiterWithLimits(limits ...Limit) LimiterOption {
+	return func(l *Limiter) error {
+		for _, limit := range limits {
+			if err := limit.verify(); err != nil {
+				return err
+			}
+			for _, other := range l.limits {
+				if other.Section == limit.Section {
+					return ErrInvalidLimit
+				}
+			}
+			l.limits = append(l.limits, limit)
+		}
+		return nil
+	}
+}
+
+// LimiterWithInterval sets how often readings are checked, 200 ms by default
+func LimiterWithInterval(t time.Duration) LimiterOption {
+	return func(l *Limiter) error {
+		if t <= 0 {
+			return ErrInvalidPeriod
+		}
+		l.interval = t
+		return nil
+	}
+}
+
+// LimiterWithHandler sets function called with each event
+func LimiterWithHandler(handler func(LimitEvent)) LimiterOption {
+	return func(l *Limiter) error {
+		l.handler = handler
+		return nil
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/psu"
+)
+
+type LimitTestSuite struct {
+	suite.Suite
+	mock   *AccessMocker
+	events chan psu.LimitEvent
+}
+
+func TestLimit(t *testing.T) {
+	suite.Run(t, new(LimitTestSuite))
+}
+
+func (t *LimitTestSuite) SetupTest() {
+	t.mock = new(AccessMocker)
+	t.events = make(chan psu.LimitEvent, 10)
+}
+
+// run starts Limiter in background, it is stopped at the end of test
+func (t *LimitTestSuite) run(limits ...psu.Limit) *psu.Limiter {
+	l, err := psu.NewLimiter(
+		psu.LimiterWithAccess(t.mock),
+		psu.LimiterWithLimits(limits...),
+		psu.LimiterWithInterval(time.Millisecond),
+		psu.LimiterWithHandler(func(e psu.LimitEvent) {
+			select {
+			case t.events <- e:
+			default:
+			}
+		}),
+	)
+	t.Require().Nil(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.T().Cleanup(cancel)
+	go func() {
+		_ = l.Run(ctx)
+	}()
+	return l
+}
+
+func (t *LimitTestSuite) TestNew() {
+	args := []struct {
+		name string
+		opts []psu.LimiterOption
+		err  error
+	}{
+		{name: "no access", opts: []psu.LimiterOption{psu.LimiterWithLimits(psu.Limit{Section: 1})}, err: psu.ErrNoAccess},
+		{name: "no limits", opts: []psu.LimiterOption{psu.LimiterWithAccess(t.mock)}, err: psu.ErrInvalidLimit},
+		{name: "section", opts: []psu.LimiterOption{psu.LimiterWithLimits(psu.Limit{Section: 0})}, err: psu.ErrInvalidLimit},
+		{name: "negative", opts: []psu.LimiterOption{psu.LimiterWithLimits(psu.Limit{Section: 1, MaxPower: -1})}, err: psu.ErrInvalidLimit},
+		{name: "window", opts: []psu.LimiterOption{psu.LimiterWithLimits(psu.Limit{Section: 1, MinVoltage: 5, MaxVoltage: 3})}, err: psu.ErrInvalidLimit},
+		{name: "duplicate", opts: []psu.LimiterOption{psu.LimiterWithLimits(psu.Limit{Section: 1}, psu.Limit{Section: 1})}, err: psu.ErrInvalidLimit},
+		{name: "interval", opts: []psu.LimiterOption{psu.LimiterWithInterval(0)}, err: psu.ErrInvalidPeriod},
+		{
+			name: "all good",
+			opts: []psu.LimiterOption{psu.LimiterWithAccess(t.mock), psu.LimiterWithLimits(psu.Limit{Section: 1, MaxCurrent: 1}, psu.Limit{Section: 2})},
+			err:  nil,
+		},
+	}
+	for _, arg := range args {
+		l, err := psu.NewLimiter(arg.opts...)
+		t.ErrorIs(err, arg.err, arg.name)
+		t.Equal(arg.err == nil, l != nil, arg.name)
+	}
+}
+
+func (t *LimitTestSuite) TestExceeded() {
+	limit := psu.Limit{Section: 1, MaxCurrent: 2, MinVoltage: 4.5, MaxVoltage: 5.5, MaxPower: 8}
+	args := []struct {
+		values psu.Values
+		reason string
+	}{
+		{values: psu.Values{ActualVoltage: 5, ActualCurrent: 1}, reason: ""},
+		{values: psu.Values{ActualVoltage: 5, ActualCurrent: 2.1}, reason: "current 2.100 A > 2.000 A"},
+		{values: psu.Values{ActualVoltage: 5.6, ActualCurrent: 1}, reason: "voltage 5.60 V > 5.50 V"},
+		{values: psu.Values{ActualVoltage: 4, ActualCurrent: 1}, reason: "voltage 4.00 V < 4.50 V"},
+		{values: psu.Values{ActualVoltage: 5, ActualCurrent: 1.9}, reason: "power 9.50 W > 8.00 W"},
+	}
+	for _, arg := range args {
+		t.Equal(arg.reason, limit.Exceeded(arg.values))
+	}
+	t.Equal("", psu.Limit{Section: 1}.Exceeded(psu.Values{ActualVoltage: 30, ActualCurrent: 10}))
+}
+
+func (t *LimitTestSuite) TestSwitchOff() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(section("12.00", "0.500"), nil).Times(3)
+	t.mock.On("Section", 1).Return(section("12.00", "1.500"), nil)
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+
+	l := t.run(psu.Limit{Section: 1, MaxCurrent: 1, For: 5 * time.Millisecond})
+	start := time.Now()
+	e := <-t.events
+	r.GreaterOrEqual(time.Since(start), 5*time.Millisecond)
+	r.Equal(1, e.Section)
+	r.Equal("current 1.500 A > 1.000 A", e.Reason)
+	r.InDelta(1.5, e.Values.ActualCurrent, 1e-9)
+	r.Nil(e.Err)
+	r.Len(l.Events(), 1)
+}
+
+func (t *LimitTestSuite) TestOutageIgnored() {
+	var nilSection *psu.Section
+	// Limit is exceeded before and after outage, which lasts longer than For
+	t.mock.On("Section", 1).Return(section("12.00", "1.500"), nil).Once()
+	t.mock.On("Section", 1).Return(nilSection, errors.New("timeout")).Times(30)
+	t.mock.On("Section", 1).Return(section("12.00", "1.500"), nil).Once()
+	t.mock.On("Section", 1).Return(section("12.00", "0.500"), nil)
+
+	t.run(psu.Limit{Section: 1, MaxCurrent: 1, For: 10 * time.Millisecond})
+	// Condition may be checked after test, when suite fields belong to the next one
+	events := t.events
+	t.Never(func() bool { return len(events) > 0 }, 100*time.Millisecond, time.Millisecond)
+	t.mock.AssertNotCalled(t.T(), "SetState", 1, false)
+}
+
+func (t *LimitTestSuite) TestGlitchIgnored() {
+	r := t.Require()
+	// Limit is exceeded shortly, every few readings
+	for i := 0; i < 5; i++ {
+		t.mock.On("Section", 1).Return(section("12.00", "0.500"), nil).Times(3)
+		t.mock.On("Section", 1).Return(section("12.00", "1.500"), nil).Once()
+	}
+	t.mock.On("Section", 1).Return(section("12.00", "0.500"), nil)
+
+	l := t.run(psu.Limit{Section: 1, MaxCurrent: 1, For: 5 * time.Millisecond})
+	<-time.After(50 * time.Millisecond)
+	r.Empty(t.events)
+	r.Empty(l.Events())
+	t.mock.AssertNotCalled(t.T(), "SetState", 1, false)
+}
+
+func (t *LimitTestSuite) TestOutputOff() {
+	off := section("0.00", "0.000")
+	off.State = false
+	t.mock.On("Section", 2).Return(off, nil)
+
+	// Under-voltage of output, which is off, isn't a problem
+	t.run(psu.Limit{Section: 2, MinVoltage: 3})
+	<-time.After(20 * time.Millisecond)
+	t.Empty(t.events)
+	t.mock.AssertNotCalled(t.T(), "SetState", 2, false)
+}
+
+func (t *LimitTestSuite) TestSwitchOffFails() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(section("3.00", "0.100"), nil)
+	t.mock.On("SetState", 1, false).Return(false, errors.New("timeout"))
+
+	t.run(psu.Limit{Section: 1, MinVoltage: 4.5})
+	e := <-t.events
+	r.EqualError(e.Err, "timeout")
+	r.Equal("voltage 3.00 V < 4.50 V", e.Reason)
+	// Still exceeded, so switching off is tried again
+	e = <-t.events
+	r.NotNil(e.Err)
+}
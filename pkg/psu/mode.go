@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Mode describes how output is regulated. It is derived from readings, as limit status register
+// holds only latched events and is cleared by each query.
+type Mode int
+
+const (
+	// ModeUnknown - output is off
+	ModeUnknown Mode = iota
+	// ModeCV - constant voltage, output voltage is equal to set-point
+	ModeCV
+	// ModeCC - constant current, output is current limiting
+	ModeCC
+	// ModeUnregulated - neither voltage nor current is at set-point, e.g. output reached power limit
+	ModeUnregulated
+)
+
+// Trip is set of protection trips reported by limit status register
+type Trip uint8
+
+const (
+	TripOverVoltage Trip = 1 << iota
+	TripOverCurrent
+	// TripHard can be reset only from the front panel or by power cycle
+	TripHard
+)
+
+// Limit status register bits
+const (
+	lsrConstantVoltage = 1 << 0
+	lsrConstantCurrent = 1 << 1
+	lsrOverVoltageTrip = 1 << 2
+	lsrOverCurrentTrip = 1 << 3
+	lsrPowerLimit      = 1 << 4
+	lsrHardTrip        = 1 << 6
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeCV:
+		return "CV"
+	case ModeCC:
+		return "CC"
+	case ModeUnregulated:
+		return "UNREG"
+	default:
+		return "-"
+	}
+}
+
+// Has returns true, if all flags are set
+func (t Trip) Has(flags Trip) bool {
+	return t&flags == flags
+}
+
+func (t Trip) String() string {
+	var names []string
+	for _, flag := range []struct {
+		trip Trip
+		name string
+	}{
+		{trip: TripOverVoltage, name: "OVP"},
+		{trip: TripOverCurrent, name: "OCP"},
+		{trip: TripHard, name: "HARD"},
+	} {
+		if t.Has(flag.trip) {
+			names = append(names, flag.name)
+		}
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, "|")
+}
+
+// parseTrip decodes trips from reply of LSR<N>? query. Query clears the register, so trips must be latched by caller.
+func parseTrip(value string) (Trip, error) {
+	lsr, err := strconv.ParseUint(value, 10, 8)
+	if err != nil {
+		return 0, err
+	}
+
+	var trip Trip
+	if lsr&lsrOverVoltageTrip != 0 {
+		trip |= TripOverVoltage
+	}
+	if lsr&lsrOverCurrentTrip != 0 {
+		trip |= TripOverCurrent
+	}
+	if lsr&lsrHardTrip != 0 {
+		trip |= TripHard
+	}
+	return trip, nil
+}
+
+// regulation returns mode of output from its readings, reading within meter accuracy of set-point is regulated to it
+func regulation(state bool, v Values) Mode {
+	switch {
+	case !state:
+		return ModeUnknown
+	case math.Abs(v.ActualVoltage-v.SetVoltage) <= 0.01*v.SetVoltage+0.02:
+		return ModeCV
+	case math.Abs(v.ActualCurrent-v.SetCurrent) <= 0.01*v.SetCurrent+0.002:
+		return ModeCC
+	default:
+		return ModeUnregulated
+	}
+}
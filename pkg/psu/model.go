@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Model describes limits of single output of power supply
+type Model struct {
+	Name       string
+	MaxVoltage float64
+	MaxCurrent float64
+	// MaxPower limits product of voltage and current (PowerFlex)
+	MaxPower float64
+	// Ranges of protection trip points
+	MinOverVoltage, MaxOverVoltage float64
+	MinOverCurrent, MaxOverCurrent float64
+}
+
+// CPX400DP - each output is capable of 60 V, 20 A, but no more than 420 W.
+// Protection trips at 1 - 66 V and 1 - 22 A.
+var CPX400DP = Model{
+	Name:           "CPX400DP",
+	MaxVoltage:     60,
+	MaxCurrent:     20,
+	MaxPower:       420,
+	MinOverVoltage: 1,
+	MaxOverVoltage: 66,
+	MinOverCurrent: 1,
+	MaxOverCurrent: 22,
+}
+
+var (
+	ErrOutsideEnvelope = errors.New("setpoint outside of model envelope")
+	ErrUndeliverable   = errors.New("setpoints can't be delivered at the same time")
+)
+
+// MaxCurrentAt returns current, which can be delivered with voltage set-point
+func (m Model) MaxCurrentAt(voltage float64) float64 {
+	if voltage <= 0 || m.MaxPower <= 0 {
+		return m.MaxCurrent
+	}
+	return math.Min(m.MaxCurrent, m.MaxPower/voltage)
+}
+
+// MaxVoltageAt returns voltage, which can be delivered with current set-point
+func (m Model) MaxVoltageAt(current float64) float64 {
+	if current <= 0 || m.MaxPower <= 0 {
+		return m.MaxVoltage
+	}
+	return math.Min(m.MaxVoltage, m.MaxPower/current)
+}
+
+// Check returns ErrOutsideEnvelope, if voltage or current is outside of model range.
+// Combination above MaxPower is accepted by PSU, which just limits output, see Deliverable.
+func (m Model) Check(voltage, current float64) error {
+	if voltage < 0 || voltage > m.MaxVoltage {
+		return fmt.Errorf("%w: %s voltage range is 0 - %.2f V, requested %.2f V", ErrOutsideEnvelope, m.Name, m.MaxVoltage, voltage)
+	}
+	if current < 0 || current > m.MaxCurrent {
+		return fmt.Errorf("%w: %s current range is 0 - %.2f A, requested %.2f A", ErrOutsideEnvelope, m.Name, m.MaxCurrent, current)
+	}
+	return nil
+}
+
+// Deliverable returns ErrUndeliverable, if voltage and current exceed MaxPower, so output will be limited.
+// It is warning, such setpoints can still be written.
+func (m Model) Deliverable(voltage, current float64) error {
+	if maxCurrent := m.MaxCurrentAt(voltage); current > maxCurrent {
+		return fmt.Errorf("%w: %s delivers up to %.2f A at %.2f V, requested %.2f A", ErrUndeliverable, m.Name, maxCurrent, voltage, current)
+	}
+	return nil
+}
+
+// CheckOverVoltage returns ErrOutsideEnvelope, if over voltage protection can't trip at voltage
+func (m Model) CheckOverVoltage(voltage float64) error {
+	if voltage < m.MinOverVoltage || voltage > m.MaxOverVoltage {
+		return fmt.Errorf("%w: %s OVP range is %.2f - %.2f V, requested %.2f V", ErrOutsideEnvelope, m.Name, m.MinOverVoltage, m.MaxOverVoltage, voltage)
+	}
+	return nil
+}
+
+// CheckOverCurrent returns ErrOutsideEnvelope, if over current protection can't trip at current
+func (m Model) CheckOverCurrent(current float64) error {
+	if current < m.MinOverCurrent || current > m.MaxOverCurrent {
+		return fmt.Errorf("%w: %s OCP range is %.2f - %.2f A, requested %.2f A", ErrOutsideEnvelope, m.Name, m.MinOverCurrent, m.MaxOverCurrent, current)
+	}
+	return nil
+}
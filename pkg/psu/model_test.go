@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"psu/pkg/psu"
+)
+
+func TestModel_MaxCurrentAt(t *testing.T) {
+	args := []struct {
+		voltage, current float64
+	}{
+		{voltage: 0, current: 20},
+		{voltage: 5, current: 20},
+		{voltage: 21, current: 20},
+		{voltage: 35, current: 12},
+		{voltage: 60, current: 7},
+	}
+	for _, arg := range args {
+		require.InDelta(t, arg.current, psu.CPX400DP.MaxCurrentAt(arg.voltage), 1e-9, arg.voltage)
+	}
+}
+
+func TestModel_MaxVoltageAt(t *testing.T) {
+	args := []struct {
+		current, voltage float64
+	}{
+		{current: 0, voltage: 60},
+		{current: 7, voltage: 60},
+		{current: 12, voltage: 35},
+		{current: 20, voltage: 21},
+	}
+	for _, arg := range args {
+		require.InDelta(t, arg.voltage, psu.CPX400DP.MaxVoltageAt(arg.current), 1e-9, arg.current)
+	}
+}
+
+func TestModel_Check(t *testing.T) {
+	args := []struct {
+		name             string
+		voltage, current float64
+		err              error
+	}{
+		{name: "zero", voltage: 0, current: 0, err: nil},
+		{name: "max current", voltage: 20, current: 20, err: nil},
+		{name: "max voltage", voltage: 60, current: 7, err: nil},
+		{name: "over power", voltage: 60, current: 20, err: nil},
+		{name: "over voltage", voltage: 61, current: 0, err: psu.ErrOutsideEnvelope},
+		{name: "over current", voltage: 1, current: 21, err: psu.ErrOutsideEnvelope},
+		{name: "negative", voltage: -1, current: 0, err: psu.ErrOutsideEnvelope},
+	}
+	for _, arg := range args {
+		require.ErrorIs(t, psu.CPX400DP.Check(arg.voltage, arg.current), arg.err, arg.name)
+	}
+}
+
+func TestModel_Deliverable(t *testing.T) {
+	args := []struct {
+		name             string
+		voltage, current float64
+		err              error
+	}{
+		{name: "zero", voltage: 0, current: 20, err: nil},
+		{name: "max power", voltage: 21, current: 20, err: nil},
+		{name: "over power", voltage: 60, current: 7.5, err: psu.ErrUndeliverable},
+	}
+	for _, arg := range args {
+		require.ErrorIs(t, psu.CPX400DP.Deliverable(arg.voltage, arg.current), arg.err, arg.name)
+	}
+}
+
+func TestModel_CheckProtection(t *testing.T) {
+	require.Nil(t, psu.CPX400DP.CheckOverVoltage(13.5))
+	require.ErrorIs(t, psu.CPX400DP.CheckOverVoltage(0.5), psu.ErrOutsideEnvelope)
+	require.ErrorIs(t, psu.CPX400DP.CheckOverVoltage(67), psu.ErrOutsideEnvelope)
+	require.Nil(t, psu.CPX400DP.CheckOverCurrent(2.2))
+	require.ErrorIs(t, psu.CPX400DP.CheckOverCurrent(0.5), psu.ErrOutsideEnvelope)
+	require.ErrorIs(t, psu.CPX400DP.CheckOverCurrent(23), psu.ErrOutsideEnvelope)
+}
@@ -61,3 +61,10 @@ func WithRetries(retries int) Option {
 		return nil
 	}
 }
+
+func WithModel(m Model) Option {
+	return func(psu *PSU) error {
+		psu.model = m
+		return nil
+	}
+}
@@ -6,10 +6,13 @@
 package psu
 
 import (
+	"bytes"
 	"errors"
 	"io"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -20,19 +23,62 @@ type Conn interface {
 }
 
 type PSU struct {
+	mtx      sync.Mutex
 	conn     Conn
 	deadline time.Duration
 	retries  int
+	model    Model
+
+	// trips are latched per section, as LSR<N>? clears them on read
+	tripMtx sync.Mutex
+	trips   map[int]Trip
 }
 
 type Section struct {
 	State                     bool
 	ActualVoltage, SetVoltage string
 	ActualCurrent, SetCurrent string
+	Mode                      Mode
+	Trip                      Trip
+}
+
+// Values is numeric representation of Section readings
+type Values struct {
+	ActualVoltage, SetVoltage float64
+	ActualCurrent, SetCurrent float64
 }
 
 var (
 	ErrNoConnInterface = errors.New("lack of Conn interface")
+	ErrReplyTooLong    = errors.New("reply without line terminator")
+)
+
+// Identifier is implemented by Access, which is able to identify instrument
+type Identifier interface {
+	Identify() (string, error)
+}
+
+// Setter is implemented by Access, which is able to change set-points.
+// Set-point read back from instrument is returned.
+type Setter interface {
+	WriteVoltage(section int, voltage float64) (string, error)
+	WriteCurrent(section int, current float64) (string, error)
+}
+
+// Protector is implemented by Access, which is able to change protection trip points.
+// Trip point read back from instrument is returned.
+type Protector interface {
+	OverVoltage(section int) (string, error)
+	OverCurrent(section int) (string, error)
+	WriteOverVoltage(section int, voltage float64) (string, error)
+	WriteOverCurrent(section int, current float64) (string, error)
+}
+
+var (
+	_ Access     = (*PSU)(nil)
+	_ Identifier = (*PSU)(nil)
+	_ Setter     = (*PSU)(nil)
+	_ Protector  = (*PSU)(nil)
 )
 
 func New(options ...Option) (*PSU, error) {
@@ -40,6 +86,8 @@ func New(options ...Option) (*PSU, error) {
 		conn:     nil,
 		deadline: 100 * time.Millisecond,
 		retries:  0,
+		model:    CPX400DP,
+		trips:    make(map[int]Trip),
 	}
 	for _, option := range options {
 		if err := option(p); err != nil {
@@ -52,6 +100,12 @@ func New(options ...Option) (*PSU, error) {
 	return p, nil
 }
 
+// lsrQuery matches LSR<N>? sent through Raw
+var lsrQuery = regexp.MustCompile(`(?i)^LSR([0-9]+)\?$`)
+
+// Section reads state, readings, mode and trips of section.
+// LSR<N>? clears trips in instrument, so PSU latches them for all readers, until output is switched on
+// or TRIPRST is sent by Raw. Mode is derived from readings, see Mode.
 func (p *PSU) Section(section int) (*Section, error) {
 	sectStr := p.format(section)
 
@@ -60,6 +114,7 @@ func (p *PSU) Section(section int) (*Section, error) {
 	setVoltage := &setVoltageType{section: sectStr}
 	actualCurrent := &actualCurrentType{section: sectStr}
 	setCurrent := &setCurrentType{section: sectStr}
+	limitStatus := &limitStatusType{section: sectStr}
 
 	cmds := []commander{
 		getState,
@@ -67,6 +122,7 @@ func (p *PSU) Section(section int) (*Section, error) {
 		setVoltage,
 		actualCurrent,
 		setCurrent,
+		limitStatus,
 	}
 	reply, err := p.communicate(cmds...)
 	if err != nil {
@@ -87,14 +143,80 @@ func (p *PSU) Section(section int) (*Section, error) {
 			s.ActualCurrent = value
 		case setCurrent.Command():
 			s.SetCurrent = value
+		case limitStatus.Command():
+			trip, err := parseTrip(value)
+			if err != nil {
+				log.Error("error on parsing limit status: ", err)
+			}
+			s.Trip = p.latchTrip(section, trip)
 		default:
 			log.Error("unknown key ", string(key))
 		}
 	}
+	if values, err := s.Values(); err == nil {
+		s.Mode = regulation(s.State, values)
+	}
 
 	return s, nil
 }
 
+// Values parses Section readings
+func (s *Section) Values() (Values, error) {
+	var v Values
+	fields := []struct {
+		text  string
+		value *float64
+	}{
+		{text: s.ActualVoltage, value: &v.ActualVoltage},
+		{text: s.SetVoltage, value: &v.SetVoltage},
+		{text: s.ActualCurrent, value: &v.ActualCurrent},
+		{text: s.SetCurrent, value: &v.SetCurrent},
+	}
+	for _, field := range fields {
+		value, err := strconv.ParseFloat(field.text, 64)
+		if err != nil {
+			return Values{}, err
+		}
+		*field.value = value
+	}
+	return v, nil
+}
+
+// Identify returns identification of instrument: manufacturer, model, serial number and firmware version
+func (p *PSU) Identify() (string, error) {
+	idn := &identifyType{}
+	reply, err := p.communicate(idn)
+	if err != nil {
+		return "", err
+	}
+	return reply[idn.Command()], nil
+}
+
+// Raw sends cmd as is. Reply is awaited only if cmd is query, see IsQuery.
+// Trips replied to LSR<N>? are latched as by Section, TRIPRST clears latched trips.
+func (p *PSU) Raw(cmd string) (string, error) {
+	raw := &rawType{cmd: cmd}
+	reply, err := p.communicate(raw)
+	if err != nil {
+		return "", err
+	}
+	value := reply[raw.Command()]
+	if match := lsrQuery.FindStringSubmatch(strings.TrimSpace(cmd)); match != nil {
+		section, _ := strconv.Atoi(match[1])
+		if trip, err := parseTrip(value); err == nil {
+			p.latchTrip(section, trip)
+		}
+	} else if strings.EqualFold(strings.TrimSpace(cmd), "TRIPRST") {
+		p.clearTrip()
+	}
+	return value, nil
+}
+
+// Model returns limits of PSU outputs
+func (p *PSU) Model() Model {
+	return p.model
+}
+
 func (p *PSU) ActualCurrent(section int) (string, error) {
 	ac := &actualCurrentType{section: p.format(section)}
 	reply, err := p.communicate(ac)
@@ -131,6 +253,116 @@ func (p *PSU) SetVoltage(section int) (string, error) {
 	return reply[sv.Command()], nil
 }
 
+// WriteVoltage changes voltage set-point of section and returns set-point read back from PSU.
+// Set-point outside of model range is refused. Set-point, which can't be delivered along with
+// actual current set-point, is written with warning in log, as PSU just limits output.
+func (p *PSU) WriteVoltage(section int, voltage float64) (string, error) {
+	if err := p.model.Check(voltage, 0); err != nil {
+		return "", err
+	}
+	return p.writeSetPoint(
+		&setCurrentType{section: p.format(section)},
+		func(current float64) error { return p.model.Deliverable(voltage, current) },
+		&writeVoltageType{section: p.format(section), value: p.formatFloat(voltage)},
+		&setVoltageType{section: p.format(section)},
+	)
+}
+
+// WriteCurrent changes current set-point of section and returns set-point read back from PSU.
+// Set-point outside of model range is refused. Set-point, which can't be delivered along with
+// actual voltage set-point, is written with warning in log, as PSU just limits output.
+func (p *PSU) WriteCurrent(section int, current float64) (string, error) {
+	if err := p.model.Check(0, current); err != nil {
+		return "", err
+	}
+	return p.writeSetPoint(
+		&setVoltageType{section: p.format(section)},
+		func(voltage float64) error { return p.model.Deliverable(voltage, current) },
+		&writeCurrentType{section: p.format(section), value: p.formatFloat(current)},
+		&setCurrentType{section: p.format(section)},
+	)
+}
+
+// writeSetPoint reads the other set-point and writes set-point in single exchange, so nobody can change
+// the other set-point in the meantime. Warning of deliverable is logged. Set-point read back is returned.
+func (p *PSU) writeSetPoint(other commander, deliverable func(other float64) error, write, readBack commander) (string, error) {
+	var setPoint string
+	err := p.session(func() error {
+		reply, err := p.exchange(other)
+		if err != nil {
+			return err
+		}
+		value, err := strconv.ParseFloat(reply[other.Command()], 64)
+		if err != nil {
+			return err
+		}
+		if err := deliverable(value); err != nil {
+			log.Error("output will be limited after ", write.Command(), ": ", err)
+		}
+		reply, err = p.exchange(write, readBack)
+		if err != nil {
+			return err
+		}
+		setPoint = reply[readBack.Command()]
+		return nil
+	})
+	return setPoint, err
+}
+
+// OverVoltage returns over voltage protection trip point of section
+func (p *PSU) OverVoltage(section int) (string, error) {
+	ov := &overVoltageType{section: p.format(section)}
+	reply, err := p.communicate(ov)
+	if err != nil {
+		return "", err
+	}
+	return reply[ov.Command()], nil
+}
+
+// OverCurrent returns over current protection trip point of section
+func (p *PSU) OverCurrent(section int) (string, error) {
+	oc := &overCurrentType{section: p.format(section)}
+	reply, err := p.communicate(oc)
+	if err != nil {
+		return "", err
+	}
+	return reply[oc.Command()], nil
+}
+
+// WriteOverVoltage changes over voltage protection trip point of section and returns trip point read back from PSU.
+// Trip point outside of model range is refused.
+func (p *PSU) WriteOverVoltage(section int, voltage float64) (string, error) {
+	if err := p.model.CheckOverVoltage(voltage); err != nil {
+		return "", err
+	}
+	cmds := []commander{
+		&writeOverVoltageType{section: p.format(section), value: p.formatFloat(voltage)},
+		&overVoltageType{section: p.format(section)},
+	}
+	reply, err := p.communicate(cmds...)
+	if err != nil {
+		return "", err
+	}
+	return reply[cmds[1].Command()], nil
+}
+
+// WriteOverCurrent changes over current protection trip point of section and returns trip point read back from PSU.
+// Trip point outside of model range is refused.
+func (p *PSU) WriteOverCurrent(section int, current float64) (string, error) {
+	if err := p.model.CheckOverCurrent(current); err != nil {
+		return "", err
+	}
+	cmds := []commander{
+		&writeOverCurrentType{section: p.format(section), value: p.formatFloat(current)},
+		&overCurrentType{section: p.format(section)},
+	}
+	reply, err := p.communicate(cmds...)
+	if err != nil {
+		return "", err
+	}
+	return reply[cmds[1].Command()], nil
+}
+
 func (p *PSU) SetState(section int, value bool) (bool, error) {
 	cmds := []commander{
 		&setStateType{section: p.format(section), value: value},
@@ -141,7 +373,12 @@ func (p *PSU) SetState(section int, value bool) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	return strconv.ParseBool(reply[cmds[1].Command()])
+	state, err := strconv.ParseBool(reply[cmds[1].Command()])
+	if err == nil && state {
+		// Output isn't held off by protection anymore
+		p.clearTrip(section)
+	}
+	return state, err
 }
 
 func (p *PSU) State(section int) (bool, error) {
@@ -153,7 +390,65 @@ func (p *PSU) State(section int) (bool, error) {
 	return strconv.ParseBool(reply[gs.Command()])
 }
 
+// Mode returns regulation mode of section, derived from its readings
+func (p *PSU) Mode(section int) (Mode, error) {
+	s, err := p.Section(section)
+	if err != nil {
+		return ModeUnknown, err
+	}
+	return s.Mode, nil
+}
+
+// Trip returns protection trips of section, latched since output was switched on
+func (p *PSU) Trip(section int) (Trip, error) {
+	ls := &limitStatusType{section: p.format(section)}
+	reply, err := p.communicate(ls)
+	if err != nil {
+		return 0, err
+	}
+	trip, err := parseTrip(reply[ls.Command()])
+	if err != nil {
+		return 0, err
+	}
+	return p.latchTrip(section, trip), nil
+}
+
+// latchTrip adds trip of section and returns all trips latched
+func (p *PSU) latchTrip(section int, trip Trip) Trip {
+	p.tripMtx.Lock()
+	defer p.tripMtx.Unlock()
+	p.trips[section] |= trip
+	return p.trips[section]
+}
+
+// clearTrip forgets latched trips of sections, all of them if none is given
+func (p *PSU) clearTrip(sections ...int) {
+	p.tripMtx.Lock()
+	defer p.tripMtx.Unlock()
+	if len(sections) == 0 {
+		p.trips = make(map[int]Trip)
+	}
+	for _, section := range sections {
+		delete(p.trips, section)
+	}
+}
+
 func (p *PSU) communicate(cmds ...commander) (map[command]string, error) {
+	var reply map[command]string
+	err := p.session(func() error {
+		var err error
+		reply, err = p.exchange(cmds...)
+		return err
+	})
+	return reply, err
+}
+
+// session opens Conn, runs exchanges of fn and closes Conn
+func (p *PSU) session(fn func() error) error {
+	// Conn is shared, so only one session can be in progress
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
 	var err error
 	for i := 0; i <= p.retries; i++ {
 		log.Debug("Connecting ...")
@@ -164,7 +459,7 @@ func (p *PSU) communicate(cmds ...commander) (map[command]string, error) {
 
 	if err != nil {
 		log.Error("Failed to connect: ", err)
-		return nil, err
+		return err
 	}
 
 	defer func() {
@@ -173,7 +468,11 @@ func (p *PSU) communicate(cmds ...commander) (map[command]string, error) {
 			log.Error("Failed to disconnect: ", err)
 		}
 	}()
+	return fn()
+}
 
+// exchange writes cmds and reads their replies, it must be called within session
+func (p *PSU) exchange(cmds ...commander) (map[command]string, error) {
 	reply := make(map[command]string)
 	for _, cmd := range cmds {
 		p.setDeadline()
@@ -186,15 +485,11 @@ func (p *PSU) communicate(cmds ...commander) (map[command]string, error) {
 		if cmd.WriteOnly() {
 			continue
 		}
-		// CPX usually respond within few bytes
-		readBuffer := make([]byte, 64)
-		p.setDeadline()
-		size, err := p.conn.Read(readBuffer)
+		data, err := p.readLine()
 		if err != nil {
 			log.Error("error on Read: ", err)
 			return nil, err
 		}
-		data := strings.TrimSuffix(string(readBuffer[:size]), "\r\n")
 		log.Debug("received data: ", data)
 		cmdReply, err := cmd.Parse(strings.Split(data, " "))
 		if err != nil {
@@ -207,12 +502,38 @@ func (p *PSU) communicate(cmds ...commander) (map[command]string, error) {
 	return reply, nil
 }
 
+// maxReplyLength limits reply, which is read until line terminator
+const maxReplyLength = 4096
+
+// readLine reads reply until line terminator, which is stripped. Reply to line with many queries may come in many chunks.
+func (p *PSU) readLine() (string, error) {
+	var data []byte
+	// CPX usually respond within few bytes
+	readBuffer := make([]byte, 64)
+	for !bytes.HasSuffix(data, []byte("\n")) {
+		if len(data) > maxReplyLength {
+			return "", ErrReplyTooLong
+		}
+		p.setDeadline()
+		size, err := p.conn.Read(readBuffer)
+		if err != nil {
+			return "", err
+		}
+		data = append(data, readBuffer[:size]...)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
 func (p *PSU) setDeadline() {
 	if err := p.conn.SetDeadline(time.Now().Add(p.deadline)); err != nil {
 		log.Error("Error on setting deadline: ", err)
 	}
 }
 
+func (p *PSU) formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 3, 64)
+}
+
 func (p *PSU) format(section int) string {
 	return strconv.FormatInt(int64(section), 10)
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"psu/pkg/psu"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -53,7 +54,7 @@ func (t *PSUTestSuite) Test_Section() {
 		},
 		{
 			write: []byte("I1O?\r\n"),
-			reply: []byte("123.45A\r\n"),
+			reply: []byte("7.45A\r\n"),
 		},
 		{
 			write: []byte("I1?\r\n"),
@@ -63,6 +64,10 @@ func (t *PSUTestSuite) Test_Section() {
 			write: []byte("OP1?\r\n"),
 			reply: []byte("1\r\n"),
 		},
+		{
+			write: []byte("LSR1?\r\n"),
+			reply: []byte("2\r\n"),
+		},
 	}
 	// That is crazy :D, however works pretty well
 	// After specific Write call we add exactly right Read reply
@@ -82,8 +87,9 @@ func (t *PSUTestSuite) Test_Section() {
 		State:         true,
 		ActualVoltage: "21.45",
 		SetVoltage:    "27.45",
-		ActualCurrent: "123.45",
+		ActualCurrent: "7.45",
 		SetCurrent:    "7.45",
+		Mode:          psu.ModeCC,
 	}
 
 	r := t.Require()
@@ -222,6 +228,340 @@ func (t *PSUTestSuite) Test_ActualCurrent() {
 	r.Nil(err)
 }
 
+func (t *PSUTestSuite) Test_Mode() {
+	args := []struct {
+		name                    string
+		state, voltage, current string
+		mode                    psu.Mode
+	}{
+		{name: "off", state: "0", voltage: "0.00", current: "0.000", mode: psu.ModeUnknown},
+		{name: "CV", state: "1", voltage: "11.99", current: "0.500", mode: psu.ModeCV},
+		{name: "CC", state: "1", voltage: "5.00", current: "1.000", mode: psu.ModeCC},
+		{name: "power limit", state: "1", voltage: "8.40", current: "0.800", mode: psu.ModeUnregulated},
+	}
+	r := t.Require()
+	for _, arg := range args {
+		t.mock = new(ConnMock)
+		// Register bits don't matter, as they are cleared by each query
+		t.expectExchanges(sectionExchanges(2, arg.state, arg.voltage, arg.current, "1"))
+		v, err := t.psu().Mode(2)
+		r.Nil(err)
+		r.Equal(arg.mode, v, arg.name)
+	}
+}
+
+func (t *PSUTestSuite) Test_TripLatched() {
+	r := t.Require()
+	exchanges := sectionExchanges(1, "0", "0.00", "0.000", "8")
+	exchanges = append(exchanges, sectionExchanges(1, "0", "0.00", "0.000", "0")...)
+	exchanges = append(exchanges, []struct{ write, reply []byte }{
+		{write: []byte("OP1 1\r\n")},
+		{write: []byte("OP1?\r\n"), reply: []byte("1\r\n")},
+		{write: []byte("LSR1?\r\n"), reply: []byte("4\r\n")},
+	}...)
+	exchanges = append(exchanges, sectionExchanges(1, "0", "0.00", "0.000", "0")...)
+	exchanges = append(exchanges, struct{ write, reply []byte }{write: []byte("TRIPRST\r\n")})
+	exchanges = append(exchanges, sectionExchanges(1, "0", "0.00", "0.000", "0")...)
+	t.expectExchanges(exchanges)
+	p := t.psu()
+
+	s, err := p.Section(1)
+	r.Nil(err)
+	r.Equal(psu.TripOverCurrent, s.Trip)
+	// Register was cleared by the first query, trip is still reported
+	s, err = p.Section(1)
+	r.Nil(err)
+	r.Equal(psu.TripOverCurrent, s.Trip)
+
+	// Switching on clears trip, trip read by another client is latched too
+	_, err = p.SetState(1, true)
+	r.Nil(err)
+	reply, err := p.Raw("LSR1?")
+	r.Nil(err)
+	r.Equal("4", reply)
+	s, err = p.Section(1)
+	r.Nil(err)
+	r.Equal(psu.TripOverVoltage, s.Trip)
+
+	_, err = p.Raw("TRIPRST")
+	r.Nil(err)
+	s, err = p.Section(1)
+	r.Nil(err)
+	r.Equal(psu.Trip(0), s.Trip)
+	t.mock.AssertExpectations(t.T())
+}
+
+// sectionExchanges returns exchanges of Section with set-points 12 V and 1 A
+func sectionExchanges(section int, state, voltage, current, lsr string) []struct{ write, reply []byte } {
+	n := strconv.Itoa(section)
+	return []struct{ write, reply []byte }{
+		{write: []byte("OP" + n + "?\r\n"), reply: []byte(state + "\r\n")},
+		{write: []byte("V" + n + "O?\r\n"), reply: []byte(voltage + "V\r\n")},
+		{write: []byte("V" + n + "?\r\n"), reply: []byte("V" + n + " 12.00\r\n")},
+		{write: []byte("I" + n + "O?\r\n"), reply: []byte(current + "A\r\n")},
+		{write: []byte("I" + n + "?\r\n"), reply: []byte("I" + n + " 1.000\r\n")},
+		{write: []byte("LSR" + n + "?\r\n"), reply: []byte(lsr + "\r\n")},
+	}
+}
+
+func (t *PSUTestSuite) Test_WriteVoltage() {
+	args := []struct {
+		write, reply []byte
+	}{
+		{
+			write: []byte("I1?\r\n"),
+			reply: []byte("I1 7.00\r\n"),
+		},
+		{
+			write: []byte("V1 60.000\r\n"),
+		},
+		{
+			write: []byte("V1?\r\n"),
+			reply: []byte("V1 60.00\r\n"),
+		},
+	}
+	t.expectExchanges(args)
+
+	r := t.Require()
+	p := t.psu()
+	v, err := p.WriteVoltage(1, 60)
+	r.Nil(err)
+	r.Equal("60.00", v)
+	t.mock.AssertExpectations(t.T())
+	// Current set-point can't be changed between check and write
+	t.mock.AssertNumberOfCalls(t.T(), "Open", 1)
+}
+
+func (t *PSUTestSuite) Test_WriteCurrentOutsideEnvelope() {
+	r := t.Require()
+	p := t.psu()
+	v, err := p.WriteCurrent(2, 21)
+	r.ErrorIs(err, psu.ErrOutsideEnvelope)
+	r.Equal("", v)
+	v, err = p.WriteVoltage(2, -1)
+	r.ErrorIs(err, psu.ErrOutsideEnvelope)
+	r.Equal("", v)
+	// Nothing was written
+	t.mock.AssertNotCalled(t.T(), "Open")
+}
+
+func (t *PSUTestSuite) Test_WriteCurrentOverPower() {
+	t.expectExchanges([]struct{ write, reply []byte }{
+		{
+			write: []byte("V2?\r\n"),
+			reply: []byte("V2 60.00\r\n"),
+		},
+		{
+			write: []byte("I2 10.000\r\n"),
+		},
+		{
+			write: []byte("I2?\r\n"),
+			reply: []byte("I2 10.000\r\n"),
+		},
+	})
+
+	r := t.Require()
+	p := t.psu()
+	// PSU limits output to 420 W, set-point is written anyway
+	v, err := p.WriteCurrent(2, 10)
+	r.Nil(err)
+	r.Equal("10.000", v)
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *PSUTestSuite) Test_WriteOverVoltage() {
+	args := []struct {
+		write, reply []byte
+	}{
+		{
+			write: []byte("OVP1 13.500\r\n"),
+		},
+		{
+			write: []byte("OVP1?\r\n"),
+			reply: []byte("VP1 13.50\r\n"),
+		},
+	}
+	t.expectExchanges(args)
+
+	r := t.Require()
+	p := t.psu()
+	v, err := p.WriteOverVoltage(1, 13.5)
+	r.Nil(err)
+	r.Equal("13.50", v)
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *PSUTestSuite) Test_OverCurrent() {
+	args := []struct {
+		write, reply []byte
+	}{
+		{
+			write: []byte("OCP2?\r\n"),
+			reply: []byte("CP2 2.200\r\n"),
+		},
+	}
+	t.expectExchanges(args)
+
+	r := t.Require()
+	p := t.psu()
+	v, err := p.OverCurrent(2)
+	r.Nil(err)
+	r.Equal("2.200", v)
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *PSUTestSuite) Test_WriteProtectionOutsideModel() {
+	r := t.Require()
+	p := t.psu()
+	_, err := p.WriteOverVoltage(1, 70)
+	r.ErrorIs(err, psu.ErrOutsideEnvelope)
+	_, err = p.WriteOverCurrent(1, 0.5)
+	r.ErrorIs(err, psu.ErrOutsideEnvelope)
+	// Nothing was written
+	t.mock.AssertNotCalled(t.T(), "Write", mock.Anything)
+}
+
+func (t *PSUTestSuite) Test_Values() {
+	r := t.Require()
+	s := psu.Section{
+		ActualVoltage: "1.5",
+		SetVoltage:    "2",
+		ActualCurrent: "0.25",
+		SetCurrent:    "3",
+	}
+	v, err := s.Values()
+	r.Nil(err)
+	r.Equal(psu.Values{ActualVoltage: 1.5, SetVoltage: 2, ActualCurrent: 0.25, SetCurrent: 3}, v)
+
+	s.SetCurrent = "err"
+	_, err = s.Values()
+	r.NotNil(err)
+}
+
+// expectExchanges adds mocked Write calls, each followed by Read of reply (if reply is not nil)
+func (t *PSUTestSuite) expectExchanges(args []struct{ write, reply []byte }) {
+	t.mock.On("Open").Return(nil)
+	t.mock.On("SetDeadline", mock.Anything).Return(nil)
+	t.mock.On("Close").Return(nil)
+	fn := func(buf []byte) func(arguments mock.Arguments) {
+		return func(args mock.Arguments) {
+			if buf == nil {
+				return
+			}
+			t.mock.On("Read", mock.Anything).Return(len(buf), nil).Once().Run(func(args mock.Arguments) {
+				buffer := args.Get(0).([]byte)
+				copy(buffer, buf)
+			})
+		}
+	}
+	for _, arg := range args {
+		t.mock.On("Write", arg.write).Return(len(arg.write), nil).Once().Run(fn(arg.reply))
+	}
+}
+
+func (t *PSUTestSuite) Test_Trip() {
+	args := []struct {
+		reply []byte
+		trip  psu.Trip
+		name  string
+	}{
+		{reply: []byte("1\r\n"), trip: 0, name: "-"},
+		{reply: []byte("4\r\n"), trip: psu.TripOverVoltage, name: "OVP"},
+		{reply: []byte("10\r\n"), trip: psu.TripOverCurrent, name: "OCP"},
+		{reply: []byte("76\r\n"), trip: psu.TripOverVoltage | psu.TripOverCurrent | psu.TripHard, name: "OVP|OCP|HARD"},
+	}
+	expectedWrite := []byte("LSR1?\r\n")
+	r := t.Require()
+	for _, arg := range args {
+		t.mock = new(ConnMock)
+		reply := arg.reply
+		t.mock.On("Open").Return(nil)
+		t.mock.On("SetDeadline", mock.Anything).Return(nil)
+		t.mock.On("Write", expectedWrite).Return(len(expectedWrite), nil)
+		t.mock.On("Read", mock.Anything).Return(len(reply), nil).Run(func(args mock.Arguments) {
+			buffer := args.Get(0).([]byte)
+			copy(buffer, reply)
+		})
+		t.mock.On("Close").Return(nil)
+
+		p := t.psu()
+		v, err := p.Trip(1)
+		r.Nil(err)
+		r.Equal(arg.trip, v, string(reply))
+		r.Equal(arg.name, v.String())
+	}
+}
+
+func (t *PSUTestSuite) Test_Identify() {
+	t.expectExchanges([]struct{ write, reply []byte }{
+		{
+			write: []byte("*IDN?\r\n"),
+			reply: []byte("THURLBY THANDAR, CPX400DP, 123456, 1.00-1.00\r\n"),
+		},
+	})
+
+	r := t.Require()
+	p := t.psu()
+	v, err := p.Identify()
+	r.Nil(err)
+	r.Equal("THURLBY THANDAR, CPX400DP, 123456, 1.00-1.00", v)
+}
+
+func (t *PSUTestSuite) Test_Raw() {
+	t.expectExchanges([]struct{ write, reply []byte }{
+		{
+			write: []byte("V1 5.0\r\n"),
+		},
+		{
+			write: []byte("V1?\r\n"),
+			reply: []byte("V1 5.00\r\n"),
+		},
+	})
+
+	r := t.Require()
+	p := t.psu()
+	v, err := p.Raw("V1 5.0")
+	r.Nil(err)
+	r.Equal("", v)
+
+	v, err = p.Raw("V1?")
+	r.Nil(err)
+	r.Equal("V1 5.00", v)
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *PSUTestSuite) Test_RawChunks() {
+	r := t.Require()
+	line := "V1?;I1?;V2?;I2?;OP1?;OP2?"
+	reply := "V1 12.00;I1 1.000;V2 5.00;I2 0.500;1;0;V1 12.00;I1 1.000;V2 5.00;I2 0.500"
+	t.mock.On("Open").Return(nil)
+	t.mock.On("SetDeadline", mock.Anything).Return(nil)
+	t.mock.On("Close").Return(nil)
+	t.mock.On("Write", []byte(line+"\r\n")).Return(len(line)+2, nil).Once()
+	// Reply doesn't fit single Read and its terminator comes separately
+	for _, chunk := range []string{reply[:40], reply[40:], "\r", "\n"} {
+		chunk := chunk
+		t.mock.On("Read", mock.Anything).Return(len(chunk), nil).Once().Run(func(args mock.Arguments) {
+			copy(args.Get(0).([]byte), chunk)
+		})
+	}
+
+	v, err := t.psu().Raw(line)
+	r.Nil(err)
+	r.Equal(reply, v)
+	t.mock.AssertExpectations(t.T())
+
+	// Never ending reply
+	t.mock = new(ConnMock)
+	t.mock.On("Open").Return(nil)
+	t.mock.On("SetDeadline", mock.Anything).Return(nil)
+	t.mock.On("Close").Return(nil)
+	t.mock.On("Write", mock.Anything).Return(5, nil)
+	t.mock.On("Read", mock.Anything).Return(64, nil)
+	_, err = t.psu().Raw("V1?")
+	r.ErrorIs(err, psu.ErrReplyTooLong)
+}
+
 func (t *PSUTestSuite) TestNew() {
 	r := t.Require()
 	{
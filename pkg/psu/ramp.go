@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Quantity is set-point changed by Ramp
+type Quantity int
+
+const (
+	QuantityVoltage Quantity = iota
+	QuantityCurrent
+)
+
+// RampAccess changes set-points and reads section, implemented by PSU
+type RampAccess interface {
+	Access
+	Setter
+}
+
+// Ramp moves voltage or current set-point of section in steps, as instrument has no slew control.
+// Each step is verified by read back. Ramp is aborted on protection trip, failed step or cancelled context,
+// output is switched off then.
+type Ramp struct {
+	access   RampAccess
+	section  int
+	quantity Quantity
+	from, to float64
+	// Either rate (per second) or steps is used
+	rate      float64
+	steps     int
+	interval  time.Duration
+	tolerance float64
+}
+
+var (
+	ErrNoSetter    = errors.New("Access can't change set-points")
+	ErrInvalidRamp = errors.New("invalid ramp")
+	ErrReadBack    = errors.New("set-point read back doesn't match")
+)
+
+func NewRamp(opts ...RampOption) (*Ramp, error) {
+	r := &Ramp{
+		access:    nil,
+		section:   0,
+		quantity:  QuantityVoltage,
+		rate:      0,
+		steps:     0,
+		interval:  100 * time.Millisecond,
+		tolerance: -1,
+	}
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.verify(); err != nil {
+		return nil, err
+	}
+	if r.tolerance < 0 {
+		// Instrument resolution is 10 mV and 1 mA
+		r.tolerance = 0.01
+		if r.quantity == QuantityCurrent {
+			r.tolerance = 0.001
+		}
+	}
+	return r, nil
+}
+
+func (q Quantity) String() string {
+	if q == QuantityCurrent {
+		return "current"
+	}
+	return "voltage"
+}
+
+// Values returns set-points written by Ramp, starting with from
+func (r *Ramp) Values() []float64 {
+	steps := r.steps
+	if r.rate > 0 {
+		step := r.rate * r.interval.Seconds()
+		steps = int(math.Ceil(math.Abs(r.to-r.from) / step))
+	}
+	if steps < 1 {
+		steps = 1
+	}
+	values := make([]float64, steps+1)
+	for i := range values {
+		values[i] = r.from + (r.to-r.from)*float64(i)/float64(steps)
+	}
+	// Avoid rounding errors on the last step
+	values[steps] = r.to
+	return values
+}
+
+// Run writes each value, until to is reached
+func (r *Ramp) Run(ctx context.Context) error {
+	values := r.Values()
+	for i, value := range values {
+		if i > 0 {
+			if err := sleep(ctx, r.interval); err != nil {
+				return r.abort(err)
+			}
+		}
+		if err := r.step(value); err != nil {
+			return r.abort(err)
+		}
+	}
+	return nil
+}
+
+func (r *Ramp) step(value float64) error {
+	write := r.access.WriteVoltage
+	if r.quantity == QuantityCurrent {
+		write = r.access.WriteCurrent
+	}
+	reply, err := write(r.section, value)
+	if err != nil {
+		return err
+	}
+	readBack, err := strconv.ParseFloat(strings.TrimSpace(reply), 64)
+	if err != nil {
+		return err
+	}
+	if math.Abs(readBack-value) > r.tolerance {
+		return fmt.Errorf("%w: written %.3f, read %s", ErrReadBack, value, reply)
+	}
+
+	s, err := r.access.Section(r.section)
+	if err != nil {
+		return err
+	}
+	if s.Trip != 0 {
+		return fmt.Errorf("%w: %v", ErrTripped, s.Trip)
+	}
+	return nil
+}
+
+// abort switches output off, so it isn't left at unknown set-point
+func (r *Ramp) abort(err error) error {
+	log.Error("ramp of section ", r.section, " ", r.quantity, " aborted: ", err)
+	if _, offErr := r.access.SetState(r.section, false); offErr != nil {
+		return fmt.Errorf("ramp aborted: %w, output not switched off: %v", err, offErr)
+	}
+	return fmt.Errorf("ramp aborted: %w", err)
+}
+
+func (r *Ramp) verify() error {
+	if r.access == nil {
+		return ErrNoAccess
+	}
+	if r.section < 1 {
+		return ErrNoSection
+	}
+	if (r.rate > 0) == (r.steps > 0) {
+		// Exactly one of them must be set
+		return ErrInvalidRamp
+	}
+	if r.from < 0 || r.to < 0 {
+		return ErrInvalidRamp
+	}
+	return nil
+}
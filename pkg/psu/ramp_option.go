@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"time"
+)
+
+type RampOption func(*Ramp) error
+
+func RampWithPSU(p *PSU) RampOption {
+	return func(r *Ramp) error {
+		return RampWithAccess(p)(r)
+	}
+}
+
+// RampWithAccess requires Access, which implements Setter
+func RampWithAccess(a Access) RampOption {
+	return func(r *Ramp) error {
+		access, ok := a.(RampAccess)
+		if !ok {
+			return ErrNoSetter
+		}
+		r.access = access
+		return nil
+	}
+}
+
+func RampWithSection(section int) RampOption {
+	return func(r *Ramp) error {
+		r.section = section
+		return nil
+	}
+}
+
+// RampWithVoltage ramps voltage set-point from - to, in V
+func RampWithVoltage(from, to float64) RampOption {
+	return func(r *Ramp) error {
+		r.quantity, r.from, r.to = QuantityVoltage, from, to
+		return nil
+	}
+}
+
+// RampWithCurrent ramps current set-point from - to, in A
+func RampWithCurrent(from, to float64) RampOption {
+	return func(r *Ramp) error {
+		r.quantity, r.from, r.to = QuantityCurrent, from, to
+		return nil
+	}
+}
+
+// RampWithRate sets slew rate in V/s or A/s, set-point is changed every interval
+func RampWithRate(rate float64) RampOption {
+	return func(r *Ramp) error {
+		if rate <= 0 {
+			return ErrInvalidRamp
+		}
+		r.rate = rate
+		return nil
+	}
+}
+
+// RampWithSteps sets number of steps, set-point is changed every interval
+func RampWithSteps(steps int) RampOption {
+	return func(r *Ramp) error {
+		if steps <= 0 {
+			return ErrInvalidRamp
+		}
+		r.steps = steps
+		return nil
+	}
+}
+
+// RampWithInterval sets time between steps, 100 ms by default
+func RampWithInterval(t time.Duration) RampOption {
+	return func(r *Ramp) error {
+		if t <= 0 {
+			return ErrInvalidPeriod
+		}
+		r.interval = t
+		return nil
+	}
+}
+
+// RampWithTolerance sets allowed difference between written and read back set-point,
+// by default it is resolution of instrument: 10 mV or 1 mA
+func RampWithTolerance(tolerance float64) RampOption {
+	return func(r *Ramp) error {
+		if tolerance < 0 {
+			return ErrInvalidRamp
+		}
+		r.tolerance = tolerance
+		return nil
+	}
+}
@@ -0,0 +1,268 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/psu"
+)
+
+type RampTestSuite struct {
+	suite.Suite
+	mock *SetterMocker
+}
+
+type SetterMocker struct {
+	AccessMocker
+}
+
+// CPXFake is Conn of single CPX output, which keeps set-points and limits output to 420 W like PowerFlex
+type CPXFake struct {
+	voltage, current float64
+	state            bool
+	reply            bytes.Buffer
+}
+
+func TestRamp(t *testing.T) {
+	suite.Run(t, new(RampTestSuite))
+}
+
+func (t *RampTestSuite) SetupTest() {
+	t.mock = new(SetterMocker)
+}
+
+func (s *SetterMocker) WriteVoltage(section int, voltage float64) (string, error) {
+	args := s.Called(section, voltage)
+	return args.String(0), args.Error(1)
+}
+
+func (s *SetterMocker) WriteCurrent(section int, current float64) (string, error) {
+	args := s.Called(section, current)
+	return args.String(0), args.Error(1)
+}
+
+func (t *RampTestSuite) ramp(opts ...psu.RampOption) *psu.Ramp {
+	opts = append([]psu.RampOption{
+		psu.RampWithAccess(t.mock),
+		psu.RampWithSection(1),
+		psu.RampWithInterval(time.Millisecond),
+	}, opts...)
+	r, err := psu.NewRamp(opts...)
+	t.Require().Nil(err)
+	return r
+}
+
+// written returns values passed to method in order
+func (t *RampTestSuite) written(method string) []float64 {
+	var values []float64
+	for _, call := range t.mock.Calls {
+		if call.Method == method {
+			values = append(values, call.Arguments.Get(1).(float64))
+		}
+	}
+	return values
+}
+
+func (t *RampTestSuite) TestNew() {
+	args := []struct {
+		name string
+		opts []psu.RampOption
+		err  error
+	}{
+		{name: "no access", opts: nil, err: psu.ErrNoAccess},
+		{name: "no setter", opts: []psu.RampOption{psu.RampWithAccess(new(AccessMocker))}, err: psu.ErrNoSetter},
+		{name: "no section", opts: []psu.RampOption{psu.RampWithAccess(t.mock), psu.RampWithSteps(1)}, err: psu.ErrNoSection},
+		{name: "no rate nor steps", opts: []psu.RampOption{psu.RampWithAccess(t.mock), psu.RampWithSection(1)}, err: psu.ErrInvalidRamp},
+		{
+			name: "rate and steps",
+			opts: []psu.RampOption{psu.RampWithAccess(t.mock), psu.RampWithSection(1), psu.RampWithSteps(2), psu.RampWithRate(1)},
+			err:  psu.ErrInvalidRamp,
+		},
+		{
+			name: "negative set-point",
+			opts: []psu.RampOption{psu.RampWithAccess(t.mock), psu.RampWithSection(1), psu.RampWithSteps(2), psu.RampWithVoltage(-1, 5)},
+			err:  psu.ErrInvalidRamp,
+		},
+		{name: "rate", opts: []psu.RampOption{psu.RampWithRate(0)}, err: psu.ErrInvalidRamp},
+		{name: "steps", opts: []psu.RampOption{psu.RampWithSteps(0)}, err: psu.ErrInvalidRamp},
+		{name: "tolerance", opts: []psu.RampOption{psu.RampWithTolerance(-0.1)}, err: psu.ErrInvalidRamp},
+		{name: "interval", opts: []psu.RampOption{psu.RampWithInterval(0)}, err: psu.ErrInvalidPeriod},
+		{
+			name: "all good",
+			opts: []psu.RampOption{psu.RampWithAccess(t.mock), psu.RampWithSection(1), psu.RampWithRate(1), psu.RampWithCurrent(0, 1)},
+			err:  nil,
+		},
+	}
+	for _, arg := range args {
+		r, err := psu.NewRamp(arg.opts...)
+		t.ErrorIs(err, arg.err, arg.name)
+		t.Equal(arg.err == nil, r != nil, arg.name)
+	}
+}
+
+func (t *RampTestSuite) TestValues() {
+	args := []struct {
+		name     string
+		opts     []psu.RampOption
+		expected []float64
+	}{
+		{
+			name:     "steps up",
+			opts:     []psu.RampOption{psu.RampWithVoltage(0, 12), psu.RampWithSteps(4)},
+			expected: []float64{0, 3, 6, 9, 12},
+		},
+		{
+			name:     "steps down",
+			opts:     []psu.RampOption{psu.RampWithVoltage(12, 6), psu.RampWithSteps(2)},
+			expected: []float64{12, 9, 6},
+		},
+		{
+			// 1 A/s every 100 ms gives 0.1 A per step
+			name:     "rate",
+			opts:     []psu.RampOption{psu.RampWithCurrent(0, 0.25), psu.RampWithRate(1), psu.RampWithInterval(100 * time.Millisecond)},
+			expected: []float64{0, 0.25 / 3, 0.5 / 3, 0.25},
+		},
+		{
+			name:     "nothing to do",
+			opts:     []psu.RampOption{psu.RampWithVoltage(5, 5), psu.RampWithRate(1)},
+			expected: []float64{5, 5},
+		},
+	}
+	for _, arg := range args {
+		values := t.ramp(arg.opts...).Values()
+		t.InDeltaSlice(arg.expected, values, 1e-9, arg.name)
+	}
+}
+
+func (t *RampTestSuite) TestRun() {
+	r := t.Require()
+	for _, v := range []string{"0.00", "1.00", "2.00", "3.00", "4.00", "5.00"} {
+		value, _ := strconv.ParseFloat(v, 64)
+		t.mock.On("WriteVoltage", 1, value).Return(v, nil).Once()
+	}
+	t.mock.On("Section", 1).Return(section("0.00", "0.000"), nil)
+
+	ramp := t.ramp(psu.RampWithVoltage(0, 5), psu.RampWithSteps(5))
+	r.Nil(ramp.Run(context.Background()))
+	r.Equal([]float64{0, 1, 2, 3, 4, 5}, t.written("WriteVoltage"))
+	t.mock.AssertNotCalled(t.T(), "SetState", mock.Anything, mock.Anything)
+}
+
+func (t *RampTestSuite) TestReadBackAborts() {
+	r := t.Require()
+	t.mock.On("WriteCurrent", 1, 0.0).Return("0.000", nil).Once()
+	t.mock.On("WriteCurrent", 1, 0.5).Return("0.480", nil).Once()
+	t.mock.On("Section", 1).Return(section("0.00", "0.000"), nil)
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+
+	ramp := t.ramp(psu.RampWithCurrent(0, 1), psu.RampWithSteps(2))
+	r.ErrorIs(ramp.Run(context.Background()), psu.ErrReadBack)
+	r.Equal([]float64{0, 0.5}, t.written("WriteCurrent"))
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *RampTestSuite) TestTripAborts() {
+	r := t.Require()
+	tripped := section("0.00", "0.000")
+	tripped.Trip = psu.TripOverVoltage
+	t.mock.On("WriteVoltage", 1, 10.0).Return("10.00", nil).Once()
+	t.mock.On("Section", 1).Return(tripped, nil).Once()
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+
+	ramp := t.ramp(psu.RampWithVoltage(10, 20), psu.RampWithSteps(2))
+	r.ErrorIs(ramp.Run(context.Background()), psu.ErrTripped)
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *RampTestSuite) TestCancelAborts() {
+	r := t.Require()
+	t.mock.On("WriteVoltage", 1, 0.0).Return("0.00", nil).Once()
+	t.mock.On("Section", 1).Return(section("0.00", "0.000"), nil).Once()
+	t.mock.On("SetState", 1, false).Return(false, errors.New("timeout")).Once()
+
+	ramp := t.ramp(psu.RampWithVoltage(0, 10), psu.RampWithSteps(2), psu.RampWithInterval(time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := ramp.Run(ctx)
+	r.ErrorIs(err, context.DeadlineExceeded)
+	r.ErrorContains(err, "not switched off")
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *RampTestSuite) TestOverPower() {
+	r := t.Require()
+	conn := &CPXFake{current: 20, state: true}
+	p, err := psu.New(psu.WithConn(conn))
+	r.Nil(err)
+	ramp, err := psu.NewRamp(psu.RampWithAccess(p), psu.RampWithSection(1), psu.RampWithInterval(time.Millisecond),
+		psu.RampWithVoltage(0, 30), psu.RampWithSteps(6))
+	r.Nil(err)
+
+	// Soft-start above 21 V with 20 A limit is just limited by PowerFlex
+	r.Nil(ramp.Run(context.Background()))
+	r.Equal(30.0, conn.voltage)
+	r.True(conn.state)
+}
+
+func (c *CPXFake) Open() error {
+	return nil
+}
+
+func (c *CPXFake) Close() error {
+	return nil
+}
+
+func (c *CPXFake) SetDeadline(time.Time) error {
+	return nil
+}
+
+func (c *CPXFake) Write(p []byte) (int, error) {
+	cmd := strings.TrimSpace(string(p))
+	var value float64
+	switch {
+	case cmd == "V1?":
+		fmt.Fprintf(&c.reply, "V1 %.2f\r\n", c.voltage)
+	case cmd == "I1?":
+		fmt.Fprintf(&c.reply, "I1 %.3f\r\n", c.current)
+	case cmd == "V1O?":
+		fmt.Fprintf(&c.reply, "%.2fV\r\n", psu.CPX400DP.MaxVoltageAt(c.current))
+	case cmd == "I1O?":
+		fmt.Fprintf(&c.reply, "%.3fA\r\n", 0.0)
+	case cmd == "OP1?":
+		state := "0"
+		if c.state {
+			state = "1"
+		}
+		c.reply.WriteString(state + "\r\n")
+	case cmd == "LSR1?":
+		c.reply.WriteString("0\r\n")
+	case strings.HasPrefix(cmd, "OP1 "):
+		c.state = cmd == "OP1 1"
+	default:
+		if _, err := fmt.Sscanf(cmd, "V1 %f", &value); err == nil {
+			c.voltage = value
+		} else if _, err := fmt.Sscanf(cmd, "I1 %f", &value); err == nil {
+			c.current = value
+		} else {
+			return 0, fmt.Errorf("unknown command %q", cmd)
+		}
+	}
+	return len(p), nil
+}
+
+func (c *CPXFake) Read(p []byte) (int, error) {
+	return c.reply.Read(p)
+}
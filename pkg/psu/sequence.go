@@ -0,0 +1,284 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Step of Sequence: Section is switched to State, then Until is awaited and Delay passes
+type Step struct {
+	Section int
+	State   bool
+	// Until is awaited after switching, nil doesn't wait
+	Until *Condition
+	// Timeout of Until, zero means timeout of Sequence
+	Timeout time.Duration
+	// Delay before the next step
+	Delay time.Duration
+}
+
+// Condition is awaited by Step, it must be met by consecutive readings for Hold
+type Condition struct {
+	// Name describes condition in errors
+	Name  string
+	Check func(s *Section, v Values) bool
+	Hold  time.Duration
+}
+
+// Sequence switches outputs in declared order. If any step fails,
+// outputs switched on by Sequence are switched off in reverse order.
+type Sequence struct {
+	access   Access
+	steps    []Step
+	interval time.Duration
+	timeout  time.Duration
+	// rollbackDelay passes between switching off outputs on rollback
+	rollbackDelay time.Duration
+}
+
+// StepError is returned by Sequence.Run, when step failed
+type StepError struct {
+	// Step is index of failed step
+	Step    int
+	Section int
+	Err     error
+	// Rollback is set, if outputs couldn't be switched off
+	Rollback error
+}
+
+var (
+	ErrInvalidStep      = errors.New("invalid step")
+	ErrConditionTimeout = errors.New("condition not met in time")
+	ErrNotSwitched      = errors.New("output didn't switch")
+	ErrTripped          = errors.New("protection tripped")
+)
+
+func NewSequence(opts ...SequenceOption) (*Sequence, error) {
+	s := &Sequence{
+		access:        nil,
+		steps:         nil,
+		interval:      50 * time.Millisecond,
+		timeout:       5 * time.Second,
+		rollbackDelay: 0,
+	}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.verify(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// VoltageWithin is met, when actual voltage differs from set-point by at most percent
+func VoltageWithin(percent float64, hold time.Duration) *Condition {
+	return &Condition{
+		Name: fmt.Sprintf("voltage within %g%% of set-point for %v", percent, hold),
+		Check: func(_ *Section, v Values) bool {
+			return math.Abs(v.ActualVoltage-v.SetVoltage) <= math.Abs(v.SetVoltage)*percent/100
+		},
+		Hold: hold,
+	}
+}
+
+// VoltageBelow is met, when actual voltage is below volts, e.g. output is discharged
+func VoltageBelow(volts float64, hold time.Duration) *Condition {
+	return &Condition{
+		Name: fmt.Sprintf("voltage below %g V for %v", volts, hold),
+		Check: func(_ *Section, v Values) bool {
+			return v.ActualVoltage < volts
+		},
+		Hold: hold,
+	}
+}
+
+// CurrentBelow is met, when actual current is below amps
+func CurrentBelow(amps float64, hold time.Duration) *Condition {
+	return &Condition{
+		Name: fmt.Sprintf("current below %g A for %v", amps, hold),
+		Check: func(_ *Section, v Values) bool {
+			return v.ActualCurrent < amps
+		},
+		Hold: hold,
+	}
+}
+
+// Run executes steps until all are done or one fails, in which case StepError is returned.
+// Rollback isn't affected by ctx, so outputs are switched off also when ctx is cancelled.
+func (s *Sequence) Run(ctx context.Context) error {
+	// switchedOn lists sections in order they were switched on by this run
+	var switchedOn []int
+	for i, step := range s.steps {
+		if step.State {
+			switchedOn = append(switchedOn, step.Section)
+		} else {
+			switchedOn = remove(switchedOn, step.Section)
+		}
+
+		if err := s.execute(ctx, step); err != nil {
+			log.Error("sequence step ", i+1, " failed: ", err)
+			return &StepError{
+				Step:     i,
+				Section:  step.Section,
+				Err:      err,
+				Rollback: s.rollback(switchedOn),
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Sequence) execute(ctx context.Context, step Step) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	log.Debug("switching section ", step.Section, " to ", step.State)
+	state, err := s.access.SetState(step.Section, step.State)
+	if err != nil {
+		return err
+	}
+	if state != step.State {
+		return ErrNotSwitched
+	}
+
+	if step.Until != nil {
+		timeout := step.Timeout
+		if timeout == 0 {
+			timeout = s.timeout
+		}
+		if err := s.await(ctx, step.Section, step.Until, timeout); err != nil {
+			return err
+		}
+	}
+	return sleep(ctx, step.Delay)
+}
+
+// await polls section until condition is met for Hold, timeout or ctx is done
+func (s *Sequence) await(ctx context.Context, section int, c *Condition, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var since time.Time
+	for {
+		reading, err := s.access.Section(section)
+		if err != nil {
+			return err
+		}
+		if reading.Trip != 0 {
+			return fmt.Errorf("%w: %v", ErrTripped, reading.Trip)
+		}
+		values, err := reading.Values()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		switch {
+		case !c.Check(reading, values):
+			since = time.Time{}
+		case since.IsZero():
+			since = now
+		}
+		if !since.IsZero() && now.Sub(since) >= c.Hold {
+			return nil
+		}
+
+		if err := sleep(ctx, s.interval); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return fmt.Errorf("%w: %s", ErrConditionTimeout, c.Name)
+			}
+			return err
+		}
+	}
+}
+
+// rollback switches off sections in reverse order, each one is tried
+func (s *Sequence) rollback(sections []int) error {
+	var failed []int
+	var lastErr error
+	for i := len(sections) - 1; i >= 0; i-- {
+		log.Debug("rollback: switching off section ", sections[i])
+		state, err := s.access.SetState(sections[i], false)
+		if err == nil && state {
+			err = ErrNotSwitched
+		}
+		if err != nil {
+			failed = append(failed, sections[i])
+			lastErr = err
+		}
+		if i > 0 {
+			time.Sleep(s.rollbackDelay)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("sections %v not switched off: %w", failed, lastErr)
+	}
+	return nil
+}
+
+func (s *Sequence) verify() error {
+	if s.access == nil {
+		return ErrNoAccess
+	}
+	if len(s.steps) == 0 {
+		return ErrInvalidStep
+	}
+	return nil
+}
+
+func (e *StepError) Error() string {
+	msg := fmt.Sprintf("step %d (section %d): %v", e.Step+1, e.Section, e.Err)
+	if e.Rollback != nil {
+		msg += ", rollback failed: " + e.Rollback.Error()
+	}
+	return msg
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+func (st Step) verify() error {
+	if st.Section < 1 || st.Timeout < 0 || st.Delay < 0 {
+		return ErrInvalidStep
+	}
+	if st.Until != nil && (st.Until.Check == nil || st.Until.Hold < 0) {
+		return ErrInvalidStep
+	}
+	return nil
+}
+
+func remove(sections []int, section int) []int {
+	result := sections[:0]
+	for _, s := range sections {
+		if s != section {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// sleep returns ctx error, if ctx is done before d passes
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
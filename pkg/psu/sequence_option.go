@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"time"
+)
+
+type SequenceOption func(*Sequence) error
+
+func SequenceWithPSU(p *PSU) SequenceOption {
+	return func(s *Sequence) error {
+		return SequenceWithAccess(p)(s)
+	}
+}
+
+func SequenceWithAccess(a Access) SequenceOption {
+	return func(s *Sequence) error {
+		s.access = a
+		return nil
+	}
+}
+
+func SequenceWithSteps(steps ...Step) SequenceOption {
+	return func(s *Sequence) error {
+		for _, step := range steps {
+			if err := step.verify(); err != nil {
+				return err
+			}
+		}
+		s.steps = append(s.steps, steps...)
+		return nil
+	}
+}
+
+// SequenceWithInterval sets how often conditions are checked
+func SequenceWithInterval(t time.Duration) SequenceOption {
+	return func(s *Sequence) error {
+		if t <= 0 {
+			return ErrInvalidPeriod
+		}
+		s.interval = t
+		return nil
+	}
+}
+
+// SequenceWithTimeout sets timeout of conditions, which don't specify one
+func SequenceWithTimeout(t time.Duration) SequenceOption {
+	return func(s *Sequence) error {
+		if t <= 0 {
+			return ErrInvalidPeriod
+		}
+		s.timeout = t
+		return nil
+	}
+}
+
+// SequenceWithRollbackDelay sets delay between switching off outputs on rollback
+func SequenceWithRollbackDelay(t time.Duration) SequenceOption {
+	return func(s *Sequence) error {
+		if t < 0 {
+			return ErrInvalidPeriod
+		}
+		s.rollbackDelay = t
+		return nil
+	}
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/psu"
+)
+
+type SequenceTestSuite struct {
+	suite.Suite
+	mock *AccessMocker
+}
+
+func TestSequence(t *testing.T) {
+	suite.Run(t, new(SequenceTestSuite))
+}
+
+func (t *SequenceTestSuite) SetupTest() {
+	t.mock = new(AccessMocker)
+}
+
+func (t *SequenceTestSuite) sequence(steps ...psu.Step) *psu.Sequence {
+	s, err := psu.NewSequence(
+		psu.SequenceWithAccess(t.mock),
+		psu.SequenceWithSteps(steps...),
+		psu.SequenceWithInterval(time.Millisecond),
+		psu.SequenceWithTimeout(30*time.Millisecond),
+	)
+	t.Require().Nil(err)
+	return s
+}
+
+func section(voltage, current string) *psu.Section {
+	return &psu.Section{
+		State:         true,
+		ActualVoltage: voltage,
+		SetVoltage:    "12.00",
+		ActualCurrent: current,
+		SetCurrent:    "1.000",
+	}
+}
+
+// switched returns arguments of SetState calls in order
+func (t *SequenceTestSuite) switched() [][]interface{} {
+	var calls [][]interface{}
+	for _, call := range t.mock.Calls {
+		if call.Method == "SetState" {
+			calls = append(calls, call.Arguments)
+		}
+	}
+	return calls
+}
+
+func (t *SequenceTestSuite) TestNew() {
+	args := []struct {
+		name string
+		opts []psu.SequenceOption
+		err  error
+	}{
+		{name: "no access", opts: nil, err: psu.ErrNoAccess},
+		{name: "no steps", opts: []psu.SequenceOption{psu.SequenceWithAccess(t.mock)}, err: psu.ErrInvalidStep},
+		{name: "section", opts: []psu.SequenceOption{psu.SequenceWithSteps(psu.Step{Section: 0})}, err: psu.ErrInvalidStep},
+		{name: "delay", opts: []psu.SequenceOption{psu.SequenceWithSteps(psu.Step{Section: 1, Delay: -1})}, err: psu.ErrInvalidStep},
+		{name: "condition", opts: []psu.SequenceOption{psu.SequenceWithSteps(psu.Step{Section: 1, Until: &psu.Condition{}})}, err: psu.ErrInvalidStep},
+		{name: "interval", opts: []psu.SequenceOption{psu.SequenceWithInterval(0)}, err: psu.ErrInvalidPeriod},
+		{name: "timeout", opts: []psu.SequenceOption{psu.SequenceWithTimeout(0)}, err: psu.ErrInvalidPeriod},
+		{
+			name: "all good",
+			opts: []psu.SequenceOption{psu.SequenceWithAccess(t.mock), psu.SequenceWithSteps(psu.Step{Section: 1, State: true})},
+			err:  nil,
+		},
+	}
+	for _, arg := range args {
+		s, err := psu.NewSequence(arg.opts...)
+		t.ErrorIs(err, arg.err, arg.name)
+		t.Equal(arg.err == nil, s != nil, arg.name)
+	}
+}
+
+func (t *SequenceTestSuite) TestRun() {
+	r := t.Require()
+	t.mock.On("SetState", 1, true).Return(true, nil).Once()
+	t.mock.On("SetState", 2, true).Return(true, nil).Once()
+	// Core rail rises
+	t.mock.On("Section", 1).Return(section("5.00", "0.100"), nil).Once()
+	t.mock.On("Section", 1).Return(section("11.90", "0.100"), nil)
+
+	s := t.sequence(
+		psu.Step{Section: 1, State: true, Until: psu.VoltageWithin(2, 5*time.Millisecond), Delay: 5 * time.Millisecond},
+		psu.Step{Section: 2, State: true},
+	)
+	start := time.Now()
+	r.Nil(s.Run(context.Background()))
+	r.GreaterOrEqual(time.Since(start), 10*time.Millisecond)
+	r.Equal([][]interface{}{{1, true}, {2, true}}, t.switched())
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *SequenceTestSuite) TestHold() {
+	r := t.Require()
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+	// Current drops, but comes back before hold passes
+	t.mock.On("Section", 1).Return(section("0.00", "0.000"), nil).Times(3)
+	t.mock.On("Section", 1).Return(section("0.00", "0.500"), nil).Once()
+	t.mock.On("Section", 1).Return(section("0.00", "0.000"), nil)
+
+	s := t.sequence(psu.Step{Section: 1, State: false, Until: psu.CurrentBelow(0.01, 10*time.Millisecond)})
+	r.Nil(s.Run(context.Background()))
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *SequenceTestSuite) TestTimeoutRollsBack() {
+	r := t.Require()
+	t.mock.On("SetState", 1, true).Return(true, nil).Once()
+	t.mock.On("SetState", 2, true).Return(true, nil).Once()
+	t.mock.On("SetState", 2, false).Return(false, nil).Once()
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+	// I/O rail never settles
+	t.mock.On("Section", 2).Return(section("12.00", "2.000"), nil)
+
+	s := t.sequence(
+		psu.Step{Section: 1, State: true},
+		psu.Step{Section: 2, State: true, Until: psu.CurrentBelow(1, 0)},
+	)
+	err := s.Run(context.Background())
+	r.ErrorIs(err, psu.ErrConditionTimeout)
+	var stepErr *psu.StepError
+	r.ErrorAs(err, &stepErr)
+	r.Equal(1, stepErr.Step)
+	r.Equal(2, stepErr.Section)
+	r.Nil(stepErr.Rollback)
+
+	// Reverse power-down
+	r.Equal([][]interface{}{{1, true}, {2, true}, {2, false}, {1, false}}, t.switched())
+}
+
+func (t *SequenceTestSuite) TestTripRollsBack() {
+	r := t.Require()
+	tripped := section("0.00", "0.000")
+	tripped.Trip = psu.TripOverCurrent
+	t.mock.On("SetState", 1, true).Return(true, nil).Once()
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+	t.mock.On("Section", 1).Return(tripped, nil)
+
+	s := t.sequence(psu.Step{Section: 1, State: true, Until: psu.VoltageWithin(2, 0), Timeout: time.Hour})
+	r.ErrorIs(s.Run(context.Background()), psu.ErrTripped)
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *SequenceTestSuite) TestRollbackSkipsSwitchedOff() {
+	r := t.Require()
+	t.mock.On("SetState", 1, true).Return(true, nil).Once()
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+	t.mock.On("SetState", 2, true).Return(false, errors.New("timeout")).Once()
+	// Section 2 might have been switched, even though reply was lost
+	t.mock.On("SetState", 2, false).Return(true, nil).Once()
+
+	s := t.sequence(
+		psu.Step{Section: 1, State: true},
+		psu.Step{Section: 1, State: false},
+		psu.Step{Section: 2, State: true},
+	)
+	err := s.Run(context.Background())
+	var stepErr *psu.StepError
+	r.ErrorAs(err, &stepErr)
+	r.Equal(2, stepErr.Step)
+	r.ErrorIs(stepErr.Rollback, psu.ErrNotSwitched)
+	r.Equal([][]interface{}{{1, true}, {1, false}, {2, true}, {2, false}}, t.switched())
+}
+
+func (t *SequenceTestSuite) TestCancel() {
+	r := t.Require()
+	t.mock.On("SetState", 1, true).Return(true, nil).Once()
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+
+	s := t.sequence(
+		psu.Step{Section: 1, State: true, Delay: time.Hour},
+		psu.Step{Section: 2, State: true},
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	r.ErrorIs(s.Run(ctx), context.DeadlineExceeded)
+	t.mock.AssertExpectations(t.T())
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Setpoints of single section. Zero OverVoltage or OverCurrent is left unchanged,
+// e.g. when Setter isn't Protector.
+type Setpoints struct {
+	Voltage     float64
+	Current     float64
+	OverVoltage float64
+	OverCurrent float64
+}
+
+// Envelope is user limit of Setpoints of single section, zero value disables particular limit
+type Envelope struct {
+	Section        int
+	MaxVoltage     float64
+	MaxCurrent     float64
+	MaxOverVoltage float64
+	MaxOverCurrent float64
+}
+
+var (
+	ErrNoProtector      = errors.New("Access can't change protection")
+	ErrInvalidSetpoints = errors.New("invalid setpoints")
+)
+
+// Check returns ErrOutsideEnvelope, if setpoints are outside of model range.
+// Protection tripping below set-point is refused too, as output would trip at once.
+// Setpoints above model power are accepted, see Model.Deliverable.
+func (s Setpoints) Check(m Model) error {
+	if err := m.Check(s.Voltage, s.Current); err != nil {
+		return err
+	}
+	if s.OverVoltage != 0 {
+		if err := m.CheckOverVoltage(s.OverVoltage); err != nil {
+			return err
+		}
+		if s.OverVoltage <= s.Voltage {
+			return fmt.Errorf("%w: OVP %.2f V must be above voltage %.2f V", ErrInvalidSetpoints, s.OverVoltage, s.Voltage)
+		}
+	}
+	if s.OverCurrent != 0 {
+		if err := m.CheckOverCurrent(s.OverCurrent); err != nil {
+			return err
+		}
+		if s.OverCurrent <= s.Current {
+			return fmt.Errorf("%w: OCP %.3f A must be above current %.3f A", ErrInvalidSetpoints, s.OverCurrent, s.Current)
+		}
+	}
+	return nil
+}
+
+// Check returns ErrOutsideEnvelope, if setpoints exceed envelope
+func (e Envelope) Check(s Setpoints) error {
+	for _, limit := range []struct {
+		name, unit string
+		value, max float64
+	}{
+		{name: "voltage", unit: "V", value: s.Voltage, max: e.MaxVoltage},
+		{name: "current", unit: "A", value: s.Current, max: e.MaxCurrent},
+		{name: "OVP", unit: "V", value: s.OverVoltage, max: e.MaxOverVoltage},
+		{name: "OCP", unit: "A", value: s.OverCurrent, max: e.MaxOverCurrent},
+	} {
+		if limit.max > 0 && limit.value > limit.max {
+			return fmt.Errorf("%w: section %d %s is limited to %.3f %s, requested %.3f %s",
+				ErrOutsideEnvelope, e.Section, limit.name, limit.max, limit.unit, limit.value, limit.unit)
+		}
+	}
+	return nil
+}
+
+// ReadSetpoints returns setpoints of section, protection is read only if setter is Protector
+func ReadSetpoints(access Access, setter Setter, section int) (Setpoints, error) {
+	data, err := access.Section(section)
+	if err != nil {
+		return Setpoints{}, err
+	}
+	values, err := data.Values()
+	if err != nil {
+		return Setpoints{}, err
+	}
+	s := Setpoints{Voltage: values.SetVoltage, Current: values.SetCurrent}
+	protector, ok := setter.(Protector)
+	if !ok {
+		return s, nil
+	}
+	for _, read := range []struct {
+		read func(int) (string, error)
+		dst  *float64
+	}{
+		{read: protector.OverVoltage, dst: &s.OverVoltage},
+		{read: protector.OverCurrent, dst: &s.OverCurrent},
+	} {
+		reply, err := read.read(section)
+		if err != nil {
+			return Setpoints{}, err
+		}
+		if *read.dst, err = strconv.ParseFloat(strings.TrimSpace(reply), 64); err != nil {
+			return Setpoints{}, err
+		}
+	}
+	return s, nil
+}
+
+// WriteSetpoints changes setpoints of section from before to after and verifies each one by read back.
+// Order of writes keeps PSU within model envelope and protection above set-points all the time.
+// Setpoints read back are returned, also on failure.
+func WriteSetpoints(setter Setter, section int, before, after Setpoints) (Setpoints, error) {
+	protector, _ := setter.(Protector)
+	if (after.OverVoltage != 0 || after.OverCurrent != 0) && protector == nil {
+		return before, ErrNoProtector
+	}
+	written := before
+	type write struct {
+		write func(int, float64) (string, error)
+		value float64
+		dst   *float64
+		// tolerance is instrument resolution, 10 mV and 1 mA
+		tolerance float64
+	}
+	var first, second, last []write
+	voltage := write{write: setter.WriteVoltage, value: after.Voltage, dst: &written.Voltage, tolerance: 0.01}
+	current := write{write: setter.WriteCurrent, value: after.Current, dst: &written.Current, tolerance: 0.001}
+	// Decreased set-point is written first, so product of both stays within model power
+	if after.Current < before.Current {
+		second = append(second, current, voltage)
+	} else {
+		second = append(second, voltage, current)
+	}
+	if after.OverVoltage != 0 {
+		ovp := write{write: protector.WriteOverVoltage, value: after.OverVoltage, dst: &written.OverVoltage, tolerance: 0.01}
+		// Raised trip point is written before set-point, lowered one after it
+		if after.OverVoltage > before.OverVoltage {
+			first = append(first, ovp)
+		} else {
+			last = append(last, ovp)
+		}
+	}
+	if after.OverCurrent != 0 {
+		ocp := write{write: protector.WriteOverCurrent, value: after.OverCurrent, dst: &written.OverCurrent, tolerance: 0.001}
+		if after.OverCurrent > before.OverCurrent {
+			first = append(first, ocp)
+		} else {
+			last = append(last, ocp)
+		}
+	}
+	for _, w := range append(append(first, second...), last...) {
+		reply, err := w.write(section, w.value)
+		if err != nil {
+			return written, err
+		}
+		readBack, err := strconv.ParseFloat(strings.TrimSpace(reply), 64)
+		if err != nil {
+			return written, err
+		}
+		*w.dst = readBack
+		if math.Abs(readBack-w.value) > w.tolerance {
+			return written, fmt.Errorf("%w: written %.3f, read %s", ErrReadBack, w.value, reply)
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/psu"
+)
+
+type SetpointsTestSuite struct {
+	suite.Suite
+	mock *ProtectorMocker
+}
+
+type ProtectorMocker struct {
+	SetterMocker
+}
+
+func TestSetpoints(t *testing.T) {
+	suite.Run(t, new(SetpointsTestSuite))
+}
+
+func (t *SetpointsTestSuite) SetupTest() {
+	t.mock = new(ProtectorMocker)
+}
+
+func (p *ProtectorMocker) OverVoltage(section int) (string, error) {
+	args := p.Called(section)
+	return args.String(0), args.Error(1)
+}
+
+func (p *ProtectorMocker) OverCurrent(section int) (string, error) {
+	args := p.Called(section)
+	return args.String(0), args.Error(1)
+}
+
+func (p *ProtectorMocker) WriteOverVoltage(section int, voltage float64) (string, error) {
+	args := p.Called(section, voltage)
+	return args.String(0), args.Error(1)
+}
+
+func (p *ProtectorMocker) WriteOverCurrent(section int, current float64) (string, error) {
+	args := p.Called(section, current)
+	return args.String(0), args.Error(1)
+}
+
+// written returns names of write methods in order of calls
+func (t *SetpointsTestSuite) written() []string {
+	var methods []string
+	for _, call := range t.mock.Calls {
+		methods = append(methods, call.Method)
+	}
+	return methods
+}
+
+func (t *SetpointsTestSuite) TestCheck() {
+	args := []struct {
+		name string
+		s    psu.Setpoints
+		err  error
+	}{
+		{name: "valid", s: psu.Setpoints{Voltage: 12, Current: 1, OverVoltage: 13, OverCurrent: 2}, err: nil},
+		{name: "protection unchanged", s: psu.Setpoints{Voltage: 12, Current: 1}, err: nil},
+		{name: "over power", s: psu.Setpoints{Voltage: 60, Current: 7.5}, err: nil},
+		{name: "over current", s: psu.Setpoints{Voltage: 12, Current: 21}, err: psu.ErrOutsideEnvelope},
+		{name: "OVP outside model", s: psu.Setpoints{Voltage: 12, Current: 1, OverVoltage: 70}, err: psu.ErrOutsideEnvelope},
+		{name: "OVP below voltage", s: psu.Setpoints{Voltage: 12, Current: 1, OverVoltage: 11}, err: psu.ErrInvalidSetpoints},
+		{name: "OCP below current", s: psu.Setpoints{Voltage: 12, Current: 3, OverCurrent: 2}, err: psu.ErrInvalidSetpoints},
+	}
+	for _, arg := range args {
+		t.Require().ErrorIs(arg.s.Check(psu.CPX400DP), arg.err, arg.name)
+	}
+}
+
+func (t *SetpointsTestSuite) TestEnvelope() {
+	r := t.Require()
+	e := psu.Envelope{Section: 1, MaxVoltage: 15, MaxOverCurrent: 3}
+	r.Nil(e.Check(psu.Setpoints{Voltage: 15, Current: 10, OverCurrent: 3}))
+	r.ErrorIs(e.Check(psu.Setpoints{Voltage: 15.1}), psu.ErrOutsideEnvelope)
+	r.ErrorIs(e.Check(psu.Setpoints{Voltage: 12, OverCurrent: 3.5}), psu.ErrOutsideEnvelope)
+}
+
+func (t *SetpointsTestSuite) TestRead() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(section("11.98", "0.500"), nil)
+	t.mock.On("OverVoltage", 1).Return("13.50", nil)
+	t.mock.On("OverCurrent", 1).Return("2.200", nil)
+
+	s, err := psu.ReadSetpoints(t.mock, t.mock, 1)
+	r.Nil(err)
+	r.Equal(psu.Setpoints{Voltage: 12, Current: 1, OverVoltage: 13.5, OverCurrent: 2.2}, s)
+}
+
+func (t *SetpointsTestSuite) TestReadWithoutProtector() {
+	r := t.Require()
+	setter := new(SetterMocker)
+	setter.On("Section", 1).Return(section("11.98", "0.500"), nil)
+
+	s, err := psu.ReadSetpoints(setter, setter, 1)
+	r.Nil(err)
+	r.Equal(psu.Setpoints{Voltage: 12, Current: 1}, s)
+}
+
+func (t *SetpointsTestSuite) TestWriteRaise() {
+	r := t.Require()
+	before := psu.Setpoints{Voltage: 12, Current: 1, OverVoltage: 13, OverCurrent: 2}
+	after := psu.Setpoints{Voltage: 24, Current: 2, OverVoltage: 26, OverCurrent: 3}
+	t.mock.On("WriteOverVoltage", 1, 26.0).Return("26.00", nil)
+	t.mock.On("WriteOverCurrent", 1, 3.0).Return("3.000", nil)
+	t.mock.On("WriteVoltage", 1, 24.0).Return("24.00", nil)
+	t.mock.On("WriteCurrent", 1, 2.0).Return("2.000", nil)
+
+	written, err := psu.WriteSetpoints(t.mock, 1, before, after)
+	r.Nil(err)
+	r.Equal(after, written)
+	r.Equal([]string{"WriteOverVoltage", "WriteOverCurrent", "WriteVoltage", "WriteCurrent"}, t.written())
+}
+
+func (t *SetpointsTestSuite) TestWriteLower() {
+	r := t.Require()
+	before := psu.Setpoints{Voltage: 24, Current: 2, OverVoltage: 26, OverCurrent: 3}
+	after := psu.Setpoints{Voltage: 12, Current: 1, OverVoltage: 13, OverCurrent: 2}
+	t.mock.On("WriteCurrent", 1, 1.0).Return("1.000", nil)
+	t.mock.On("WriteVoltage", 1, 12.0).Return("12.00", nil)
+	t.mock.On("WriteOverVoltage", 1, 13.0).Return("13.00", nil)
+	t.mock.On("WriteOverCurrent", 1, 2.0).Return("2.000", nil)
+
+	written, err := psu.WriteSetpoints(t.mock, 1, before, after)
+	r.Nil(err)
+	r.Equal(after, written)
+	r.Equal([]string{"WriteCurrent", "WriteVoltage", "WriteOverVoltage", "WriteOverCurrent"}, t.written())
+}
+
+func (t *SetpointsTestSuite) TestWriteReadBack() {
+	r := t.Require()
+	before := psu.Setpoints{Voltage: 12, Current: 1}
+	after := psu.Setpoints{Voltage: 24, Current: 1}
+	t.mock.On("WriteVoltage", 1, 24.0).Return("12.00", nil)
+
+	written, err := psu.WriteSetpoints(t.mock, 1, before, after)
+	r.ErrorIs(err, psu.ErrReadBack)
+	r.Equal(before, written)
+	// Nothing is written after failure
+	t.mock.AssertNotCalled(t.T(), "WriteCurrent", mock.Anything, mock.Anything)
+}
+
+func (t *SetpointsTestSuite) TestWriteError() {
+	r := t.Require()
+	errWrite := errors.New("interesting error")
+	before := psu.Setpoints{Voltage: 12, Current: 1}
+	after := psu.Setpoints{Voltage: 12, Current: 2}
+	t.mock.On("WriteVoltage", 1, 12.0).Return("12.00", nil)
+	t.mock.On("WriteCurrent", 1, 2.0).Return("", errWrite)
+
+	written, err := psu.WriteSetpoints(t.mock, 1, before, after)
+	r.ErrorIs(err, errWrite)
+	r.Equal(before, written)
+}
+
+func (t *SetpointsTestSuite) TestWriteWithoutProtector() {
+	r := t.Require()
+	setter := new(SetterMocker)
+	_, err := psu.WriteSetpoints(setter, 1, psu.Setpoints{}, psu.Setpoints{Voltage: 1, OverVoltage: 2})
+	r.ErrorIs(err, psu.ErrNoProtector)
+	setter.AssertNotCalled(t.T(), "WriteVoltage", mock.Anything, mock.Anything)
+}
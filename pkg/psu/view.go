@@ -6,11 +6,16 @@
 package psu
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"image/color"
 	"strconv"
+	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
@@ -19,20 +24,76 @@ import (
 
 type View struct {
 	psu            Access
+	model          Model
 	sectionNumbers []int
 	sections       []*viewSection
 	trigger, close chan struct{}
-	ticker         *time.Ticker
-	refreshButton  *widget.Button
+	// refreshed is closed, when background refresh is done
+	refreshed     chan struct{}
+	ticker        *time.Ticker
+	refreshButton *widget.Button
+	// energyGap is max time between readings to be integrated, zero disables energy accounting
+	energyGap time.Duration
+
+	dataLogger    *DataLogger
+	recordButton  *widget.Button
+	recordingMtx  sync.Mutex
+	recording     context.Context
+	stopRecording context.CancelFunc
+
+	// cycleOpts configure PowerCycle started by section button, nil hides buttons
+	cycleOpts []CycleOption
+
+	// autoOffStep is time output is switched on for and extended by, zero disables timed outputs
+	autoOffStep time.Duration
+	autoOff     *AutoOff
+
+	// limits are enforced by limiter, which runs until View is closed
+	limits  []Limit
+	limiter *Limiter
+
+	// eventHandlers are notified about events found by View, e.g. exceeded limits
+	eventHandlers []func(Event)
+
+	// alertHistory is number of alerts kept, zero disables alerts
+	alertHistory int
+	alerts       *viewAlerts
+
+	// chartWindow is time window shown by charts at start, zero disables charts. Charts are exported to chartDir.
+	chartWindow time.Duration
+	chartDir    string
+
+	// setter changes setpoints from dialog, nil hides setpoints buttons. Setpoints are limited by envelopes.
+	setter       Setter
+	envelopes    []Envelope
+	setpointsMtx sync.Mutex
+
+	// connection is status badge, failed refresh is retried with backoff between reconnectMin and reconnectMax
+	reconnectMin, reconnectMax time.Duration
+	connection                 *viewConnection
 }
 
 type viewSection struct {
 	section int
 	psu     Access
+	model   Model
 	number  *widget.Label
 	voltage *widget.Label
 	current *widget.Label
 	enable  *widget.Button
+	// status shows failed switching of output, it is hidden otherwise
+	status *widget.Label
+	mode   *widget.Label
+	// requestRefresh asks View to refresh, so all readings are done by its goroutine
+	requestRefresh func()
+	// modeBackground highlights mode badge, when output is current limiting
+	modeBackground *canvas.Rectangle
+	energy         *viewEnergy
+	cycle          *viewCycle
+	autoOff        *viewAutoOff
+	limit          *viewLimit
+	chart          *viewChart
+	setpoints      *viewSetpoints
 }
 
 type Access interface {
@@ -48,16 +109,24 @@ var (
 func NewView(opts ...ViewOption) (*View, error) {
 	v := &View{
 		psu:           nil,
+		model:         CPX400DP,
 		sections:      nil,
 		trigger:       make(chan struct{}),
 		close:         make(chan struct{}),
+		refreshed:     make(chan struct{}),
 		ticker:        time.NewTicker(1 * time.Hour),
 		refreshButton: widget.NewButtonWithIcon("", theme.MediaReplayIcon(), nil),
+		recordButton:  widget.NewButtonWithIcon("", theme.MediaRecordIcon(), nil),
+		reconnectMin:  defaultReconnectMin,
+		reconnectMax:  defaultReconnectMax,
 	}
 	v.ticker.Stop()
 	v.refreshButton.OnTapped = func() {
 		v.Refresh()
 	}
+	v.recordButton.OnTapped = func() {
+		v.toggleRecording()
+	}
 	for _, opt := range opts {
 		if err := opt(v); err != nil {
 			return nil, err
@@ -68,45 +137,188 @@ func NewView(opts ...ViewOption) (*View, error) {
 		return nil, err
 	}
 
+	v.connection = newViewConnection(v.reconnectMin, v.reconnectMax)
+
+	if v.alertHistory > 0 {
+		// Before anything else uses access, so outputs switched by View aren't alerts
+		if err := v.watchAlerts(); err != nil {
+			return nil, err
+		}
+	}
+
+	if v.autoOffStep > 0 {
+		autoOff, err := NewAutoOff(AutoOffWithAccess(v.psu), AutoOffWithHandler(v.switchedOff))
+		if err != nil {
+			return nil, err
+		}
+		v.autoOff = autoOff
+	}
+
 	v.sections = make([]*viewSection, len(v.sectionNumbers))
 	for i, sec := range v.sectionNumbers {
-		v.sections[i] = newViewSection(sec, v.psu)
+		v.sections[i] = newViewSection(sec, v.psu, v.model)
+		v.sections[i].requestRefresh = v.requestRefresh
+		if v.energyGap > 0 {
+			v.sections[i].energy = newViewEnergy(v.energyGap)
+		}
+		if v.cycleOpts != nil {
+			v.sections[i].cycle = newViewCycle(sec, v.psu, v.cycleOpts)
+		}
+		if v.autoOff != nil {
+			v.sections[i].autoOff = newViewAutoOff(sec, v.autoOff, v.autoOffStep)
+		}
+		if v.limits != nil {
+			v.sections[i].limit = newViewLimit(sec, v.limits)
+		}
+		if v.chartWindow > 0 {
+			v.sections[i].chart = newViewChart(sec, v.chartWindow, v.chartDir)
+		}
+		if v.setter != nil {
+			v.sections[i].setpoints = newViewSetpoints(sec, v)
+		}
+	}
+	if v.limits != nil {
+		// Sections are ready to show events
+		if err := v.startLimiter(); err != nil {
+			return nil, err
+		}
 	}
 
 	go v.backgroundRefresh()
+	if v.autoOff != nil {
+		go v.countdown()
+	}
 
 	return v, nil
 }
 
 func (v *View) Content() fyne.CanvasObject {
-	title := container.NewHBox(layout.NewSpacer(), widget.NewLabel("CPX400"), layout.NewSpacer(), v.refreshButton)
+	title := container.NewHBox(v.connection.controls(), layout.NewSpacer(), widget.NewLabel("CPX400"), layout.NewSpacer())
+	if v.dataLogger != nil {
+		title.Add(v.recordButton)
+	}
+	title.Add(v.refreshButton)
 	sections := len(v.sections)
 
 	number := container.NewGridWithColumns(sections)
 	enable := container.NewGridWithColumns(sections)
 	voltage := container.NewGridWithColumns(sections)
 	current := container.NewGridWithColumns(sections)
+	mode := container.NewGridWithColumns(sections)
+	status := container.NewGridWithColumns(sections)
 	for _, section := range v.sections {
 		number.Add(section.number)
-		enable.Add(section.enable)
+		if section.autoOff != nil {
+			enable.Add(section.autoOff.controls(section.enable))
+		} else {
+			enable.Add(section.enable)
+		}
+		status.Add(section.status)
 		voltage.Add(section.voltage)
 		current.Add(section.current)
+		mode.Add(container.NewMax(section.modeBackground, section.mode))
+	}
+	rows := []fyne.CanvasObject{title}
+	if v.alerts != nil {
+		rows = append(rows, v.alerts.controls())
 	}
-	return container.NewGridWithRows(5,
-		title,
+	rows = append(rows,
 		number,
 		enable,
+		status,
 		voltage,
-		current)
+		current,
+		mode,
+	)
+
+	if v.limiter != nil {
+		limit := container.NewGridWithColumns(sections)
+		for _, section := range v.sections {
+			limit.Add(section.limit.controls())
+		}
+		rows = append(rows, limit)
+	}
+
+	if v.energyGap > 0 {
+		energy := container.NewGridWithColumns(sections)
+		controls := container.NewGridWithColumns(sections)
+		for _, section := range v.sections {
+			energy.Add(section.energy.energy)
+			controls.Add(section.energy.controls())
+		}
+		rows = append(rows, energy, controls)
+	}
+
+	if v.setter != nil {
+		setpoints := container.NewGridWithColumns(sections)
+		for _, section := range v.sections {
+			setpoints.Add(section.setpoints.button)
+		}
+		rows = append(rows, setpoints)
+	}
+
+	if v.cycleOpts != nil {
+		cycle := container.NewGridWithColumns(sections)
+		for _, section := range v.sections {
+			cycle.Add(section.cycle.button)
+		}
+		rows = append(rows, cycle)
+	}
+
+	grid := container.NewGridWithRows(len(rows), rows...)
+	if v.chartWindow == 0 {
+		return grid
+	}
+	// Charts take the rest of window
+	charts := container.NewGridWithColumns(sections)
+	for _, section := range v.sections {
+		charts.Add(section.chart.controls())
+	}
+	return container.NewBorder(grid, nil, nil, nil, charts)
+}
 
+// Accumulator returns energy accumulator of section, nil if energy accounting is disabled
+func (v *View) Accumulator(section int) *Accumulator {
+	for _, s := range v.sections {
+		if s.section == section && s.energy != nil {
+			return s.energy.accumulator
+		}
+	}
+	return nil
 }
 
 func (v *View) Refresh() {
 	v.trigger <- struct{}{}
 }
 
+// requestRefresh is Refresh for background tasks, it gives up once View is closed.
+// All refreshes are done by single goroutine, so readings and widgets aren't updated twice at once.
+func (v *View) requestRefresh() {
+	select {
+	case v.trigger <- struct{}{}:
+	case <-v.close:
+	}
+}
+
+// Close stops background tasks of View. Outputs with AutoOff still armed are switched off at once.
+// Refresh in progress is awaited.
 func (v *View) Close() {
+	v.StopRecording()
+	if v.autoOff != nil {
+		// Outputs still armed are switched off, as promised by their countdown
+		if err := v.autoOff.Close(); err != nil {
+			log.Error("error on switching off timed outputs on close: ", err)
+		}
+	}
+	for _, section := range v.sections {
+		if section.cycle != nil {
+			section.cycle.stop()
+			section.cycle.wait()
+		}
+	}
 	close(v.close)
+	<-v.refreshed
+	v.connection.stop()
 }
 
 func (v *View) BackgroundRefresh(t time.Duration) {
@@ -117,6 +329,7 @@ func (v *View) StopBackgroundRefresh() {
 }
 
 func (v *View) backgroundRefresh() {
+	defer close(v.refreshed)
 	running := true
 	for running {
 		select {
@@ -125,48 +338,89 @@ func (v *View) backgroundRefresh() {
 		case <-v.trigger:
 			v.refresh()
 		case <-v.ticker.C:
-			v.refresh()
+			// Reconnect backoff limits polling of PSU, which doesn't answer
+			if !v.connection.reconnecting() {
+				v.refresh()
+			}
 		}
 	}
 }
 
 func (v *View) refresh() {
+	read := false
 	for _, section := range v.sections {
-		section.refresh()
+		if section.refresh() {
+			read = true
+		}
+	}
+	if read {
+		v.connection.connected(time.Now())
+	} else {
+		v.connection.failed(v.requestRefresh)
 	}
 }
 
-func newViewSection(number int, access Access) *viewSection {
+func newViewSection(number int, access Access, model Model) *viewSection {
 	section := strconv.FormatInt(int64(number), 32)
 	v := &viewSection{
-		section: number,
-		psu:     access,
-		number:  widget.NewLabelWithStyle(section, fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
-		voltage: widget.NewLabelWithStyle("0/8", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
-		current: widget.NewLabelWithStyle("0/13", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
-		enable:  widget.NewButton("", func() {}),
+		section:        number,
+		psu:            access,
+		model:          model,
+		number:         widget.NewLabelWithStyle(section, fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		voltage:        widget.NewLabelWithStyle("0/8", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		current:        widget.NewLabelWithStyle("0/13", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		enable:         widget.NewButton("", func() {}),
+		status:         widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{Italic: true}),
+		mode:           widget.NewLabelWithStyle(ModeUnknown.String(), fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		modeBackground: canvas.NewRectangle(color.Transparent),
 	}
 	v.enable.Importance = widget.HighImportance
+	v.status.Wrapping = fyne.TextWrapWord
+	v.status.Hide()
+	// State of output is unknown until first reading
+	v.enable.Disable()
 	return v
 }
 
-func (vs *viewSection) refresh() {
+// refresh reads section and returns whether it succeeded
+func (vs *viewSection) refresh() bool {
 	data, err := vs.psu.Section(vs.section)
 	if err != nil {
 		const errText = "err"
 		vs.voltage.SetText(errText)
 		vs.current.SetText(errText)
-		return
+		vs.setMode(ModeUnknown)
+		// Nobody should switch output based on stale state
+		vs.setKnown(false)
+		if vs.energy != nil {
+			vs.energy.lost()
+		}
+		if vs.chart != nil {
+			vs.chart.lost()
+		}
+		return false
 	}
 	text := data.ActualVoltage + " / " + data.SetVoltage + " V DC"
 	vs.voltage.SetText(text)
 
 	text = data.ActualCurrent + " / " + data.SetCurrent + " A"
+	if maxCurrent, ok := vs.undeliverable(data); ok {
+		text += fmt.Sprintf(" (max %.2f)", maxCurrent)
+	}
 	vs.current.SetText(text)
 
+	vs.setMode(data.Mode)
+	if vs.energy != nil {
+		vs.energy.add(data)
+	}
+	if vs.chart != nil {
+		vs.chart.add(data)
+	}
+
+	state := !data.State
 	vs.enable.OnTapped = func() {
-		_, _ = vs.psu.SetState(vs.section, !data.State)
-		vs.refresh()
+		// Switching and refresh wait for PSU, UI mustn't
+		go vs.switchTo(state)
 	}
 
 	if data.State {
@@ -176,7 +430,79 @@ func (vs *viewSection) refresh() {
 		vs.enable.Icon = theme.MediaPlayIcon()
 		vs.enable.SetText("ON")
 	}
+	vs.setKnown(true)
+	return true
+}
+
+// switchTo switches output as tapped by user, failure is shown in section until next switching
+func (vs *viewSection) switchTo(state bool) {
+	switched, err := vs.psu.SetState(vs.section, state)
+	if err == nil && switched != state {
+		err = ErrNotSwitched
+	}
+	if err != nil {
+		log.Error("error on switching section ", vs.section, ": ", err)
+		action := "off"
+		if state {
+			action = "on"
+		}
+		vs.status.SetText(fmt.Sprintf("switching %s failed: %v", action, err))
+		vs.status.Show()
+	} else {
+		vs.status.Hide()
+		if !state && vs.autoOff != nil {
+			// Switched off by hand, nothing left to time
+			vs.autoOff.autoOff.Cancel(vs.section)
+			vs.autoOff.update()
+		}
+	}
+	vs.requestRefresh()
+}
 
+// setKnown enables controls switching output or changing its setpoints, only if state of output is known.
+// Power cycle in progress can still be stopped.
+func (vs *viewSection) setKnown(known bool) {
+	if known {
+		vs.enable.Enable()
+	} else {
+		vs.enable.Disable()
+	}
+	if vs.autoOff != nil {
+		vs.autoOff.setKnown(known)
+	}
+	if vs.cycle != nil {
+		vs.cycle.setKnown(known)
+	}
+	if vs.setpoints != nil {
+		if known {
+			vs.setpoints.button.Enable()
+		} else {
+			vs.setpoints.button.Disable()
+		}
+	}
+}
+
+// undeliverable returns max current, if current set-point exceeds power envelope at voltage set-point
+func (vs *viewSection) undeliverable(data *Section) (float64, bool) {
+	values, err := data.Values()
+	if err != nil {
+		return 0, false
+	}
+	maxCurrent := vs.model.MaxCurrentAt(values.SetVoltage)
+	return maxCurrent, values.SetCurrent > maxCurrent
+}
+
+func (vs *viewSection) setMode(mode Mode) {
+	vs.mode.SetText(mode.String())
+	switch mode {
+	case ModeCC:
+		vs.modeBackground.FillColor = theme.WarningColor()
+	case ModeUnregulated:
+		vs.modeBackground.FillColor = theme.ErrorColor()
+	default:
+		vs.modeBackground.FillColor = color.Transparent
+	}
+	vs.modeBackground.Refresh()
 }
 
 func (v *View) verify() error {
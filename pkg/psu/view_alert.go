@@ -0,0 +1,197 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Alert is Event worth telling user about, shown in banner and sent as desktop notification
+type Alert struct {
+	Time  time.Time
+	Title string
+	Text  string
+	Event Event
+}
+
+// viewAlerts is banner with the latest alert and button showing history
+type viewAlerts struct {
+	history    int
+	mtx        sync.Mutex
+	alerts     []Alert
+	text       *widget.Label
+	background *canvas.Rectangle
+	dismiss    *widget.Button
+	show       *widget.Button
+}
+
+var (
+	ErrInvalidHistory = errors.New("history must be positive")
+)
+
+// Alerts returns the latest alerts, newest first
+func (v *View) Alerts() []Alert {
+	if v.alerts == nil {
+		return nil
+	}
+	v.alerts.mtx.Lock()
+	defer v.alerts.mtx.Unlock()
+	return append([]Alert{}, v.alerts.alerts...)
+}
+
+// Access returns Access used by View. Outputs switched through it, e.g. by rules, aren't alerts.
+func (v *View) Access() Access {
+	return v.psu
+}
+
+// watchAlerts puts Watcher in front of access, so every component of View is watched
+func (v *View) watchAlerts() error {
+	w, err := NewWatcher(WatcherWithAccess(v.psu), WatcherWithHandler(v.alert))
+	if err != nil {
+		return err
+	}
+	v.psu = w
+	v.alerts = newViewAlerts(v.alertHistory)
+	return nil
+}
+
+// alert is called by Watcher and Limiter
+func (v *View) alert(e Event) {
+	a, ok := alertOf(e)
+	if !ok {
+		return
+	}
+	// Notification is the only way to reach user, while window is in background.
+	// It is sent first, so whoever sees alert in history can be sure it was sent.
+	if app := fyne.CurrentApp(); app != nil {
+		app.SendNotification(fyne.NewNotification(a.Title, a.Text))
+	}
+	v.alerts.add(a)
+}
+
+// alertOf describes event, output switched by this View isn't an alert
+func alertOf(e Event) (Alert, bool) {
+	a := Alert{Time: e.Time, Event: e}
+	switch e.Kind {
+	case EventSwitched:
+		if e.Local {
+			return Alert{}, false
+		}
+		state := "off"
+		if e.State {
+			state = "on"
+		}
+		a.Title = fmt.Sprintf("Output %d switched %s", e.Section, state)
+		a.Text = "Switched outside of this window, e.g. on front panel or by another client"
+	case EventTripped:
+		a.Title = fmt.Sprintf("Output %d tripped", e.Section)
+		a.Text = e.Trip.String() + " protection tripped"
+	case EventLimit:
+		a.Title = fmt.Sprintf("Output %d limit exceeded", e.Section)
+		a.Text = e.Reason
+		if e.Err != nil {
+			a.Text += ", output NOT switched off: " + e.Err.Error()
+		}
+	case EventConnectionLost:
+		a.Title = "Connection lost"
+		a.Text = "PSU doesn't answer"
+		if e.Err != nil {
+			a.Text = e.Err.Error()
+		}
+	case EventConnectionRestored:
+		a.Title = "Connection restored"
+		a.Text = "PSU answers again"
+	default:
+		return Alert{}, false
+	}
+	return a, true
+}
+
+func newViewAlerts(history int) *viewAlerts {
+	va := &viewAlerts{
+		history:    history,
+		text:       widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		background: canvas.NewRectangle(color.Transparent),
+		dismiss:    widget.NewButtonWithIcon("", theme.ContentClearIcon(), nil),
+		show:       widget.NewButtonWithIcon("", theme.ListIcon(), nil),
+	}
+	va.dismiss.OnTapped = va.reset
+	va.show.OnTapped = va.showHistory
+	va.reset()
+	return va
+}
+
+func (va *viewAlerts) add(a Alert) {
+	va.mtx.Lock()
+	va.alerts = append([]Alert{a}, va.alerts...)
+	if len(va.alerts) > va.history {
+		va.alerts = va.alerts[:va.history]
+	}
+	va.mtx.Unlock()
+
+	va.text.SetText(a.Time.Format("15:04:05") + " " + a.Title + ": " + a.Text)
+	va.background.FillColor = theme.ErrorColor()
+	if a.Event.Kind == EventConnectionRestored {
+		va.background.FillColor = theme.SuccessColor()
+	}
+	va.background.Refresh()
+	va.dismiss.Enable()
+}
+
+// reset hides the latest alert, history is kept
+func (va *viewAlerts) reset() {
+	va.text.SetText("no alerts")
+	va.background.FillColor = color.Transparent
+	va.background.Refresh()
+	va.dismiss.Disable()
+}
+
+// showHistory shows alerts in pop-up over window of button
+func (va *viewAlerts) showHistory() {
+	app := fyne.CurrentApp()
+	if app == nil {
+		return
+	}
+	c := app.Driver().CanvasForObject(va.show)
+	if c == nil {
+		return
+	}
+	va.mtx.Lock()
+	lines := container.NewVBox()
+	for _, a := range va.alerts {
+		lines.Add(widget.NewLabel(a.Time.Format("2006-01-02 15:04:05") + " " + a.Title + ": " + a.Text))
+	}
+	va.mtx.Unlock()
+	if len(lines.Objects) == 0 {
+		lines.Add(widget.NewLabel("no alerts"))
+	}
+
+	var popUp *widget.PopUp
+	closeButton := widget.NewButtonWithIcon("", theme.CancelIcon(), func() {
+		popUp.Hide()
+	})
+	title := container.NewHBox(widget.NewLabelWithStyle("Alerts", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}), layout.NewSpacer(), closeButton)
+	scroll := container.NewVScroll(lines)
+	scroll.SetMinSize(fyne.NewSize(c.Size().Width*0.9, c.Size().Height*0.7))
+	popUp = widget.NewModalPopUp(container.NewBorder(title, nil, nil, nil, scroll), c)
+	popUp.Show()
+}
+
+func (va *viewAlerts) controls() fyne.CanvasObject {
+	buttons := container.NewHBox(va.dismiss, va.show)
+	return container.NewBorder(nil, nil, nil, buttons, container.NewMax(va.background, va.text))
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// viewAutoOff shows countdown of AutoOff of single section, next to ON/OFF button
+type viewAutoOff struct {
+	section   int
+	autoOff   *AutoOff
+	step      time.Duration
+	countdown *widget.Label
+	start     *widget.Button
+	extend    *widget.Button
+	cancel    *widget.Button
+	// known is false while state of output is unknown, e.g. connection is lost.
+	// It is set by refresh and read by countdown.
+	knownMtx sync.Mutex
+	known    bool
+}
+
+// AutoOff returns supervisor of timed outputs, nil if it isn't enabled
+func (v *View) AutoOff() *AutoOff {
+	return v.autoOff
+}
+
+// countdown updates countdowns every second, until View is closed
+func (v *View) countdown() {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-v.close:
+			return
+		case <-ticker.C:
+			for _, section := range v.sections {
+				section.autoOff.update()
+			}
+		}
+	}
+}
+
+// switchedOff is called by AutoOff, so section is shown off without waiting for refresh
+func (v *View) switchedOff(section int, err error) {
+	for _, s := range v.sections {
+		if s.section == section {
+			s.autoOff.update()
+		}
+	}
+	if err == nil {
+		v.requestRefresh()
+	}
+}
+
+func newViewAutoOff(section int, autoOff *AutoOff, step time.Duration) *viewAutoOff {
+	va := &viewAutoOff{
+		section:   section,
+		autoOff:   autoOff,
+		step:      step,
+		countdown: widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{Monospace: true}),
+		start:     widget.NewButtonWithIcon("", theme.HistoryIcon(), nil),
+		extend:    widget.NewButtonWithIcon("", theme.ContentAddIcon(), nil),
+		cancel:    widget.NewButtonWithIcon("", theme.CancelIcon(), nil),
+	}
+	va.start.OnTapped = func() {
+		if err := va.autoOff.Start(va.section, va.step); err != nil {
+			log.Error("error on timed switching on of section ", va.section, ": ", err)
+		}
+		va.update()
+	}
+	va.extend.OnTapped = func() {
+		if err := va.autoOff.Extend(va.section, va.step); err != nil {
+			log.Error("error on extending auto-off of section ", va.section, ": ", err)
+		}
+		va.update()
+	}
+	va.cancel.OnTapped = func() {
+		va.autoOff.Cancel(va.section)
+		va.update()
+	}
+	va.update()
+	return va
+}
+
+func (va *viewAutoOff) update() {
+	remaining, armed := va.autoOff.Remaining(va.section)
+	if !armed {
+		va.countdown.SetText("")
+		if va.isKnown() {
+			va.start.Enable()
+		} else {
+			va.start.Disable()
+		}
+		va.extend.Disable()
+		va.cancel.Disable()
+		return
+	}
+	va.countdown.SetText(formatCountdown(remaining))
+	va.start.Disable()
+	va.extend.Enable()
+	va.cancel.Enable()
+}
+
+func (va *viewAutoOff) setKnown(known bool) {
+	va.knownMtx.Lock()
+	va.known = known
+	va.knownMtx.Unlock()
+	va.update()
+}
+
+func (va *viewAutoOff) isKnown() bool {
+	va.knownMtx.Lock()
+	defer va.knownMtx.Unlock()
+	return va.known
+}
+
+func (va *viewAutoOff) controls(enable *widget.Button) fyne.CanvasObject {
+	return container.NewBorder(nil, nil, nil, container.NewHBox(va.countdown, va.start, va.extend, va.cancel), enable)
+}
+
+// formatCountdown returns h:mm:ss, or mm:ss below hour
+func formatCountdown(d time.Duration) string {
+	// Round up, so 0:00 is shown only when time is up
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
@@ -0,0 +1,261 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// chartWindows are time windows to choose from, the longest one is kept in History
+var chartWindows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour}
+
+const (
+	// chartMaxZoom is how many times visible part of window can be shortened
+	chartMaxZoom = 16
+	// Size of exported charts
+	chartExportWidth, chartExportHeight = 1000, 600
+)
+
+// viewChart plots readings of single section, taken by refresh
+type viewChart struct {
+	section int
+	history *History
+	dir     string
+
+	mtx    sync.Mutex
+	window time.Duration
+	zoom   int
+	// pausedAt is end of visible window, while chart is paused
+	pausedAt *time.Time
+
+	raster       *canvas.Raster
+	windowSelect *widget.Select
+	pause        *widget.Button
+	zoomIn       *widget.Button
+	zoomOut      *widget.Button
+	export       *widget.Button
+	status       *widget.Label
+}
+
+var (
+	ErrNoChart = errors.New("charts are disabled")
+)
+
+// Chart returns currently visible chart of section
+func (v *View) Chart(section int) (Chart, error) {
+	for _, s := range v.sections {
+		if s.section == section {
+			if s.chart == nil {
+				return Chart{}, ErrNoChart
+			}
+			return s.chart.chart(), nil
+		}
+	}
+	return Chart{}, ErrNoSection
+}
+
+func newViewChart(section int, window time.Duration, dir string) *viewChart {
+	windows := append([]time.Duration{}, chartWindows...)
+	known := false
+	for _, w := range windows {
+		known = known || w == window
+	}
+	if !known {
+		windows = append(windows, window)
+		sort.Slice(windows, func(i, j int) bool { return windows[i] < windows[j] })
+	}
+	vc := &viewChart{
+		section: section,
+		history: NewHistory(windows[len(windows)-1]),
+		dir:     dir,
+		window:  window,
+		zoom:    1,
+		pause:   widget.NewButtonWithIcon("", theme.MediaPauseIcon(), nil),
+		zoomIn:  widget.NewButtonWithIcon("", theme.ZoomInIcon(), nil),
+		zoomOut: widget.NewButtonWithIcon("", theme.ZoomOutIcon(), nil),
+		export:  widget.NewButtonWithIcon("", theme.DocumentSaveIcon(), nil),
+		status:  widget.NewLabel(""),
+	}
+	vc.raster = canvas.NewRaster(func(w, h int) image.Image {
+		return vc.chart().Image(w, h)
+	})
+	vc.raster.SetMinSize(fyne.NewSize(300, 200))
+
+	options := make([]string, len(windows))
+	for i, w := range windows {
+		options[i] = formatWindow(w)
+	}
+	vc.windowSelect = widget.NewSelect(options, func(s string) {
+		if w, err := time.ParseDuration(s); err == nil {
+			vc.setWindow(w)
+		}
+	})
+	vc.windowSelect.SetSelected(formatWindow(window))
+	vc.pause.OnTapped = vc.togglePause
+	vc.zoomIn.OnTapped = func() {
+		vc.setZoom(2)
+	}
+	vc.zoomOut.OnTapped = func() {
+		vc.setZoom(0.5)
+	}
+	vc.export.OnTapped = func() {
+		paths, err := vc.save()
+		if err != nil {
+			log.Error("chart of section ", section, " not exported: ", err)
+			vc.status.SetText("export failed")
+			return
+		}
+		vc.status.SetText(filepath.Base(paths[0]) + " saved")
+	}
+	vc.updateZoom()
+	return vc
+}
+
+func (vc *viewChart) add(s *Section) {
+	if err := vc.history.Add(time.Now(), s); err != nil {
+		log.Error("error on adding readings to chart: ", err)
+	}
+	vc.redraw()
+}
+
+func (vc *viewChart) lost() {
+	vc.history.Lost(time.Now())
+	vc.redraw()
+}
+
+// redraw shows new readings, unless chart is paused
+func (vc *viewChart) redraw() {
+	vc.mtx.Lock()
+	paused := vc.pausedAt != nil
+	vc.mtx.Unlock()
+	if !paused {
+		vc.raster.Refresh()
+	}
+}
+
+// chart returns visible part of window, ending now or when chart was paused
+func (vc *viewChart) chart() Chart {
+	vc.mtx.Lock()
+	to := time.Now()
+	if vc.pausedAt != nil {
+		to = *vc.pausedAt
+	}
+	from := to.Add(-vc.window / time.Duration(vc.zoom))
+	vc.mtx.Unlock()
+	return Chart{
+		Title:   fmt.Sprintf("Output %d", vc.section),
+		From:    from,
+		To:      to,
+		Samples: vc.history.Samples(from, to),
+	}
+}
+
+func (vc *viewChart) setWindow(w time.Duration) {
+	vc.mtx.Lock()
+	vc.window = w
+	vc.mtx.Unlock()
+	vc.raster.Refresh()
+}
+
+// setZoom multiplies zoom by factor, within 1 and chartMaxZoom
+func (vc *viewChart) setZoom(factor float64) {
+	vc.mtx.Lock()
+	zoom := int(float64(vc.zoom) * factor)
+	if zoom >= 1 && zoom <= chartMaxZoom {
+		vc.zoom = zoom
+	}
+	vc.mtx.Unlock()
+	vc.updateZoom()
+	vc.raster.Refresh()
+}
+
+func (vc *viewChart) updateZoom() {
+	vc.mtx.Lock()
+	zoom := vc.zoom
+	vc.mtx.Unlock()
+	if zoom < chartMaxZoom {
+		vc.zoomIn.Enable()
+	} else {
+		vc.zoomIn.Disable()
+	}
+	if zoom > 1 {
+		vc.zoomOut.Enable()
+	} else {
+		vc.zoomOut.Disable()
+	}
+}
+
+// togglePause freezes visible window, readings are still kept
+func (vc *viewChart) togglePause() {
+	vc.mtx.Lock()
+	if vc.pausedAt == nil {
+		now := time.Now()
+		vc.pausedAt = &now
+		vc.pause.SetIcon(theme.MediaPlayIcon())
+	} else {
+		vc.pausedAt = nil
+		vc.pause.SetIcon(theme.MediaPauseIcon())
+	}
+	vc.mtx.Unlock()
+	vc.raster.Refresh()
+}
+
+// save writes visible chart to dir as PNG and SVG, paths are returned
+func (vc *viewChart) save() ([]string, error) {
+	if err := os.MkdirAll(vc.dir, 0o755); err != nil {
+		return nil, err
+	}
+	c := vc.chart()
+	name := fmt.Sprintf("chart-%d-%s", vc.section, c.To.Format("20060102-150405"))
+	var paths []string
+	for _, format := range []ChartFormat{ChartPNG, ChartSVG} {
+		path := filepath.Join(vc.dir, name+format.Extension())
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Write(file, format, chartExportWidth, chartExportHeight); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		if err := file.Close(); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// formatWindow drops zero minutes and seconds, e.g. 1h instead of 1h0m0s
+func formatWindow(w time.Duration) string {
+	text := w.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
+
+func (vc *viewChart) controls() fyne.CanvasObject {
+	buttons := container.NewHBox(vc.windowSelect, vc.pause, vc.zoomOut, vc.zoomIn, vc.export)
+	return container.NewBorder(container.NewBorder(nil, nil, buttons, nil, vc.status), nil, nil, nil, vc.raster)
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"image/color"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ConnectionState is state of connection with PSU, as seen by View
+type ConnectionState int
+
+const (
+	// ConnectionUnknown is state before first reading
+	ConnectionUnknown ConnectionState = iota
+	ConnectionConnected
+	// ConnectionReconnecting means readings fail and View retries with growing backoff
+	ConnectionReconnecting
+	// ConnectionOffline means readings still fail after backoff reached its max, View retries every max backoff
+	ConnectionOffline
+)
+
+const (
+	defaultReconnectMin = time.Second
+	defaultReconnectMax = 30 * time.Second
+)
+
+// viewConnection is status badge of View, it schedules reconnect after failed refresh
+type viewConnection struct {
+	min, max time.Duration
+
+	mtx         sync.Mutex
+	state       ConnectionState
+	lastSuccess time.Time
+	backoff     time.Duration
+	retry       *time.Timer
+
+	text       *widget.Label
+	background *canvas.Rectangle
+}
+
+func (c ConnectionState) String() string {
+	switch c {
+	case ConnectionConnected:
+		return "connected"
+	case ConnectionReconnecting:
+		return "reconnecting"
+	case ConnectionOffline:
+		return "offline"
+	default:
+		return "connecting"
+	}
+}
+
+// Connection returns state of connection and time of last successful refresh, zero if there was none
+func (v *View) Connection() (ConnectionState, time.Time) {
+	v.connection.mtx.Lock()
+	defer v.connection.mtx.Unlock()
+	return v.connection.state, v.connection.lastSuccess
+}
+
+func newViewConnection(min, max time.Duration) *viewConnection {
+	vc := &viewConnection{
+		min:        min,
+		max:        max,
+		backoff:    min,
+		text:       widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		background: canvas.NewRectangle(color.Transparent),
+	}
+	vc.update()
+	return vc
+}
+
+// connected is called after refresh, which read at least one section
+func (vc *viewConnection) connected(now time.Time) {
+	vc.mtx.Lock()
+	vc.state = ConnectionConnected
+	vc.lastSuccess = now
+	vc.backoff = vc.min
+	if vc.retry != nil {
+		vc.retry.Stop()
+		vc.retry = nil
+	}
+	vc.mtx.Unlock()
+	vc.update()
+}
+
+// failed is called after refresh, which didn't read any section. It schedules retry by reconnect.
+func (vc *viewConnection) failed(reconnect func()) {
+	vc.mtx.Lock()
+	delay := vc.backoff
+	if delay >= vc.max {
+		vc.state = ConnectionOffline
+	} else {
+		vc.state = ConnectionReconnecting
+	}
+	vc.backoff *= 2
+	if vc.backoff > vc.max {
+		vc.backoff = vc.max
+	}
+	if vc.retry != nil {
+		vc.retry.Stop()
+	}
+	vc.retry = time.AfterFunc(delay, reconnect)
+	vc.mtx.Unlock()
+	vc.update()
+}
+
+// reconnecting returns true, while retry of failed refresh is scheduled
+func (vc *viewConnection) reconnecting() bool {
+	vc.mtx.Lock()
+	defer vc.mtx.Unlock()
+	return vc.retry != nil
+}
+
+func (vc *viewConnection) stop() {
+	vc.mtx.Lock()
+	defer vc.mtx.Unlock()
+	if vc.retry != nil {
+		vc.retry.Stop()
+		vc.retry = nil
+	}
+}
+
+func (vc *viewConnection) update() {
+	vc.mtx.Lock()
+	state, lastSuccess := vc.state, vc.lastSuccess
+	vc.mtx.Unlock()
+
+	text := state.String()
+	if !lastSuccess.IsZero() {
+		text += ", last " + lastSuccess.Format("15:04:05")
+	}
+	vc.text.SetText(text)
+	switch state {
+	case ConnectionConnected:
+		vc.background.FillColor = theme.SuccessColor()
+	case ConnectionReconnecting:
+		vc.background.FillColor = theme.WarningColor()
+	case ConnectionOffline:
+		vc.background.FillColor = theme.ErrorColor()
+	default:
+		vc.background.FillColor = color.Transparent
+	}
+	vc.background.Refresh()
+}
+
+func (vc *viewConnection) controls() fyne.CanvasObject {
+	return container.NewMax(vc.background, vc.text)
+}
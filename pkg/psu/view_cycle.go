@@ -0,0 +1,277 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const cycleText = "Cycle"
+
+var (
+	ErrNoPowerCycle = errors.New("power cycle not enabled")
+	ErrUnknownState = errors.New("state of output is unknown")
+	ErrCycleFailed  = errors.New("power cycle failed")
+)
+
+// viewCycle starts and stops PowerCycle of single section, button shows progress
+type viewCycle struct {
+	section int
+	access  Access
+	opts    []CycleOption
+	button  *widget.Button
+
+	mtx    sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	// known is false while state of output is unknown, cycle can be stopped but not started then
+	known bool
+	// err is error of the last power cycle, which wasn't stopped by user
+	err error
+	// background is done, when power cycle in background has reported its result
+	background sync.WaitGroup
+}
+
+// StartPowerCycle runs PowerCycle of section in background, as if button was tapped and confirmed
+func (v *View) StartPowerCycle(section int) error {
+	vc, err := v.viewCycle(section)
+	if err != nil {
+		return err
+	}
+	return vc.start()
+}
+
+func (v *View) StopPowerCycle(section int) {
+	if vc, err := v.viewCycle(section); err == nil {
+		vc.stop()
+	}
+}
+
+// PowerCycleError returns error of the last PowerCycle of section, which wasn't stopped by user.
+// Failed cycles are reported as ErrCycleFailed.
+func (v *View) PowerCycleError(section int) error {
+	vc, err := v.viewCycle(section)
+	if err != nil {
+		return err
+	}
+	vc.mtx.Lock()
+	defer vc.mtx.Unlock()
+	return vc.err
+}
+
+// PowerCycling returns true, if PowerCycle of section is started by View
+func (v *View) PowerCycling(section int) bool {
+	vc, err := v.viewCycle(section)
+	return err == nil && vc.running()
+}
+
+func (v *View) viewCycle(section int) (*viewCycle, error) {
+	if v.cycleOpts == nil {
+		return nil, ErrNoPowerCycle
+	}
+	for _, s := range v.sections {
+		if s.section == section {
+			return s.cycle, nil
+		}
+	}
+	return nil, ErrNoSection
+}
+
+func newViewCycle(section int, access Access, opts []CycleOption) *viewCycle {
+	vc := &viewCycle{
+		section: section,
+		access:  access,
+		opts:    opts,
+		button:  widget.NewButtonWithIcon(cycleText, theme.ViewRefreshIcon(), nil),
+	}
+	// Enabled by first reading of section
+	vc.button.Disable()
+	vc.button.OnTapped = func() {
+		if vc.running() {
+			vc.stop()
+			return
+		}
+		vc.confirm()
+	}
+	return vc
+}
+
+// confirm starts power cycle, once user confirms it, as whatever is connected loses power
+func (vc *viewCycle) confirm() {
+	c, err := NewPowerCycle(append([]CycleOption{CycleWithAccess(vc.access), CycleWithSections(vc.section)}, vc.opts...)...)
+	if err != nil {
+		log.Error("error on starting power cycle of section ", vc.section, ": ", err)
+		return
+	}
+	text := fmt.Sprintf("Output %d will be switched off and on %d times.\nWhatever is connected to it loses power.", vc.section, c.Cycles())
+	vc.show("Power cycle?", text, func(hide func()) []fyne.CanvasObject {
+		start := widget.NewButtonWithIcon("Start", theme.ConfirmIcon(), func() {
+			hide()
+			if err := vc.start(); err != nil {
+				log.Error("error on starting power cycle of section ", vc.section, ": ", err)
+				vc.show("Power cycle not started", err.Error(), nil)
+			}
+		})
+		start.Importance = widget.HighImportance
+		return []fyne.CanvasObject{widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), hide), start}
+	})
+}
+
+// show opens dialog over window of button, buttons returns buttons next to Close, if it isn't nil
+func (vc *viewCycle) show(title, text string, buttons func(hide func()) []fyne.CanvasObject) {
+	app := fyne.CurrentApp()
+	if app == nil {
+		return
+	}
+	c := app.Driver().CanvasForObject(vc.button)
+	if c == nil {
+		return
+	}
+	holder := container.NewMax()
+	popUp := widget.NewModalPopUp(holder, c)
+	row := container.NewHBox(layout.NewSpacer())
+	if buttons != nil {
+		for _, b := range buttons(popUp.Hide) {
+			row.Add(b)
+		}
+	} else {
+		row.Add(widget.NewButtonWithIcon("Close", theme.CancelIcon(), popUp.Hide))
+	}
+	label := widget.NewLabel(text)
+	label.Wrapping = fyne.TextWrapWord
+	holder.Objects = []fyne.CanvasObject{container.NewVBox(
+		widget.NewLabelWithStyle(title, fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		label,
+		row,
+	)}
+	popUp.Resize(fyne.NewSize(300, popUp.MinSize().Height))
+	popUp.Show()
+}
+
+func (vc *viewCycle) start() error {
+	vc.mtx.Lock()
+	defer vc.mtx.Unlock()
+	if vc.ctx != nil {
+		return ErrRunning
+	}
+	if !vc.known {
+		return ErrUnknownState
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	failures := 0
+	var lastFailure error
+	var c *PowerCycle
+	opts := append([]CycleOption{
+		CycleWithAccess(vc.access),
+		CycleWithSections(vc.section),
+		CycleWithHandler(func(result CycleResult) {
+			if result.Err != nil {
+				failures++
+				lastFailure = result.Err
+			}
+			if ctx.Err() != nil {
+				// Button is already reset
+				return
+			}
+			vc.button.SetText(fmt.Sprintf("%d/%d, %d failed", result.Cycle, c.Cycles(), failures))
+		}),
+	}, vc.opts...)
+	c, err := NewPowerCycle(opts...)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	vc.ctx, vc.cancel = ctx, cancel
+	vc.err = nil
+	vc.button.SetIcon(theme.MediaStopIcon())
+	vc.updateButton()
+
+	vc.background.Add(1)
+	go func() {
+		defer vc.background.Done()
+		summary, err := c.Run(ctx)
+		if err != nil {
+			log.Error("power cycle of section ", vc.section, " stopped: ", err)
+		}
+		log.Debug("power cycle of section ", vc.section, ": ", summary.Cycles, " cycles, ", summary.Failures, " failures")
+		stopped := errors.Is(err, context.Canceled)
+		if err == nil && summary.Failures > 0 {
+			err = fmt.Errorf("%w: %d failures in %d cycles, last: %v", ErrCycleFailed, summary.Failures, summary.Cycles, lastFailure)
+		} else if err != nil && !stopped {
+			err = fmt.Errorf("stopped after %d of %d cycles: %w", summary.Cycles, c.Cycles(), err)
+		}
+		vc.mtx.Lock()
+		// Power cycle might have been restarted in the meantime
+		current := vc.ctx == ctx
+		if current {
+			vc.reset()
+			if !stopped {
+				vc.err = err
+			}
+		}
+		vc.mtx.Unlock()
+		cancel()
+		if current && !stopped && err != nil {
+			vc.show(fmt.Sprintf("Output %d power cycle failed", vc.section), err.Error(), nil)
+		}
+	}()
+	return nil
+}
+
+func (vc *viewCycle) stop() {
+	vc.mtx.Lock()
+	defer vc.mtx.Unlock()
+	if vc.ctx == nil {
+		return
+	}
+	vc.cancel()
+	vc.reset()
+}
+
+// wait for power cycle in background to finish
+func (vc *viewCycle) wait() {
+	vc.background.Wait()
+}
+
+func (vc *viewCycle) reset() {
+	vc.ctx, vc.cancel = nil, nil
+	vc.button.SetIcon(theme.ViewRefreshIcon())
+	vc.button.SetText(cycleText)
+	vc.updateButton()
+}
+
+func (vc *viewCycle) setKnown(known bool) {
+	vc.mtx.Lock()
+	defer vc.mtx.Unlock()
+	vc.known = known
+	vc.updateButton()
+}
+
+// updateButton must be called with mtx locked
+func (vc *viewCycle) updateButton() {
+	if vc.known || vc.ctx != nil {
+		vc.button.Enable()
+	} else {
+		vc.button.Disable()
+	}
+}
+
+func (vc *viewCycle) running() bool {
+	vc.mtx.Lock()
+	defer vc.mtx.Unlock()
+	return vc.ctx != nil
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// viewEnergy shows accumulated energy of single section
+type viewEnergy struct {
+	accumulator *Accumulator
+	power       *widget.Label
+	energy      *widget.Label
+	startStop   *widget.Button
+	reset       *widget.Button
+}
+
+func newViewEnergy(maxGap time.Duration) *viewEnergy {
+	ve := &viewEnergy{
+		accumulator: NewAccumulator(maxGap),
+		power:       widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{}),
+		energy:      widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{}),
+		startStop:   widget.NewButtonWithIcon("", theme.MediaPauseIcon(), nil),
+		reset:       widget.NewButtonWithIcon("", theme.ContentClearIcon(), nil),
+	}
+	ve.startStop.OnTapped = func() {
+		if ve.accumulator.Running() {
+			ve.accumulator.Stop()
+		} else {
+			ve.accumulator.Start()
+		}
+		ve.update()
+	}
+	ve.reset.OnTapped = func() {
+		ve.accumulator.Reset()
+		ve.update()
+	}
+	ve.update()
+	return ve
+}
+
+func (ve *viewEnergy) add(s *Section) {
+	if err := ve.accumulator.Add(time.Now(), s); err != nil {
+		log.Error("error on accumulating energy: ", err)
+	}
+	ve.update()
+}
+
+func (ve *viewEnergy) lost() {
+	ve.accumulator.Lost()
+	ve.update()
+}
+
+func (ve *viewEnergy) update() {
+	e := ve.accumulator.Energy()
+	ve.power.SetText(fmt.Sprintf("%.2f W", e.Power))
+	ve.energy.SetText(fmt.Sprintf("%.3f Wh / %.3f Ah", e.Energy, e.Charge))
+	if ve.accumulator.Running() {
+		ve.startStop.SetIcon(theme.MediaPauseIcon())
+	} else {
+		ve.startStop.SetIcon(theme.MediaPlayIcon())
+	}
+}
+
+func (ve *viewEnergy) controls() fyne.CanvasObject {
+	return container.NewBorder(nil, nil, nil, container.NewHBox(ve.startStop, ve.reset), ve.power)
+}
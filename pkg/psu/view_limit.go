@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"context"
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// viewLimit shows the last LimitEvent of single section, until it is cleared
+type viewLimit struct {
+	limit      *Limit
+	event      *widget.Label
+	background *canvas.Rectangle
+	clear      *widget.Button
+}
+
+// Limiter returns supervisor of software limits, nil if no limits are set
+func (v *View) Limiter() *Limiter {
+	return v.limiter
+}
+
+func (v *View) startLimiter() error {
+	l, err := NewLimiter(
+		LimiterWithAccess(v.psu),
+		LimiterWithLimits(v.limits...),
+		LimiterWithHandler(v.limitExceeded),
+	)
+	if err != nil {
+		return err
+	}
+	v.limiter = l
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-v.close
+		cancel()
+	}()
+	go func() {
+		_ = l.Run(ctx)
+	}()
+	return nil
+}
+
+// limitExceeded is called by Limiter, so event is shown at once
+func (v *View) limitExceeded(e LimitEvent) {
+	for _, handler := range v.eventHandlers {
+		handler(e.Event())
+	}
+	if v.alerts != nil {
+		v.alert(e.Event())
+	}
+	for _, s := range v.sections {
+		if s.section == e.Section {
+			s.limit.show(e)
+		}
+	}
+	// Output is shown off without waiting for next refresh
+	v.requestRefresh()
+}
+
+func newViewLimit(section int, limits []Limit) *viewLimit {
+	vl := &viewLimit{
+		event:      widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		background: canvas.NewRectangle(color.Transparent),
+		clear:      widget.NewButtonWithIcon("", theme.ContentClearIcon(), nil),
+	}
+	for i := range limits {
+		if limits[i].Section == section {
+			vl.limit = &limits[i]
+		}
+	}
+	vl.clear.OnTapped = vl.reset
+	vl.reset()
+	return vl
+}
+
+func (vl *viewLimit) show(e LimitEvent) {
+	text := "LIMIT " + e.Time.Format("15:04:05") + ": " + e.Reason
+	if e.Err != nil {
+		text += ", NOT SWITCHED OFF"
+	}
+	vl.event.SetText(text)
+	vl.background.FillColor = theme.ErrorColor()
+	vl.background.Refresh()
+	vl.clear.Enable()
+}
+
+// reset clears event, section without limit shows it has none
+func (vl *viewLimit) reset() {
+	text := "no limit"
+	if vl.limit != nil {
+		text = "limit OK"
+	}
+	vl.event.SetText(text)
+	vl.background.FillColor = color.Transparent
+	vl.background.Refresh()
+	vl.clear.Disable()
+}
+
+func (vl *viewLimit) controls() fyne.CanvasObject {
+	return container.NewBorder(nil, nil, nil, vl.clear, container.NewMax(vl.background, vl.event))
+}
@@ -5,10 +5,17 @@
 
 package psu
 
+import (
+	"time"
+)
+
 type ViewOption func(*View) error
 
 func ViewWithPSU(p *PSU) ViewOption {
 	return func(view *View) error {
+		if p != nil {
+			view.model = p.Model()
+		}
 		return ViewWithAccess(p)(view)
 	}
 }
@@ -26,3 +33,115 @@ func ViewWithSections(sections ...int) ViewOption {
 		return nil
 	}
 }
+
+// ViewWithModel sets limits used to warn about set-points, which can't be delivered
+func ViewWithModel(m Model) ViewOption {
+	return func(view *View) error {
+		view.model = m
+		return nil
+	}
+}
+
+// ViewWithEnergy enables power, energy and charge accounting of each section.
+// Readings more than maxGap apart are not integrated, so it should be a bit longer than refresh interval.
+func ViewWithEnergy(maxGap time.Duration) ViewOption {
+	return func(view *View) error {
+		view.energyGap = maxGap
+		return nil
+	}
+}
+
+// ViewWithDataLogger adds button to start and stop DataLogger
+func ViewWithDataLogger(d *DataLogger) ViewOption {
+	return func(view *View) error {
+		view.dataLogger = d
+		return nil
+	}
+}
+
+// ViewWithPowerCycle adds button to power cycle each section, access and section are set by View
+func ViewWithPowerCycle(opts ...CycleOption) ViewOption {
+	return func(view *View) error {
+		view.cycleOpts = append([]CycleOption{}, opts...)
+		return nil
+	}
+}
+
+// ViewWithAutoOff adds controls to switch output on for step, after which AutoOff switches it off.
+// Countdown can be extended by step or cancelled.
+func ViewWithAutoOff(step time.Duration) ViewOption {
+	return func(view *View) error {
+		if step < 0 {
+			return ErrInvalidPeriod
+		}
+		view.autoOffStep = step
+		return nil
+	}
+}
+
+// ViewWithLimits enforces software limits by Limiter, events are shown below readings of section
+func ViewWithLimits(limits ...Limit) ViewOption {
+	return func(view *View) error {
+		view.limits = append(view.limits, limits...)
+		return nil
+	}
+}
+
+// ViewWithEventHandler adds function notified about events of View, e.g. Watcher.Notify
+func ViewWithEventHandler(handler func(Event)) ViewOption {
+	return func(view *View) error {
+		view.eventHandlers = append(view.eventHandlers, handler)
+		return nil
+	}
+}
+
+// ViewWithAlerts shows banner with the latest alert and sends it as desktop notification.
+// Alerts are connection loss and restore, trips, exceeded limits and outputs switched outside of View.
+// History of the latest alerts is shown by button.
+func ViewWithAlerts(history int) ViewOption {
+	return func(view *View) error {
+		if history <= 0 {
+			return ErrInvalidHistory
+		}
+		view.alertHistory = history
+		return nil
+	}
+}
+
+// ViewWithCharts plots voltage and current of each section against set-points, window is shown at start.
+// Readings are taken by refresh. Charts are exported to dir as PNG and SVG.
+func ViewWithCharts(window time.Duration, dir string) ViewOption {
+	return func(view *View) error {
+		if window <= 0 {
+			return ErrInvalidPeriod
+		}
+		view.chartWindow, view.chartDir = window, dir
+		return nil
+	}
+}
+
+// ViewWithSetpoints adds button to change setpoints of each section by setter, e.g. PSU.
+// Protection trip points can be changed too, if setter is Protector.
+// Setpoints are checked against model and envelopes, confirmed by user and verified by read back.
+func ViewWithSetpoints(setter Setter, envelopes ...Envelope) ViewOption {
+	return func(view *View) error {
+		if setter == nil {
+			return ErrNoSetter
+		}
+		view.setter = setter
+		view.envelopes = append(view.envelopes, envelopes...)
+		return nil
+	}
+}
+
+// ViewWithReconnect sets backoff of retries after failed refresh, it doubles from min up to max.
+// Connection is shown as offline, once backoff reaches max. Default is 1s up to 30s.
+func ViewWithReconnect(min, max time.Duration) ViewOption {
+	return func(view *View) error {
+		if min <= 0 || max < min {
+			return ErrInvalidPeriod
+		}
+		view.reconnectMin, view.reconnectMax = min, max
+		return nil
+	}
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"context"
+	"errors"
+
+	"fyne.io/fyne/v2/theme"
+)
+
+var (
+	ErrNoDataLogger = errors.New("no DataLogger")
+)
+
+// StartRecording runs DataLogger in background
+func (v *View) StartRecording() error {
+	if v.dataLogger == nil {
+		return ErrNoDataLogger
+	}
+	v.recordingMtx.Lock()
+	defer v.recordingMtx.Unlock()
+	if v.recording != nil {
+		return ErrRunning
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	v.recording, v.stopRecording = ctx, cancel
+	v.recordButton.SetIcon(theme.MediaStopIcon())
+
+	go func() {
+		if err := v.dataLogger.Run(ctx); err != nil {
+			log.Error("data logger stopped: ", err)
+		}
+		v.recordingMtx.Lock()
+		defer v.recordingMtx.Unlock()
+		// Recording might have been restarted in the meantime
+		if v.recording == ctx {
+			v.recording, v.stopRecording = nil, nil
+			v.recordButton.SetIcon(theme.MediaRecordIcon())
+		}
+		cancel()
+	}()
+	return nil
+}
+
+func (v *View) StopRecording() {
+	v.recordingMtx.Lock()
+	defer v.recordingMtx.Unlock()
+	if v.recording == nil {
+		return
+	}
+	v.stopRecording()
+	v.recording, v.stopRecording = nil, nil
+	v.recordButton.SetIcon(theme.MediaRecordIcon())
+}
+
+// Recording returns true, if DataLogger is started by View
+func (v *View) Recording() bool {
+	v.recordingMtx.Lock()
+	defer v.recordingMtx.Unlock()
+	return v.recording != nil
+}
+
+func (v *View) toggleRecording() {
+	if v.Recording() {
+		v.StopRecording()
+		return
+	}
+	if err := v.StartRecording(); err != nil {
+		log.Error("error on starting data logger: ", err)
+	}
+}
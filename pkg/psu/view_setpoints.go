@@ -0,0 +1,232 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+var (
+	ErrNoSetpoints = errors.New("setpoints can't be changed from View")
+)
+
+// viewSetpoints is button opening dialog, which changes setpoints of single section
+type viewSetpoints struct {
+	section int
+	view    *View
+	button  *widget.Button
+}
+
+// setpointsField is row of dialog
+type setpointsField struct {
+	name, unit string
+	format     string
+	value      func(*Setpoints) *float64
+}
+
+var setpointsFields = []setpointsField{
+	{name: "Voltage", unit: "V", format: "%.2f", value: func(s *Setpoints) *float64 { return &s.Voltage }},
+	{name: "Current", unit: "A", format: "%.3f", value: func(s *Setpoints) *float64 { return &s.Current }},
+	{name: "OVP", unit: "V", format: "%.2f", value: func(s *Setpoints) *float64 { return &s.OverVoltage }},
+	{name: "OCP", unit: "A", format: "%.3f", value: func(s *Setpoints) *float64 { return &s.OverCurrent }},
+}
+
+// Setpoints reads setpoints of section, protection is read only if Setter is Protector
+func (v *View) Setpoints(section int) (Setpoints, error) {
+	if err := v.setpointsSection(section); err != nil {
+		return Setpoints{}, err
+	}
+	// Cache may keep old set-points for a while, ask instrument if possible
+	access := v.psu
+	if a, ok := v.setter.(Access); ok {
+		access = a
+	}
+	return ReadSetpoints(access, v.setter, section)
+}
+
+// CheckSetpoints returns error, if setpoints of section are outside of model or user envelope
+func (v *View) CheckSetpoints(section int, s Setpoints) error {
+	if err := v.setpointsSection(section); err != nil {
+		return err
+	}
+	if err := s.Check(v.model); err != nil {
+		return err
+	}
+	for _, e := range v.envelopes {
+		if e.Section == section {
+			if err := e.Check(s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ApplySetpoints checks and writes setpoints of section, as confirmed in dialog.
+// Setpoints read back are returned.
+func (v *View) ApplySetpoints(section int, s Setpoints) (Setpoints, error) {
+	if err := v.CheckSetpoints(section, s); err != nil {
+		return Setpoints{}, err
+	}
+	v.setpointsMtx.Lock()
+	defer v.setpointsMtx.Unlock()
+	before, err := v.Setpoints(section)
+	if err != nil {
+		return Setpoints{}, err
+	}
+	written, err := WriteSetpoints(v.setter, section, before, s)
+	if err != nil {
+		log.Error("setpoints of section ", section, " not applied: ", err)
+	} else {
+		log.Debug("setpoints of section ", section, " applied: ", written)
+	}
+	v.requestRefresh()
+	return written, err
+}
+
+func (v *View) setpointsSection(section int) error {
+	if v.setter == nil {
+		return ErrNoSetpoints
+	}
+	for _, s := range v.sections {
+		if s.section == section {
+			return nil
+		}
+	}
+	return ErrNoSection
+}
+
+func newViewSetpoints(section int, v *View) *viewSetpoints {
+	vs := &viewSetpoints{
+		section: section,
+		view:    v,
+		button:  widget.NewButtonWithIcon("Set", theme.SettingsIcon(), nil),
+	}
+	vs.button.OnTapped = vs.show
+	// Enabled by first reading of section
+	vs.button.Disable()
+	return vs
+}
+
+// fields returns rows of dialog, protection is shown only if it can be changed
+func (vs *viewSetpoints) fields() []setpointsField {
+	if _, ok := vs.view.setter.(Protector); ok {
+		return setpointsFields
+	}
+	return setpointsFields[:2]
+}
+
+// show opens dialog over window of button: form, then before / after confirmation, then result
+func (vs *viewSetpoints) show() {
+	app := fyne.CurrentApp()
+	if app == nil {
+		return
+	}
+	c := app.Driver().CanvasForObject(vs.button)
+	if c == nil {
+		return
+	}
+	holder := container.NewMax()
+	popUp := widget.NewModalPopUp(holder, c)
+	setContent := func(o fyne.CanvasObject) {
+		holder.Objects = []fyne.CanvasObject{o}
+		holder.Refresh()
+		popUp.Resize(popUp.MinSize())
+	}
+	title := widget.NewLabelWithStyle(fmt.Sprintf("Output %d setpoints", vs.section), fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	closeButton := func(text string) *widget.Button {
+		return widget.NewButtonWithIcon(text, theme.CancelIcon(), popUp.Hide)
+	}
+
+	before, err := vs.view.Setpoints(vs.section)
+	if err != nil {
+		setContent(container.NewVBox(title, widget.NewLabel("Can't read setpoints: "+err.Error()), closeButton("Close")))
+		popUp.Show()
+		return
+	}
+
+	fields := vs.fields()
+	entries := make([]*widget.Entry, len(fields))
+	form := widget.NewForm()
+	for i, f := range fields {
+		entries[i] = widget.NewEntry()
+		entries[i].SetText(fmt.Sprintf(f.format, *f.value(&before)))
+		form.Append(f.name+" ["+f.unit+"]", entries[i])
+	}
+	status := widget.NewLabel("")
+	status.Wrapping = fyne.TextWrapWord
+
+	var showForm func()
+	review := func() {
+		after := Setpoints{}
+		for i, f := range fields {
+			value, err := strconv.ParseFloat(strings.TrimSpace(entries[i].Text), 64)
+			if err != nil {
+				status.SetText(f.name + " is not a number")
+				return
+			}
+			*f.value(&after) = value
+		}
+		if err := vs.view.CheckSetpoints(vs.section, after); err != nil {
+			status.SetText(err.Error())
+			return
+		}
+		apply := widget.NewButtonWithIcon("Apply", theme.ConfirmIcon(), nil)
+		apply.Importance = widget.HighImportance
+		apply.OnTapped = func() {
+			written, err := vs.view.ApplySetpoints(vs.section, after)
+			result := "Applied and verified by read back"
+			if err != nil {
+				result = "FAILED: " + err.Error()
+			}
+			resultLabel := widget.NewLabel(result)
+			resultLabel.Wrapping = fyne.TextWrapWord
+			setContent(container.NewVBox(title, compareSetpoints(fields, before, written, "Read back"), resultLabel, closeButton("Close")))
+		}
+		back := widget.NewButtonWithIcon("Back", theme.NavigateBackIcon(), showForm)
+		content := container.NewVBox(title, compareSetpoints(fields, before, after, "After"))
+		// PSU accepts such setpoints, but limits output
+		if err := vs.view.model.Deliverable(after.Voltage, after.Current); err != nil {
+			warning := widget.NewLabel("Warning: " + err.Error())
+			warning.Wrapping = fyne.TextWrapWord
+			content.Add(warning)
+		}
+		content.Add(container.NewHBox(layout.NewSpacer(), back, apply))
+		setContent(content)
+	}
+	showForm = func() {
+		next := widget.NewButtonWithIcon("Review", theme.NavigateNextIcon(), review)
+		setContent(container.NewVBox(title, form, status, container.NewHBox(layout.NewSpacer(), closeButton("Cancel"), next)))
+	}
+	showForm()
+	popUp.Show()
+}
+
+// compareSetpoints returns table of before and after values, changed values are bold
+func compareSetpoints(fields []setpointsField, before, after Setpoints, afterTitle string) fyne.CanvasObject {
+	grid := container.NewGridWithColumns(3,
+		widget.NewLabel(""),
+		widget.NewLabelWithStyle("Before", fyne.TextAlignTrailing, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle(afterTitle, fyne.TextAlignTrailing, fyne.TextStyle{Bold: true}),
+	)
+	for _, f := range fields {
+		old := fmt.Sprintf(f.format+" "+f.unit, *f.value(&before))
+		changed := fmt.Sprintf(f.format+" "+f.unit, *f.value(&after))
+		grid.Add(widget.NewLabel(f.name))
+		grid.Add(widget.NewLabelWithStyle(old, fyne.TextAlignTrailing, fyne.TextStyle{}))
+		grid.Add(widget.NewLabelWithStyle(changed, fyne.TextAlignTrailing, fyne.TextStyle{Bold: old != changed}))
+	}
+	return grid
+}
@@ -6,10 +6,14 @@
 package psu_test
 
 import (
+	"errors"
+	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"psu/pkg/psu"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -107,12 +111,312 @@ func (t *ViewTestSuite) TestRefresh() {
 		r.NotNil(v)
 
 		v.Refresh()
-		// Force scheduler
-		<-time.After(10 * time.Millisecond)
+		// Second refresh is received once the first one is done
+		v.Refresh()
 		t.mock.AssertExpectations(t.T())
+		v.Close()
+
+	}
+
+}
+
+func (t *ViewTestSuite) TestUndeliverable() {
+	args := []struct {
+		name    string
+		section *psu.Section
+		current string
+	}{
+		{
+			name:    "inside envelope",
+			section: &psu.Section{ActualVoltage: "12.00", SetVoltage: "12.00", ActualCurrent: "0.500", SetCurrent: "1.000"},
+			current: "0.500 / 1.000 A",
+		},
+		{
+			name:    "outside envelope",
+			section: &psu.Section{ActualVoltage: "59.00", SetVoltage: "60.00", ActualCurrent: "7.000", SetCurrent: "7.500"},
+			current: "7.000 / 7.500 A (max 7.00)",
+		},
+	}
+	_ = test.NewApp()
+	for _, arg := range args {
+		r := t.Require()
+		m := new(AccessMocker)
+		release := make(chan time.Time)
+		m.On("Section", 1).Return(arg.section, nil).Once()
+		// Next refresh waits, so labels aren't changed while they are checked
+		m.On("Section", 1).Return(arg.section, nil).WaitUntil(release)
+
+		v, err := psu.NewView(psu.ViewWithAccess(m), psu.ViewWithSections(1), psu.ViewWithModel(psu.CPX400DP))
+		r.Nil(err, arg.name)
+		content := v.Content()
+		v.Refresh()
+		v.Refresh()
+		r.Contains(labels(content), arg.current, arg.name)
+		close(release)
+		v.Close()
+	}
+}
+
+// labels returns texts of all visible labels within o
+func labels(o fyne.CanvasObject) []string {
+	if !o.Visible() {
+		return nil
+	}
+	switch o := o.(type) {
+	case *widget.Label:
+		return []string{o.Text}
+	case *fyne.Container:
+		var texts []string
+		for _, child := range o.Objects {
+			texts = append(texts, labels(child)...)
+		}
+		return texts
+	}
+	return nil
+}
+
+// button returns the first button with text within o
+func button(o fyne.CanvasObject, text string) *widget.Button {
+	switch o := o.(type) {
+	case *widget.Button:
+		if o.Text == text {
+			return o
+		}
+	case *fyne.Container:
+		for _, child := range o.Objects {
+			if b := button(child, text); b != nil {
+				return b
+			}
+		}
+	}
+	return nil
+}
+
+func (t *ViewTestSuite) TestSwitch() {
+	r := t.Require()
+	off := &psu.Section{ActualVoltage: "0.00", SetVoltage: "12.00", ActualCurrent: "0.000", SetCurrent: "1.000"}
+	read, proceed := make(chan struct{}), make(chan struct{})
+	t.mock.On("Section", 1).Return(off, nil).Twice()
+	// Refresh after switching waits, so labels aren't changed while they are checked
+	t.mock.On("Section", 1).Return(off, nil).Run(func(mock.Arguments) {
+		read <- struct{}{}
+		<-proceed
+	})
+	t.mock.On("SetState", 1, true).Return(false, errors.New("timeout")).Once()
+	t.mock.On("SetState", 1, true).Return(true, nil).Once()
+	_ = test.NewApp()
+	v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1))
+	r.Nil(err)
+	content := v.Content()
+	v.Refresh()
+	v.Refresh()
+	// The third one waits for the second one
+	v.Refresh()
+	<-read
+
+	// Failure is shown in section, output is read again by View
+	test.Tap(button(content, "ON"))
+	proceed <- struct{}{}
+	<-read
+	r.Contains(labels(content), "switching on failed: timeout")
+
+	test.Tap(button(content, "ON"))
+	proceed <- struct{}{}
+	<-read
+	r.NotContains(labels(content), "switching on failed: timeout")
+	proceed <- struct{}{}
+	v.Close()
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *ViewTestSuite) TestEnergy() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(&psu.Section{
+		State:         true,
+		ActualVoltage: "10",
+		SetVoltage:    "10",
+		ActualCurrent: "2",
+		SetCurrent:    "3",
+	}, nil)
+
+	_ = test.NewApp()
+	v, err := psu.NewView(
+		psu.ViewWithAccess(t.mock),
+		psu.ViewWithSections(1),
+		psu.ViewWithEnergy(time.Second),
+	)
+	r.Nil(err)
+	r.NotNil(v.Content())
+	r.Nil(v.Accumulator(2))
+
+	a := v.Accumulator(1)
+	r.NotNil(a)
+	v.Refresh()
+	v.Refresh()
+	r.Eventually(func() bool { return a.Energy().Energy > 0 }, time.Second, time.Millisecond)
+	r.InDelta(20, a.Energy().Power, 1e-9)
+	v.Close()
+}
+
+func (t *ViewTestSuite) TestRecording() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(&psu.Section{ActualVoltage: "1"}, nil)
+	_ = test.NewApp()
+	{
+		v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1))
+		r.Nil(err)
+		r.ErrorIs(v.StartRecording(), psu.ErrNoDataLogger)
+		r.False(v.Recording())
+	}
+	dir := t.T().TempDir()
+	d, err := psu.NewDataLogger(psu.DataLogWithAccess(t.mock), psu.DataLogWithSections(1), psu.DataLogWithDir(dir))
+	r.Nil(err)
+
+	v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1), psu.ViewWithDataLogger(d))
+	r.Nil(err)
+	r.Nil(v.StartRecording())
+	r.True(v.Recording())
+	r.ErrorIs(v.StartRecording(), psu.ErrRunning)
+	r.Eventually(d.Running, time.Second, time.Millisecond)
+
+	v.StopRecording()
+	r.False(v.Recording())
+	r.Eventually(func() bool { return !d.Running() }, time.Second, time.Millisecond)
+	v.Close()
+}
+
+func (t *ViewTestSuite) TestPowerCycle() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(&psu.Section{State: true, ActualCurrent: "0.100"}, nil)
+	t.mock.On("SetState", 1, false).Return(false, nil)
+	t.mock.On("SetState", 1, true).Return(true, nil)
+	_ = test.NewApp()
+	{
+		v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1))
+		r.Nil(err)
+		r.ErrorIs(v.StartPowerCycle(1), psu.ErrNoPowerCycle)
+		r.False(v.PowerCycling(1))
+	}
+
+	v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1),
+		psu.ViewWithPowerCycle(psu.CycleWithOffTime(time.Millisecond), psu.CycleWithOnTime(time.Millisecond), psu.CycleWithCycles(2)))
+	r.Nil(err)
+	r.NotNil(v.Content())
+	r.ErrorIs(v.StartPowerCycle(2), psu.ErrNoSection)
+	// Output isn't cycled, until its state is known
+	r.ErrorIs(v.StartPowerCycle(1), psu.ErrUnknownState)
+	v.Refresh()
+	v.Refresh()
+	r.Nil(v.StartPowerCycle(1))
+	r.ErrorIs(v.StartPowerCycle(1), psu.ErrRunning)
+	r.Eventually(func() bool { return !v.PowerCycling(1) }, time.Second, time.Millisecond)
+	t.mock.AssertNumberOfCalls(t.T(), "SetState", 4)
+	r.Nil(v.PowerCycleError(1))
+	r.ErrorIs(v.PowerCycleError(2), psu.ErrNoSection)
+
+	// Failed cycles are kept for UI
+	v.Close()
+	t.mock = new(AccessMocker)
+	t.mock.On("Section", 1).Return(&psu.Section{State: true, ActualCurrent: "0.100"}, nil)
+	t.mock.On("SetState", 1, false).Return(false, nil)
+	t.mock.On("SetState", 1, true).Return(false, errors.New("timeout"))
+	v, err = psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1),
+		psu.ViewWithPowerCycle(psu.CycleWithOffTime(time.Millisecond), psu.CycleWithOnTime(time.Millisecond)))
+	r.Nil(err)
+	r.NotNil(v.Content())
+	v.Refresh()
+	v.Refresh()
+	r.Nil(v.StartPowerCycle(1))
+	r.Eventually(func() bool { return !v.PowerCycling(1) }, time.Second, time.Millisecond)
+	r.ErrorIs(v.PowerCycleError(1), psu.ErrCycleFailed)
+	r.ErrorContains(v.PowerCycleError(1), "timeout")
+
+	// Stopped by user
+	v.Close()
+	v, err = psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1), psu.ViewWithPowerCycle(psu.CycleWithOffTime(time.Hour)))
+	r.Nil(err)
+	v.Refresh()
+	v.Refresh()
+	r.Nil(v.StartPowerCycle(1))
+	r.True(v.PowerCycling(1))
+	v.StopPowerCycle(1)
+	r.False(v.PowerCycling(1))
+	// Stop isn't reported as error
+	r.Never(func() bool { return v.PowerCycleError(1) != nil }, 50*time.Millisecond, time.Millisecond)
+	v.Close()
+}
+
+func (t *ViewTestSuite) TestAutoOff() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(&psu.Section{State: true, ActualCurrent: "0.100"}, nil)
+	t.mock.On("SetState", 1, true).Return(true, nil).Once()
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+	_ = test.NewApp()
+	{
+		v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1))
+		r.Nil(err)
+		r.Nil(v.AutoOff())
+		_, err = psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1), psu.ViewWithAutoOff(-1))
+		r.ErrorIs(err, psu.ErrInvalidPeriod)
+	}
 
+	v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1), psu.ViewWithAutoOff(10*time.Millisecond))
+	r.Nil(err)
+	r.NotNil(v.Content())
+	a := v.AutoOff()
+	r.NotNil(a)
+	r.Nil(a.Start(1, 10*time.Millisecond))
+	r.Eventually(func() bool {
+		_, armed := a.Remaining(1)
+		return !armed
+	}, time.Second, time.Millisecond)
+	// Output is read in background after switch off, wait for it
+	v.Refresh()
+	v.Refresh()
+	t.mock.AssertExpectations(t.T())
+
+	// Timers don't outlive View, armed output is switched off on close
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+	r.Nil(a.Arm(1, time.Hour))
+	v.Close()
+	r.ErrorIs(a.Arm(1, time.Millisecond), psu.ErrClosed)
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *ViewTestSuite) TestLimits() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(&psu.Section{
+		State:         true,
+		ActualVoltage: "12.00",
+		SetVoltage:    "12.00",
+		ActualCurrent: "3.000",
+		SetCurrent:    "5.000",
+	}, nil)
+	t.mock.On("SetState", 1, false).Return(false, nil)
+	_ = test.NewApp()
+	{
+		v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1))
+		r.Nil(err)
+		r.Nil(v.Limiter())
+		_, err = psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1), psu.ViewWithLimits(psu.Limit{Section: 0}))
+		r.ErrorIs(err, psu.ErrInvalidLimit)
 	}
 
+	{
+		// Limit isn't exceeded, so content isn't refreshed while it is built
+		v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1), psu.ViewWithLimits(psu.Limit{Section: 1, MaxPower: 50}))
+		r.Nil(err)
+		r.NotNil(v.Content())
+		v.Close()
+	}
+
+	v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1), psu.ViewWithLimits(psu.Limit{Section: 1, MaxPower: 30}))
+	r.Nil(err)
+	l := v.Limiter()
+	r.NotNil(l)
+	r.Eventually(func() bool { return len(l.Events()) > 0 }, time.Second, time.Millisecond)
+	r.Equal("power 36.00 W > 30.00 W", l.Events()[0].Reason)
+	v.Close()
 }
 
 func (t *ViewTestSuite) TestNew() {
@@ -147,3 +451,222 @@ func (a *AccessMocker) SetState(section int, value bool) (bool, error) {
 	args := a.Called(section, value)
 	return args.Bool(0), args.Error(1)
 }
+
+func (t *ViewTestSuite) TestAlerts() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(&psu.Section{State: false}, nil).Once()
+	t.mock.On("SetState", 1, true).Return(true, nil).Once()
+	t.mock.On("Section", 1).Return(&psu.Section{State: true}, nil).Once()
+	// Switched off on front panel
+	t.mock.On("Section", 1).Return(&psu.Section{State: false}, nil).Once()
+	t.mock.On("Section", 1).Return(&psu.Section{State: false, Trip: psu.TripOverCurrent}, nil).Once()
+	t.mock.On("Section", 1).Return((*psu.Section)(nil), errors.New("timeout")).Once()
+	_ = test.NewApp()
+	{
+		v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1))
+		r.Nil(err)
+		r.Nil(v.Alerts())
+		_, err = psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1), psu.ViewWithAlerts(0))
+		r.ErrorIs(err, psu.ErrInvalidHistory)
+	}
+
+	v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1), psu.ViewWithAlerts(2), psu.ViewWithAutoOff(time.Hour))
+	r.Nil(err)
+	r.NotNil(v.Content())
+	titles := func() []string {
+		var titles []string
+		for _, alert := range v.Alerts() {
+			titles = append(titles, alert.Title)
+		}
+		return titles
+	}
+
+	v.Refresh()
+	r.Nil(v.AutoOff().Start(1, time.Hour))
+	v.Refresh()
+
+	// Notification is checked once alert is seen, it is sent before alert is kept
+	test.AssertNotificationSent(t.T(), fyne.NewNotification("Output 1 switched off", "Switched outside of this window, e.g. on front panel or by another client"), func() {
+		v.Refresh()
+		r.Eventually(func() bool { return len(v.Alerts()) > 0 }, time.Second, time.Millisecond)
+	})
+	// Switched on by View isn't an alert, refreshes are done in order
+	r.Equal([]string{"Output 1 switched off"}, titles())
+
+	v.Refresh()
+	v.Refresh()
+	// Only the latest are kept
+	r.Eventually(func() bool { return len(v.Alerts()) == 2 && v.Alerts()[0].Title == "Connection lost" }, time.Second, time.Millisecond)
+	r.Equal([]string{"Connection lost", "Output 1 tripped"}, titles())
+	r.Equal("timeout", v.Alerts()[0].Text)
+	// Still armed
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+	v.Close()
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *ViewTestSuite) TestAlertsAccess() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(&psu.Section{State: false}, nil).Once()
+	t.mock.On("SetState", 1, true).Return(true, nil).Once()
+	t.mock.On("Section", 1).Return(&psu.Section{State: true}, nil).Once()
+	_ = test.NewApp()
+	{
+		v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1))
+		r.Nil(err)
+		r.Equal(t.mock, v.Access())
+	}
+
+	v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1), psu.ViewWithAlerts(2))
+	r.Nil(err)
+	v.Refresh()
+	// Switched by someone else, who shares access of View, e.g. rules
+	_, err = v.Access().SetState(1, true)
+	r.Nil(err)
+	v.Refresh()
+	// Second refresh is done, once next one is received
+	t.mock.On("Section", 1).Return(&psu.Section{State: true}, nil)
+	v.Refresh()
+	r.Empty(v.Alerts())
+	t.mock.AssertExpectations(t.T())
+	v.Close()
+}
+
+func (t *ViewTestSuite) TestCharts() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(&psu.Section{ActualVoltage: "5.00", SetVoltage: "5.00", ActualCurrent: "0.100", SetCurrent: "1.000"}, nil)
+	_ = test.NewApp()
+	{
+		v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1))
+		r.Nil(err)
+		_, err = v.Chart(1)
+		r.ErrorIs(err, psu.ErrNoChart)
+		_, err = psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1), psu.ViewWithCharts(0, ""))
+		r.ErrorIs(err, psu.ErrInvalidPeriod)
+	}
+
+	v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1), psu.ViewWithCharts(time.Minute, t.T().TempDir()))
+	r.Nil(err)
+	r.NotNil(v.Content())
+	_, err = v.Chart(2)
+	r.ErrorIs(err, psu.ErrNoSection)
+
+	v.Refresh()
+	v.Refresh()
+	r.Eventually(func() bool {
+		c, err := v.Chart(1)
+		return err == nil && len(c.Samples) == 2
+	}, time.Second, time.Millisecond)
+	c, err := v.Chart(1)
+	r.Nil(err)
+	r.Equal(time.Minute, c.To.Sub(c.From))
+	r.Equal(0.1, c.Samples[0].Values.ActualCurrent)
+	v.Close()
+}
+
+func (t *ViewTestSuite) TestSetpoints() {
+	r := t.Require()
+	_ = test.NewApp()
+	{
+		v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1))
+		r.Nil(err)
+		_, err = v.Setpoints(1)
+		r.ErrorIs(err, psu.ErrNoSetpoints)
+		_, err = psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1), psu.ViewWithSetpoints(nil))
+		r.ErrorIs(err, psu.ErrNoSetter)
+	}
+
+	protector := new(ProtectorMocker)
+	protector.On("Section", 1).Return(section("11.98", "0.500"), nil)
+	protector.On("OverVoltage", 1).Return("13.00", nil)
+	protector.On("OverCurrent", 1).Return("2.000", nil)
+	v, err := psu.NewView(psu.ViewWithAccess(protector), psu.ViewWithSections(1),
+		psu.ViewWithSetpoints(protector, psu.Envelope{Section: 1, MaxVoltage: 15}))
+	r.Nil(err)
+	r.NotNil(v.Content())
+
+	_, err = v.Setpoints(2)
+	r.ErrorIs(err, psu.ErrNoSection)
+	s, err := v.Setpoints(1)
+	r.Nil(err)
+	r.Equal(psu.Setpoints{Voltage: 12, Current: 1, OverVoltage: 13, OverCurrent: 2}, s)
+
+	// Refused by envelope and by model, nothing is written
+	_, err = v.ApplySetpoints(1, psu.Setpoints{Voltage: 16, Current: 1})
+	r.ErrorIs(err, psu.ErrOutsideEnvelope)
+	_, err = v.ApplySetpoints(1, psu.Setpoints{Voltage: 14, Current: 1, OverVoltage: 13})
+	r.ErrorIs(err, psu.ErrInvalidSetpoints)
+	protector.AssertNotCalled(t.T(), "WriteVoltage", mock.Anything, mock.Anything)
+
+	protector.On("WriteOverVoltage", 1, 15.0).Return("15.00", nil)
+	protector.On("WriteVoltage", 1, 14.0).Return("14.00", nil)
+	protector.On("WriteCurrent", 1, 1.0).Return("1.000", nil)
+	written, err := v.ApplySetpoints(1, psu.Setpoints{Voltage: 14, Current: 1, OverVoltage: 15})
+	r.Nil(err)
+	r.Equal(psu.Setpoints{Voltage: 14, Current: 1, OverVoltage: 15, OverCurrent: 2}, written)
+	protector.AssertNotCalled(t.T(), "WriteOverCurrent", mock.Anything, mock.Anything)
+	v.Close()
+}
+
+func (t *ViewTestSuite) TestConnection() {
+	r := t.Require()
+	_ = test.NewApp()
+	errLost := errors.New("connection lost")
+	_, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1), psu.ViewWithReconnect(time.Second, time.Millisecond))
+	r.ErrorIs(err, psu.ErrInvalidPeriod)
+
+	args := []struct {
+		name     string
+		min, max time.Duration
+		state    psu.ConnectionState
+	}{
+		{name: "backoff below max", min: time.Hour, max: 2 * time.Hour, state: psu.ConnectionReconnecting},
+		{name: "backoff at max", min: time.Hour, max: time.Hour, state: psu.ConnectionOffline},
+	}
+	for _, arg := range args {
+		m := new(AccessMocker)
+		m.On("Section", 1).Return((*psu.Section)(nil), errLost)
+		v, err := psu.NewView(psu.ViewWithAccess(m), psu.ViewWithSections(1), psu.ViewWithReconnect(arg.min, arg.max))
+		r.Nil(err, arg.name)
+		r.NotNil(v.Content(), arg.name)
+		state, _ := v.Connection()
+		r.Equal(psu.ConnectionUnknown, state, arg.name)
+
+		v.Refresh()
+		r.Eventually(func() bool {
+			state, last := v.Connection()
+			return state == arg.state && last.IsZero()
+		}, time.Second, time.Millisecond, arg.name)
+		v.Close()
+	}
+
+	// Ticker doesn't poll PSU, while retry is scheduled
+	{
+		var reads atomic.Int32
+		m := new(AccessMocker)
+		m.On("Section", 1).Return((*psu.Section)(nil), errLost).Run(func(mock.Arguments) { reads.Add(1) })
+		v, err := psu.NewView(psu.ViewWithAccess(m), psu.ViewWithSections(1), psu.ViewWithReconnect(time.Hour, 2*time.Hour))
+		r.Nil(err)
+		r.NotNil(v.Content())
+		v.BackgroundRefresh(time.Millisecond)
+		r.Eventually(func() bool { return reads.Load() == 1 }, time.Second, time.Millisecond)
+		r.Never(func() bool { return reads.Load() > 1 }, 50*time.Millisecond, time.Millisecond)
+		state, _ := v.Connection()
+		r.Equal(psu.ConnectionReconnecting, state)
+		v.Close()
+	}
+
+	// Failed refresh is retried by itself, until PSU answers
+	t.mock.On("Section", 1).Return((*psu.Section)(nil), errLost).Twice()
+	t.mock.On("Section", 1).Return(section("11.98", "0.500"), nil)
+	v, err := psu.NewView(psu.ViewWithAccess(t.mock), psu.ViewWithSections(1), psu.ViewWithReconnect(time.Millisecond, 10*time.Millisecond))
+	r.Nil(err)
+	v.Refresh()
+	r.Eventually(func() bool {
+		state, last := v.Connection()
+		return state == psu.ConnectionConnected && !last.IsZero()
+	}, time.Second, time.Millisecond)
+	t.mock.AssertNumberOfCalls(t.T(), "Section", 3)
+	r.Equal("connected", psu.ConnectionConnected.String())
+	v.Close()
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+import (
+	"sync"
+	"time"
+)
+
+// Watcher is Access, which turns readings passing through it into Events.
+// It can be put in front of any component reading sections, e.g. View, DataLogger or mqtt.Bridge.
+// Output switched by SetState of Watcher is reported as Local.
+type Watcher struct {
+	access   Access
+	handlers []func(Event)
+
+	mtx       sync.Mutex
+	sections  map[int]*watched
+	connected *bool
+}
+
+// watched is the last known state of section
+type watched struct {
+	// known is false until the first reading
+	known bool
+	state bool
+	trip  Trip
+	// expected is state requested by SetState, until it is read
+	expected *bool
+}
+
+var (
+	_ Access = (*Watcher)(nil)
+)
+
+func NewWatcher(opts ...WatcherOption) (*Watcher, error) {
+	w := &Watcher{
+		access:    nil,
+		handlers:  nil,
+		sections:  make(map[int]*watched),
+		connected: nil,
+	}
+	for _, opt := range opts {
+		if err := opt(w); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.verify(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Watcher) Section(section int) (*Section, error) {
+	s, err := w.access.Section(section)
+	now := time.Now()
+
+	w.mtx.Lock()
+	events := w.connection(now, err)
+	if err == nil {
+		events = append(events, w.compare(now, section, s)...)
+	}
+	w.mtx.Unlock()
+
+	for _, e := range events {
+		w.Notify(e)
+	}
+	return s, err
+}
+
+func (w *Watcher) SetState(section int, value bool) (bool, error) {
+	w.mtx.Lock()
+	last, ok := w.sections[section]
+	if !ok {
+		// Reading may be already on its way
+		last = &watched{}
+		w.sections[section] = last
+	}
+	expected := &value
+	last.expected = expected
+	w.mtx.Unlock()
+
+	state, err := w.access.SetState(section, value)
+	if err != nil {
+		w.mtx.Lock()
+		// Output wasn't switched, so later switch to the same state isn't ours. Newer request is kept.
+		if last, ok := w.sections[section]; ok && last.expected == expected {
+			last.expected = nil
+		}
+		w.mtx.Unlock()
+	}
+	return state, err
+}
+
+// Notify passes event to handlers, e.g. events of Limiter
+func (w *Watcher) Notify(e Event) {
+	for _, handler := range w.handlers {
+		handler(e)
+	}
+}
+
+// connection must be called with mtx locked
+func (w *Watcher) connection(now time.Time, err error) []Event {
+	connected := err == nil
+	if w.connected == nil {
+		w.connected = &connected
+		return nil
+	}
+	if *w.connected == connected {
+		return nil
+	}
+	*w.connected = connected
+	if connected {
+		return []Event{{Kind: EventConnectionRestored, Time: now}}
+	}
+	return []Event{{Kind: EventConnectionLost, Time: now, Err: err}}
+}
+
+// compare must be called with mtx locked
+func (w *Watcher) compare(now time.Time, section int, s *Section) []Event {
+	last, ok := w.sections[section]
+	if !ok || !last.known {
+		// The first reading tells nothing has changed
+		w.sections[section] = &watched{known: true, state: s.State, trip: s.Trip}
+		if ok && last.expected != nil && *last.expected != s.State {
+			w.sections[section].expected = last.expected
+		}
+		return nil
+	}
+	var values *Values
+	if v, err := s.Values(); err == nil {
+		values = &v
+	}
+
+	var events []Event
+	if s.State != last.state {
+		local := last.expected != nil && *last.expected == s.State
+		events = append(events, Event{Kind: EventSwitched, Time: now, Section: section, State: s.State, Local: local, Values: values})
+	}
+	if s.Trip != 0 && s.Trip != last.trip {
+		events = append(events, Event{Kind: EventTripped, Time: now, Section: section, State: s.State, Trip: s.Trip, Reason: s.Trip.String(), Values: values})
+	}
+	if last.expected != nil && *last.expected == s.State {
+		last.expected = nil
+	}
+	last.state, last.trip = s.State, s.Trip
+	return events
+}
+
+func (w *Watcher) verify() error {
+	if w.access == nil {
+		return ErrNoAccess
+	}
+	return nil
+}
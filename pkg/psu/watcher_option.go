@@ -0,0 +1,29 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu
+
+type WatcherOption func(*Watcher) error
+
+func WatcherWithPSU(p *PSU) WatcherOption {
+	return func(w *Watcher) error {
+		return WatcherWithAccess(p)(w)
+	}
+}
+
+func WatcherWithAccess(a Access) WatcherOption {
+	return func(w *Watcher) error {
+		w.access = a
+		return nil
+	}
+}
+
+// WatcherWithHandler adds function called with each event, in the same goroutine which read section
+func WatcherWithHandler(handler func(Event)) WatcherOption {
+	return func(w *Watcher) error {
+		w.handlers = append(w.handlers, handler)
+		return nil
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package psu_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/psu"
+)
+
+type WatcherTestSuite struct {
+	suite.Suite
+	mock   *AccessMocker
+	events []psu.Event
+}
+
+func TestWatcher(t *testing.T) {
+	suite.Run(t, new(WatcherTestSuite))
+}
+
+func (t *WatcherTestSuite) SetupTest() {
+	t.mock = new(AccessMocker)
+	t.events = nil
+}
+
+func (t *WatcherTestSuite) watcher() *psu.Watcher {
+	w, err := psu.NewWatcher(psu.WatcherWithAccess(t.mock), psu.WatcherWithHandler(func(e psu.Event) {
+		t.events = append(t.events, e)
+	}))
+	t.Require().Nil(err)
+	return w
+}
+
+func (t *WatcherTestSuite) kinds() []psu.EventKind {
+	var kinds []psu.EventKind
+	for _, e := range t.events {
+		kinds = append(kinds, e.Kind)
+	}
+	return kinds
+}
+
+func (t *WatcherTestSuite) TestNew() {
+	w, err := psu.NewWatcher()
+	t.Nil(w)
+	t.ErrorIs(err, psu.ErrNoAccess)
+}
+
+func (t *WatcherTestSuite) TestSwitched() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(&psu.Section{State: false}, nil).Once()
+	t.mock.On("Section", 1).Return(&psu.Section{State: true}, nil).Once()
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+	t.mock.On("Section", 1).Return(&psu.Section{State: false}, nil).Once()
+
+	w := t.watcher()
+	// The first reading is remembered only
+	_, err := w.Section(1)
+	r.Nil(err)
+	r.Empty(t.events)
+
+	// Switched on front panel
+	_, err = w.Section(1)
+	r.Nil(err)
+	r.Len(t.events, 1)
+	r.Equal(psu.EventSwitched, t.events[0].Kind)
+	r.Equal(1, t.events[0].Section)
+	r.True(t.events[0].State)
+	r.False(t.events[0].Local)
+
+	_, err = w.SetState(1, false)
+	r.Nil(err)
+	_, err = w.Section(1)
+	r.Nil(err)
+	r.Len(t.events, 2)
+	r.False(t.events[1].State)
+	r.True(t.events[1].Local)
+}
+
+func (t *WatcherTestSuite) TestSwitchedBeforeRead() {
+	r := t.Require()
+	t.mock.On("SetState", 1, true).Return(true, nil).Once()
+	// Reading taken, before output was switched
+	t.mock.On("Section", 1).Return(&psu.Section{State: false}, nil).Once()
+	t.mock.On("Section", 1).Return(&psu.Section{State: true}, nil).Once()
+
+	w := t.watcher()
+	_, err := w.SetState(1, true)
+	r.Nil(err)
+	for i := 0; i < 2; i++ {
+		_, err = w.Section(1)
+		r.Nil(err)
+	}
+	r.Len(t.events, 1)
+	r.True(t.events[0].Local)
+}
+
+func (t *WatcherTestSuite) TestSwitchFailed() {
+	r := t.Require()
+	errSwitch := errors.New("timeout")
+	t.mock.On("Section", 1).Return(&psu.Section{State: false}, nil).Once()
+	t.mock.On("SetState", 1, true).Return(false, errSwitch).Once()
+	// Switched on front panel afterwards
+	t.mock.On("Section", 1).Return(&psu.Section{State: true}, nil).Once()
+
+	w := t.watcher()
+	_, err := w.Section(1)
+	r.Nil(err)
+	_, err = w.SetState(1, true)
+	r.ErrorIs(err, errSwitch)
+	_, err = w.Section(1)
+	r.Nil(err)
+	r.Len(t.events, 1)
+	r.True(t.events[0].State)
+	r.False(t.events[0].Local)
+}
+
+func (t *WatcherTestSuite) TestTripped() {
+	r := t.Require()
+	t.mock.On("Section", 2).Return(&psu.Section{State: true}, nil).Once()
+	t.mock.On("Section", 2).Return(&psu.Section{State: false, Trip: psu.TripOverCurrent}, nil).Twice()
+
+	w := t.watcher()
+	for i := 0; i < 3; i++ {
+		_, err := w.Section(2)
+		r.Nil(err)
+	}
+	// Trip is reported once, as long as it lasts
+	r.Equal([]psu.EventKind{psu.EventSwitched, psu.EventTripped}, t.kinds())
+	r.Equal(psu.TripOverCurrent, t.events[1].Trip)
+	r.Equal(psu.TripOverCurrent.String(), t.events[1].Reason)
+}
+
+func (t *WatcherTestSuite) TestConnection() {
+	r := t.Require()
+	lost := errors.New("broken pipe")
+	t.mock.On("Section", 1).Return(&psu.Section{}, nil).Once()
+	t.mock.On("Section", 1).Return((*psu.Section)(nil), lost).Twice()
+	t.mock.On("Section", 1).Return(&psu.Section{}, nil).Once()
+
+	w := t.watcher()
+	for i := 0; i < 4; i++ {
+		_, _ = w.Section(1)
+	}
+	r.Equal([]psu.EventKind{psu.EventConnectionLost, psu.EventConnectionRestored}, t.kinds())
+	r.ErrorIs(t.events[0].Err, lost)
+}
+
+func (t *WatcherTestSuite) TestNotify() {
+	w := t.watcher()
+	e := psu.LimitEvent{Time: time.Now(), Section: 1, Reason: "current 2.000A > 1.000A", Values: psu.Values{ActualCurrent: 2}}.Event()
+	w.Notify(e)
+	t.Require().Len(t.events, 1)
+	t.Equal(psu.EventLimit, t.events[0].Kind)
+	t.Equal(2.0, t.events[0].Values.ActualCurrent)
+}
+
+func (t *WatcherTestSuite) TestEventKind() {
+	for _, kind := range []psu.EventKind{psu.EventSwitched, psu.EventTripped, psu.EventLimit, psu.EventConnectionLost, psu.EventConnectionRestored} {
+		parsed, err := psu.ParseEventKind(kind.String())
+		t.Nil(err)
+		t.Equal(kind, parsed)
+	}
+	_, err := psu.ParseEventKind("reboot")
+	t.ErrorIs(err, psu.ErrUnknownEvent)
+}
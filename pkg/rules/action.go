@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"psu/pkg/psu"
+)
+
+var (
+	ErrWebhook = errors.New("webhook failed")
+)
+
+// execute runs each action, even if previous one failed. The first error is returned.
+func (e *Engine) execute(ctx context.Context, actions []Action, f Firing) error {
+	var first error
+	for i, a := range actions {
+		if err := e.action(ctx, a, f); err != nil {
+			log.Error("rule ", f.Rule, ", action ", i+1, ": ", err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+func (e *Engine) action(ctx context.Context, a Action, f Firing) error {
+	switch {
+	case a.Switch != nil:
+		return e.switchState(a.Switch)
+	case a.Sequence != nil:
+		return e.sequence(ctx, a.Sequence)
+	case a.Webhook != nil:
+		return e.webhook(ctx, a.Webhook, f)
+	case a.Log != nil:
+		return mark(a.Log, f)
+	case a.DataLog == DataLogStart:
+		if err := e.recorder.StartRecording(); err != nil && !errors.Is(err, psu.ErrRunning) {
+			return err
+		}
+		return nil
+	case a.DataLog == DataLogStop:
+		e.recorder.StopRecording()
+		return nil
+	}
+	return ErrInvalidRule
+}
+
+func (e *Engine) switchState(a *SwitchAction) error {
+	state, err := e.access.SetState(a.Section, a.State)
+	if err != nil {
+		return err
+	}
+	if state != a.State {
+		return psu.ErrNotSwitched
+	}
+	return nil
+}
+
+func (e *Engine) sequence(ctx context.Context, steps []SequenceStep) error {
+	psuSteps := make([]psu.Step, len(steps))
+	for i, step := range steps {
+		psuSteps[i] = psu.Step{Section: step.Section, State: step.State, Delay: time.Duration(step.Delay)}
+	}
+	s, err := psu.NewSequence(psu.SequenceWithAccess(e.access), psu.SequenceWithSteps(psuSteps...))
+	if err != nil {
+		return err
+	}
+	return s.Run(ctx)
+}
+
+func (e *Engine) webhook(ctx context.Context, a *WebhookAction, f Firing) error {
+	body, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%w: %s", ErrWebhook, resp.Status)
+	}
+	return nil
+}
+
+// mark appends line to file, or writes it to log if file isn't set
+func mark(a *LogAction, f Firing) error {
+	line := fmt.Sprintf("%s rule %q: %s", f.Time.Format(time.RFC3339), f.Rule, a.Message)
+	if a.File == "" {
+		log.Error(line)
+		return nil
+	}
+	file, err := os.OpenFile(a.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(file, line); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
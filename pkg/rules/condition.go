@@ -0,0 +1,168 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package rules
+
+import (
+	"time"
+
+	"psu/pkg/psu"
+)
+
+// reading of single section in single poll, ok is false if section couldn't be read
+type reading struct {
+	section *psu.Section
+	values  psu.Values
+	ok      bool
+}
+
+type readings map[int]reading
+
+// evaluator is compiled Condition, it keeps state of For.
+// known is false, if any section used by condition couldn't be read, met is false then.
+type evaluator interface {
+	eval(r readings, now time.Time) (met, known bool)
+}
+
+var fields = map[string]func(r reading) float64{
+	FieldState:      func(r reading) float64 { return boolValue(r.section.State) },
+	FieldVoltage:    func(r reading) float64 { return r.values.ActualVoltage },
+	FieldCurrent:    func(r reading) float64 { return r.values.ActualCurrent },
+	FieldPower:      func(r reading) float64 { return r.values.ActualVoltage * r.values.ActualCurrent },
+	FieldSetVoltage: func(r reading) float64 { return r.values.SetVoltage },
+	FieldSetCurrent: func(r reading) float64 { return r.values.SetCurrent },
+	FieldCC:         func(r reading) float64 { return boolValue(r.section.Mode == psu.ModeCC) },
+	FieldTripped:    func(r reading) float64 { return boolValue(r.section.Trip != 0) },
+}
+
+var operators = map[string]func(a, b float64) bool{
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+type compare struct {
+	section int
+	field   func(r reading) float64
+	op      func(a, b float64) bool
+	value   float64
+}
+
+type allOf []evaluator
+
+type anyOf []evaluator
+
+type notOf struct {
+	evaluator
+}
+
+// hold is true, when wrapped evaluator is true for duration
+type hold struct {
+	evaluator
+	duration time.Duration
+	since    time.Time
+}
+
+// compile expects verified Condition
+func compile(c Condition) evaluator {
+	var e evaluator
+	switch {
+	case c.Field != "":
+		e = &compare{section: c.Section, field: fields[c.Field], op: operators[c.Op], value: c.Value}
+	case c.All != nil:
+		e = allOf(compileAll(c.All))
+	case c.Any != nil:
+		e = anyOf(compileAll(c.Any))
+	default:
+		e = notOf{compile(*c.Not)}
+	}
+	if c.For > 0 {
+		e = &hold{evaluator: e, duration: time.Duration(c.For)}
+	}
+	return e
+}
+
+func compileAll(conditions []Condition) []evaluator {
+	evaluators := make([]evaluator, len(conditions))
+	for i, c := range conditions {
+		evaluators[i] = compile(c)
+	}
+	return evaluators
+}
+
+// sections returns sections read by Condition
+func (c Condition) sections() []int {
+	var sections []int
+	if c.Field != "" {
+		sections = append(sections, c.Section)
+	}
+	for _, combined := range [][]Condition{c.All, c.Any} {
+		for _, sub := range combined {
+			sections = append(sections, sub.sections()...)
+		}
+	}
+	if c.Not != nil {
+		sections = append(sections, c.Not.sections()...)
+	}
+	return sections
+}
+
+// eval of section, which couldn't be read, is unknown
+func (c *compare) eval(r readings, _ time.Time) (bool, bool) {
+	reading, ok := r[c.section]
+	if !ok || !reading.ok {
+		return false, false
+	}
+	return c.op(c.field(reading), c.value), true
+}
+
+// eval evaluates each condition, so holds are updated
+func (a allOf) eval(r readings, now time.Time) (bool, bool) {
+	met, known := true, true
+	for _, e := range a {
+		m, k := e.eval(r, now)
+		met, known = met && m, known && k
+	}
+	return met && known, known
+}
+
+// eval is unknown, if any condition is unknown, even if other one is met
+func (a anyOf) eval(r readings, now time.Time) (bool, bool) {
+	met, known := false, true
+	for _, e := range a {
+		m, k := e.eval(r, now)
+		met, known = met || m, known && k
+	}
+	return met && known, known
+}
+
+// eval of unknown condition is unknown too, so lost section doesn't make it true
+func (n notOf) eval(r readings, now time.Time) (bool, bool) {
+	met, known := n.evaluator.eval(r, now)
+	return known && !met, known
+}
+
+// eval starts counting again, when condition is unknown
+func (h *hold) eval(r readings, now time.Time) (bool, bool) {
+	met, known := h.evaluator.eval(r, now)
+	if !met {
+		h.since = time.Time{}
+		return false, known
+	}
+	if h.since.IsZero() {
+		h.since = now
+	}
+	return now.Sub(h.since) >= h.duration, known
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
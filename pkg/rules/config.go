@@ -0,0 +1,223 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"psu/pkg/config"
+)
+
+// File is layout of rules file
+type File struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule runs actions of Then in order, once When becomes true.
+// Rule is fired again only after When was false in the meantime.
+type Rule struct {
+	Name string    `json:"name"`
+	When Condition `json:"when"`
+	Then []Action  `json:"then"`
+}
+
+// Condition is either comparison of single field of section (Section, Field, Op, Value)
+// or combination of other conditions (All, Any or Not). For makes condition true,
+// only if it is met by consecutive readings for given time.
+type Condition struct {
+	Section int     `json:"section,omitempty"`
+	Field   string  `json:"field,omitempty"`
+	Op      string  `json:"op,omitempty"`
+	Value   float64 `json:"value,omitempty"`
+
+	All []Condition `json:"all,omitempty"`
+	Any []Condition `json:"any,omitempty"`
+	Not *Condition  `json:"not,omitempty"`
+
+	For config.Duration `json:"for,omitempty"`
+}
+
+// Action must have exactly one field set
+type Action struct {
+	Switch   *SwitchAction  `json:"switch,omitempty"`
+	Sequence []SequenceStep `json:"sequence,omitempty"`
+	Webhook  *WebhookAction `json:"webhook,omitempty"`
+	Log      *LogAction     `json:"log,omitempty"`
+	// DataLog is "start" or "stop" of recording
+	DataLog string `json:"datalog,omitempty"`
+}
+
+type SwitchAction struct {
+	Section int  `json:"section"`
+	State   bool `json:"state"`
+}
+
+// SequenceStep is psu.Step without condition, Delay passes before the next step
+type SequenceStep struct {
+	Section int             `json:"section"`
+	State   bool            `json:"state"`
+	Delay   config.Duration `json:"delay,omitempty"`
+}
+
+// WebhookAction posts Firing as JSON to URL
+type WebhookAction struct {
+	URL string `json:"url"`
+}
+
+// LogAction writes Message to log, or appends it as a mark with time to File
+type LogAction struct {
+	Message string `json:"message"`
+	File    string `json:"file,omitempty"`
+}
+
+// Field names of Condition
+const (
+	FieldState      = "state"
+	FieldVoltage    = "voltage"
+	FieldCurrent    = "current"
+	FieldPower      = "power"
+	FieldSetVoltage = "setVoltage"
+	FieldSetCurrent = "setCurrent"
+	// FieldCC is 1, when output is current limiting
+	FieldCC = "cc"
+	// FieldTripped is 1, when any protection is tripped
+	FieldTripped = "tripped"
+)
+
+// DataLog actions
+const (
+	DataLogStart = "start"
+	DataLogStop  = "stop"
+)
+
+// Load reads rules from file
+func Load(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes and verifies rules
+func Parse(data []byte) ([]Rule, error) {
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	for _, r := range f.Rules {
+		if err := r.verify(); err != nil {
+			return nil, err
+		}
+	}
+	return f.Rules, nil
+}
+
+func (r Rule) verify() error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: rule without name", ErrInvalidRule)
+	}
+	if err := r.When.verify(); err != nil {
+		return fmt.Errorf("rule %q: %w", r.Name, err)
+	}
+	if len(r.Then) == 0 {
+		return fmt.Errorf("%w: rule %q has no actions", ErrInvalidRule, r.Name)
+	}
+	for i, a := range r.Then {
+		if err := a.verify(); err != nil {
+			return fmt.Errorf("rule %q, action %d: %w", r.Name, i+1, err)
+		}
+	}
+	return nil
+}
+
+func (c Condition) verify() error {
+	if c.For < 0 {
+		return fmt.Errorf("%w: negative for", ErrInvalidRule)
+	}
+	kinds := 0
+	if c.Field != "" {
+		kinds++
+		if c.Section < 1 {
+			return fmt.Errorf("%w: invalid section %d", ErrInvalidRule, c.Section)
+		}
+		if _, ok := fields[c.Field]; !ok {
+			return fmt.Errorf("%w: unknown field %q", ErrInvalidRule, c.Field)
+		}
+		if _, ok := operators[c.Op]; !ok {
+			return fmt.Errorf("%w: unknown operator %q", ErrInvalidRule, c.Op)
+		}
+	}
+	for _, combined := range [][]Condition{c.All, c.Any} {
+		if combined == nil {
+			continue
+		}
+		kinds++
+		if len(combined) == 0 {
+			return fmt.Errorf("%w: empty combination", ErrInvalidRule)
+		}
+		for _, sub := range combined {
+			if err := sub.verify(); err != nil {
+				return err
+			}
+		}
+	}
+	if c.Not != nil {
+		kinds++
+		if err := c.Not.verify(); err != nil {
+			return err
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("%w: condition must have exactly one of field, all, any or not", ErrInvalidRule)
+	}
+	return nil
+}
+
+func (a Action) verify() error {
+	kinds := 0
+	if a.Switch != nil {
+		kinds++
+		if a.Switch.Section < 1 {
+			return fmt.Errorf("%w: invalid section %d", ErrInvalidRule, a.Switch.Section)
+		}
+	}
+	if a.Sequence != nil {
+		kinds++
+		if len(a.Sequence) == 0 {
+			return fmt.Errorf("%w: empty sequence", ErrInvalidRule)
+		}
+		for _, step := range a.Sequence {
+			if step.Section < 1 || step.Delay < 0 {
+				return fmt.Errorf("%w: invalid sequence step", ErrInvalidRule)
+			}
+		}
+	}
+	if a.Webhook != nil {
+		kinds++
+		if a.Webhook.URL == "" {
+			return fmt.Errorf("%w: webhook without url", ErrInvalidRule)
+		}
+	}
+	if a.Log != nil {
+		kinds++
+		if a.Log.Message == "" {
+			return fmt.Errorf("%w: log without message", ErrInvalidRule)
+		}
+	}
+	if a.DataLog != "" {
+		kinds++
+		if a.DataLog != DataLogStart && a.DataLog != DataLogStop {
+			return fmt.Errorf("%w: datalog must be %q or %q", ErrInvalidRule, DataLogStart, DataLogStop)
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("%w: action must have exactly one of switch, sequence, webhook, log or datalog", ErrInvalidRule)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+// Package rules runs user-defined rules on PSU readings.
+//
+// Rules are declared in JSON: condition on fields of sections, optionally held for some time
+// and combined by all / any / not, fires actions: switching output, running sequence,
+// posting webhook, writing log mark or starting and stopping data logger.
+package rules
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"psu/pkg/api"
+	"psu/pkg/psu"
+)
+
+// Recorder starts and stops recording of readings, implemented by psu.View
+type Recorder interface {
+	StartRecording() error
+	StopRecording()
+}
+
+// Firing describes rule, which fired, and readings of its sections. It is body of webhook.
+type Firing struct {
+	Rule     string        `json:"rule"`
+	Time     time.Time     `json:"time"`
+	Sections []api.Section `json:"sections"`
+}
+
+// Engine polls sections used by rules every interval, like View does, and evaluates rules
+type Engine struct {
+	access   psu.Access
+	rules    []*rule
+	sections []int
+	interval time.Duration
+	recorder Recorder
+	client   *http.Client
+	handler  func(Firing, error)
+
+	wg sync.WaitGroup
+}
+
+// rule is compiled Rule
+type rule struct {
+	Rule
+	when  evaluator
+	fired bool
+	// running is set, while actions are executed
+	running sync.Mutex
+}
+
+var (
+	ErrInvalidRule     = errors.New("invalid rule")
+	ErrNoRules         = errors.New("no rules")
+	ErrInvalidInterval = errors.New("interval must be positive")
+	ErrNoRecorder      = errors.New("no recorder for datalog action")
+)
+
+func New(opts ...Option) (*Engine, error) {
+	e := &Engine{
+		access:   nil,
+		rules:    nil,
+		interval: 500 * time.Millisecond,
+		recorder: nil,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		handler:  nil,
+	}
+	for _, opt := range opts {
+		if err := opt(e); err != nil {
+			return nil, err
+		}
+	}
+	if err := e.verify(); err != nil {
+		return nil, err
+	}
+
+	used := make(map[int]bool)
+	for _, r := range e.rules {
+		for _, section := range r.When.sections() {
+			used[section] = true
+		}
+	}
+	for section := range used {
+		e.sections = append(e.sections, section)
+	}
+	sort.Ints(e.sections)
+	return e, nil
+}
+
+// Run polls sections and fires rules, until ctx is done. Actions in progress are awaited.
+func (e *Engine) Run(ctx context.Context) error {
+	defer e.wg.Wait()
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		e.evaluate(ctx, e.read(), time.Now())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// read reads each section once, so all rules see the same readings
+func (e *Engine) read() readings {
+	r := make(readings, len(e.sections))
+	for _, section := range e.sections {
+		s, err := e.access.Section(section)
+		if err != nil {
+			log.Debug("rules: error on reading section ", section, ": ", err)
+			r[section] = reading{}
+			continue
+		}
+		values, err := s.Values()
+		r[section] = reading{section: s, values: values, ok: err == nil}
+	}
+	return r
+}
+
+func (e *Engine) evaluate(ctx context.Context, r readings, now time.Time) {
+	for _, rl := range e.rules {
+		met, known := rl.when.eval(r, now)
+		if !known {
+			// Sections couldn't be read, rule is neither met nor reset
+			continue
+		}
+		if !met {
+			rl.fired = false
+			continue
+		}
+		if rl.fired {
+			continue
+		}
+		rl.fired = true
+		firing := Firing{Rule: rl.Name, Time: now, Sections: firingSections(r, rl.When.sections())}
+		log.Debug("rule ", rl.Name, " fired")
+		e.wg.Add(1)
+		go func(rl *rule) {
+			defer e.wg.Done()
+			// Actions of single rule never overlap, e.g. two sequences
+			rl.running.Lock()
+			defer rl.running.Unlock()
+			err := e.execute(ctx, rl.Then, firing)
+			if err != nil {
+				log.Error("rule ", rl.Name, " failed: ", err)
+			}
+			if e.handler != nil {
+				e.handler(firing, err)
+			}
+		}(rl)
+	}
+}
+
+func firingSections(r readings, sections []int) []api.Section {
+	result := []api.Section{}
+	seen := make(map[int]bool)
+	for _, section := range sections {
+		if seen[section] || !r[section].ok {
+			continue
+		}
+		seen[section] = true
+		if s, err := api.NewSection(section, r[section].section); err == nil {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func (e *Engine) verify() error {
+	if e.access == nil {
+		return psu.ErrNoAccess
+	}
+	if len(e.rules) == 0 {
+		return ErrNoRules
+	}
+	for _, r := range e.rules {
+		for _, a := range r.Then {
+			if a.DataLog != "" && e.recorder == nil {
+				return ErrNoRecorder
+			}
+		}
+	}
+	return nil
+}
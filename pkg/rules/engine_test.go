@@ -0,0 +1,331 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package rules_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/psu"
+	"psu/pkg/rules"
+)
+
+type EngineTestSuite struct {
+	suite.Suite
+	mock   *AccessMocker
+	fired  chan rules.Firing
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type AccessMocker struct {
+	mock.Mock
+}
+
+type RecorderMocker struct {
+	mock.Mock
+}
+
+func TestEngine(t *testing.T) {
+	suite.Run(t, new(EngineTestSuite))
+}
+
+func (t *EngineTestSuite) SetupTest() {
+	t.mock = new(AccessMocker)
+	t.fired = make(chan rules.Firing, 10)
+	t.cancel = nil
+}
+
+func (t *EngineTestSuite) TearDownTest() {
+	if t.cancel != nil {
+		t.cancel()
+		<-t.done
+	}
+}
+
+// run starts Engine with rules from JSON in background
+func (t *EngineTestSuite) run(file string, opts ...rules.Option) {
+	r := t.Require()
+	parsed, err := rules.Parse([]byte(file))
+	r.Nil(err)
+	opts = append([]rules.Option{
+		rules.WithAccess(t.mock),
+		rules.WithRules(parsed...),
+		rules.WithInterval(time.Millisecond),
+		rules.WithHandler(func(f rules.Firing, err error) {
+			t.Nil(err)
+			t.fired <- f
+		}),
+	}, opts...)
+	e, err := rules.New(opts...)
+	r.Nil(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel, t.done = cancel, make(chan struct{})
+	go func() {
+		defer close(t.done)
+		_ = e.Run(ctx)
+	}()
+}
+
+func section(state bool, voltage, current string) *psu.Section {
+	return &psu.Section{
+		State:         state,
+		ActualVoltage: voltage,
+		SetVoltage:    "12.00",
+		ActualCurrent: current,
+		SetCurrent:    "2.000",
+	}
+}
+
+func (t *EngineTestSuite) TestParse() {
+	args := []struct {
+		name string
+		file string
+		err  string
+	}{
+		{name: "json", file: `{"rules": [`, err: "unexpected end of JSON input"},
+		{name: "name", file: `{"rules": [{"when": {"section": 1, "field": "state", "op": "==", "value": 1}, "then": [{"log": {"message": "on"}}]}]}`, err: "rule without name"},
+		{name: "field", file: `{"rules": [{"name": "r", "when": {"section": 1, "field": "temperature", "op": ">", "value": 1}, "then": [{"log": {"message": "hot"}}]}]}`, err: `unknown field "temperature"`},
+		{name: "operator", file: `{"rules": [{"name": "r", "when": {"section": 1, "field": "current", "op": "=>", "value": 1}, "then": [{"log": {"message": "x"}}]}]}`, err: `unknown operator "=>"`},
+		{name: "section", file: `{"rules": [{"name": "r", "when": {"field": "current", "op": ">", "value": 1}, "then": [{"log": {"message": "x"}}]}]}`, err: "invalid section 0"},
+		{name: "empty", file: `{"rules": [{"name": "r", "when": {"all": []}, "then": [{"log": {"message": "x"}}]}]}`, err: "empty combination"},
+		{name: "mixed", file: `{"rules": [{"name": "r", "when": {"section": 1, "field": "current", "op": ">", "any": [{"section": 1, "field": "state", "op": "==", "value": 1}]}, "then": [{"log": {"message": "x"}}]}]}`, err: "exactly one of field, all, any or not"},
+		{name: "nothing", file: `{"rules": [{"name": "r", "when": {}, "then": [{"log": {"message": "x"}}]}]}`, err: "exactly one of field, all, any or not"},
+		{name: "no actions", file: `{"rules": [{"name": "r", "when": {"section": 1, "field": "current", "op": ">"}}]}`, err: "has no actions"},
+		{name: "action", file: `{"rules": [{"name": "r", "when": {"section": 1, "field": "current", "op": ">"}, "then": [{}]}]}`, err: "action 1: invalid rule"},
+		{name: "datalog", file: `{"rules": [{"name": "r", "when": {"section": 1, "field": "current", "op": ">"}, "then": [{"datalog": "pause"}]}]}`, err: "datalog must be"},
+		{name: "duration", file: `{"rules": [{"name": "r", "when": {"section": 1, "field": "current", "op": ">", "for": "soon"}, "then": [{"datalog": "start"}]}]}`, err: "invalid duration"},
+	}
+	for _, arg := range args {
+		_, err := rules.Parse([]byte(arg.file))
+		t.ErrorContains(err, arg.err, arg.name)
+	}
+
+	parsed, err := rules.Parse([]byte(`{"rules": [{
+		"name": "io overcurrent",
+		"when": {"all": [
+			{"section": 2, "field": "current", "op": ">", "value": 1.5},
+			{"not": {"section": 2, "field": "cc", "op": "==", "value": 1}}
+		], "for": "3s"},
+		"then": [{"switch": {"section": 1, "state": false}}, {"log": {"message": "alert"}}]
+	}]}`))
+	t.Nil(err)
+	t.Len(parsed, 1)
+	t.Equal(3*time.Second, time.Duration(parsed[0].When.For))
+}
+
+func (t *EngineTestSuite) TestNew() {
+	r := t.Require()
+	parsed, err := rules.Parse([]byte(`{"rules": [{"name": "r", "when": {"section": 1, "field": "state", "op": "==", "value": 1}, "then": [{"datalog": "start"}]}]}`))
+	r.Nil(err)
+
+	_, err = rules.New(rules.WithRules(parsed...))
+	r.ErrorIs(err, psu.ErrNoAccess)
+	_, err = rules.New(rules.WithAccess(t.mock))
+	r.ErrorIs(err, rules.ErrNoRules)
+	_, err = rules.New(rules.WithAccess(t.mock), rules.WithRules(parsed...))
+	r.ErrorIs(err, rules.ErrNoRecorder)
+	_, err = rules.New(rules.WithInterval(0))
+	r.ErrorIs(err, rules.ErrInvalidInterval)
+	_, err = rules.New(rules.WithRules(rules.Rule{Name: "r"}))
+	r.ErrorIs(err, rules.ErrInvalidRule)
+
+	e, err := rules.New(rules.WithAccess(t.mock), rules.WithRules(parsed...), rules.WithRecorder(new(RecorderMocker)))
+	r.Nil(err)
+	r.NotNil(e)
+}
+
+func (t *EngineTestSuite) TestSwitchAndMark() {
+	r := t.Require()
+	marks := filepath.Join(t.T().TempDir(), "marks.log")
+	// Short spike is ignored, then current stays high
+	t.mock.On("Section", 2).Return(section(true, "5.00", "0.500"), nil).Twice()
+	t.mock.On("Section", 2).Return(section(true, "5.00", "1.600"), nil).Once()
+	t.mock.On("Section", 2).Return(section(true, "5.00", "0.500"), nil).Twice()
+	t.mock.On("Section", 2).Return(section(true, "5.00", "1.600"), nil)
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+
+	t.run(`{"rules": [{
+		"name": "io overcurrent",
+		"when": {"section": 2, "field": "current", "op": ">", "value": 1.5, "for": "10ms"},
+		"then": [{"switch": {"section": 1, "state": false}}, {"log": {"message": "io overcurrent", "file": "` + marks + `"}}]
+	}]}`)
+	f := <-t.fired
+	r.Equal("io overcurrent", f.Rule)
+	r.Len(f.Sections, 1)
+	r.Equal(2, f.Sections[0].Section)
+	r.InDelta(1.6, f.Sections[0].ActualCurrent, 1e-9)
+
+	content, err := os.ReadFile(marks)
+	r.Nil(err)
+	r.True(strings.HasSuffix(string(content), "rule \"io overcurrent\": io overcurrent\n"), string(content))
+
+	// Condition is still met, but rule fires only once
+	<-time.After(20 * time.Millisecond)
+	r.Empty(t.fired)
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *EngineTestSuite) TestRefire() {
+	t.mock.On("Section", 1).Return(section(true, "12.00", "0.100"), nil).Twice()
+	t.mock.On("Section", 1).Return(section(false, "0.00", "0.000"), nil).Twice()
+	t.mock.On("Section", 1).Return(section(true, "12.00", "0.100"), nil)
+	recorder := new(RecorderMocker)
+	recorder.On("StartRecording").Return(nil).Twice()
+
+	t.run(`{"rules": [{
+		"name": "log while on",
+		"when": {"section": 1, "field": "state", "op": "==", "value": 1},
+		"then": [{"datalog": "start"}]
+	}]}`, rules.WithRecorder(recorder))
+	<-t.fired
+	<-t.fired
+	recorder.AssertExpectations(t.T())
+}
+
+func (t *EngineTestSuite) TestCombinators() {
+	r := t.Require()
+	t.mock.On("Section", 1).Return(section(true, "12.00", "0.100"), nil)
+	t.mock.On("Section", 2).Return(section(false, "0.00", "0.000"), nil)
+	recorder := new(RecorderMocker)
+	recorder.On("StopRecording").Return().Once()
+
+	t.run(`{"rules": [{
+		"name": "any",
+		"when": {"any": [
+			{"section": 2, "field": "state", "op": "==", "value": 1},
+			{"all": [
+				{"section": 1, "field": "power", "op": ">=", "value": 1.2},
+				{"not": {"section": 1, "field": "tripped", "op": "==", "value": 1}},
+				{"not": {"section": 2, "field": "voltage", "op": ">", "value": 0}}
+			]}
+		]},
+		"then": [{"datalog": "stop"}]
+	}]}`, rules.WithRecorder(recorder))
+	f := <-t.fired
+	r.Equal("any", f.Rule)
+	r.Len(f.Sections, 2)
+	recorder.AssertExpectations(t.T())
+}
+
+func (t *EngineTestSuite) TestUnreadable() {
+	r := t.Require()
+	// Connection lost, then output found off
+	t.mock.On("Section", 1).Return((*psu.Section)(nil), psu.ErrNoSection).Times(3)
+	t.mock.On("Section", 2).Return(section(true, "12.00", "0.100"), nil)
+	t.mock.On("Section", 1).Return(section(false, "0.00", "0.000"), nil)
+	recorder := new(RecorderMocker)
+	recorder.On("StopRecording").Return().Once()
+
+	t.run(`{"rules": [{
+		"name": "off",
+		"when": {"any": [
+			{"not": {"section": 1, "field": "state", "op": "==", "value": 1}},
+			{"section": 2, "field": "state", "op": "==", "value": 0}
+		]},
+		"then": [{"datalog": "stop"}]
+	}]}`, rules.WithRecorder(recorder))
+	f := <-t.fired
+	r.Equal("off", f.Rule)
+	// Fired by reading, not by lost section
+	r.Len(f.Sections, 2)
+	r.Equal(false, f.Sections[0].State)
+	recorder.AssertExpectations(t.T())
+}
+
+func (t *EngineTestSuite) TestWebhookAndSequence() {
+	r := t.Require()
+	received := make(chan rules.Firing, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var f rules.Firing
+		t.Nil(json.NewDecoder(req.Body).Decode(&f))
+		t.Equal("application/json", req.Header.Get("Content-Type"))
+		received <- f
+	}))
+	defer server.Close()
+
+	tripped := section(false, "0.00", "0.000")
+	tripped.Trip = psu.TripOverVoltage
+	t.mock.On("Section", 1).Return(tripped, nil)
+	t.mock.On("SetState", 2, false).Return(false, nil).Once()
+	t.mock.On("SetState", 3, false).Return(false, nil).Once()
+
+	t.run(`{"rules": [{
+		"name": "trip",
+		"when": {"section": 1, "field": "tripped", "op": "==", "value": 1},
+		"then": [
+			{"sequence": [{"section": 2, "state": false, "delay": "5ms"}, {"section": 3, "state": false}]},
+			{"webhook": {"url": "` + server.URL + `"}}
+		]
+	}]}`)
+	f := <-received
+	r.Equal("trip", f.Rule)
+	r.Equal([]string{"OVP"}, f.Sections[0].Trip)
+	<-t.fired
+	t.mock.AssertExpectations(t.T())
+}
+
+func (t *EngineTestSuite) TestActionFailure() {
+	r := t.Require()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	t.mock.On("Section", 1).Return(section(true, "12.00", "0.100"), nil)
+	t.mock.On("SetState", 1, false).Return(false, nil).Once()
+
+	parsed, err := rules.Parse([]byte(`{"rules": [{
+		"name": "r",
+		"when": {"section": 1, "field": "voltage", "op": ">", "value": 10},
+		"then": [{"webhook": {"url": "` + server.URL + `"}}, {"switch": {"section": 1, "state": false}}]
+	}]}`))
+	r.Nil(err)
+	failed := make(chan error, 1)
+	e, err := rules.New(rules.WithAccess(t.mock), rules.WithRules(parsed...), rules.WithHandler(func(_ rules.Firing, err error) {
+		failed <- err
+	}))
+	r.Nil(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = e.Run(ctx)
+	}()
+
+	// Failed webhook doesn't stop switching
+	r.ErrorIs(<-failed, rules.ErrWebhook)
+	cancel()
+	<-done
+	t.mock.AssertExpectations(t.T())
+}
+
+func (a *AccessMocker) Section(number int) (*psu.Section, error) {
+	args := a.Called(number)
+	return args.Get(0).(*psu.Section), args.Error(1)
+}
+
+func (a *AccessMocker) SetState(number int, state bool) (bool, error) {
+	args := a.Called(number, state)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *RecorderMocker) StartRecording() error {
+	return r.Called().Error(0)
+}
+
+func (r *RecorderMocker) StopRecording() {
+	r.Called()
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package rules
+
+import (
+	"net/http"
+	"time"
+
+	"psu/pkg/psu"
+)
+
+type Option func(*Engine) error
+
+func WithPSU(p *psu.PSU) Option {
+	return func(e *Engine) error {
+		return WithAccess(p)(e)
+	}
+}
+
+func WithAccess(a psu.Access) Option {
+	return func(e *Engine) error {
+		e.access = a
+		return nil
+	}
+}
+
+// WithRules adds rules, e.g. read by Load
+func WithRules(rules ...Rule) Option {
+	return func(e *Engine) error {
+		for _, r := range rules {
+			if err := r.verify(); err != nil {
+				return err
+			}
+			e.rules = append(e.rules, &rule{Rule: r, when: compile(r.When)})
+		}
+		return nil
+	}
+}
+
+// WithInterval sets how often sections are read, 500 ms by default
+func WithInterval(t time.Duration) Option {
+	return func(e *Engine) error {
+		if t <= 0 {
+			return ErrInvalidInterval
+		}
+		e.interval = t
+		return nil
+	}
+}
+
+// WithRecorder is required by datalog actions
+func WithRecorder(r Recorder) Option {
+	return func(e *Engine) error {
+		e.recorder = r
+		return nil
+	}
+}
+
+// WithHTTPClient sets client used by webhook actions
+func WithHTTPClient(c *http.Client) Option {
+	return func(e *Engine) error {
+		e.client = c
+		return nil
+	}
+}
+
+// WithHandler sets function called, once actions of fired rule are done. Err is the first failure.
+func WithHandler(handler func(f Firing, err error)) Option {
+	return func(e *Engine) error {
+		e.handler = handler
+		return nil
+	}
+}
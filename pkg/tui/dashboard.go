@@ -0,0 +1,353 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+// Package tui shows PSU in terminal, for machines without display.
+//
+// Dashboard shows the same grid as psu.View: section number, state, voltage and current.
+// Outputs are switched with keys, after confirmation.
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"psu/pkg/psu"
+)
+
+type Dashboard struct {
+	access   psu.Access
+	sections []int
+	interval time.Duration
+	screen   tcell.Screen
+	title    string
+
+	mtx      sync.Mutex
+	readings []reading
+	updated  time.Time
+	selected int
+	// confirm is true, while switching of selected section awaits confirmation
+	confirm bool
+	// pending is switching shown in confirmation, readings may change before user confirms it
+	pending switching
+	message string
+
+	trigger chan struct{}
+}
+
+type reading struct {
+	section *psu.Section
+	err     error
+}
+
+type switching struct {
+	index, number int
+	state         bool
+}
+
+var (
+	ErrInvalidInterval = errors.New("interval must be positive")
+)
+
+const (
+	labelWidth  = 10
+	columnWidth = 22
+	help        = "←/→ select  Enter toggle  r refresh  q quit"
+)
+
+func New(opts ...Option) (*Dashboard, error) {
+	d := &Dashboard{
+		access:   nil,
+		sections: nil,
+		interval: 1 * time.Second,
+		screen:   nil,
+		title:    "CPX400DP",
+		trigger:  make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		if err := opt(d); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.verify(); err != nil {
+		return nil, err
+	}
+	d.readings = make([]reading, len(d.sections))
+	return d, nil
+}
+
+// Run takes over terminal until user quits or ctx is done
+func (d *Dashboard) Run(ctx context.Context) error {
+	if d.screen == nil {
+		screen, err := tcell.NewScreen()
+		if err != nil {
+			return err
+		}
+		d.screen = screen
+	}
+	if err := d.screen.Init(); err != nil {
+		return err
+	}
+	defer d.screen.Fini()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go d.poll(ctx)
+
+	events := make(chan tcell.Event)
+	go func() {
+		for {
+			ev := d.screen.PollEvent()
+			// nil means screen was finalized
+			if ev == nil {
+				return
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	d.draw()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev := <-events:
+			if quit := d.handle(ev); quit {
+				return nil
+			}
+			d.draw()
+		}
+	}
+}
+
+// Refresh reads sections as soon as possible
+func (d *Dashboard) Refresh() {
+	select {
+	case d.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dashboard) poll(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		readings := make([]reading, len(d.sections))
+		for i, number := range d.sections {
+			s, err := d.access.Section(number)
+			readings[i] = reading{section: s, err: err}
+		}
+
+		d.mtx.Lock()
+		d.readings = readings
+		d.updated = time.Now()
+		d.mtx.Unlock()
+		// Wake up event loop to redraw
+		_ = d.screen.PostEvent(tcell.NewEventInterrupt(nil))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.trigger:
+		}
+	}
+}
+
+// handle returns true, if user wants to quit
+func (d *Dashboard) handle(ev tcell.Event) bool {
+	key, ok := ev.(*tcell.EventKey)
+	if !ok {
+		if _, resized := ev.(*tcell.EventResize); resized {
+			d.screen.Sync()
+		}
+		return false
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.confirm {
+		d.confirm = false
+		d.message = ""
+		if key.Key() == tcell.KeyRune && (key.Rune() == 'y' || key.Rune() == 'Y') {
+			d.switchSelected()
+		}
+		return false
+	}
+
+	switch key.Key() {
+	case tcell.KeyCtrlC, tcell.KeyEscape:
+		return true
+	case tcell.KeyLeft, tcell.KeyBacktab:
+		d.selected = (d.selected + len(d.sections) - 1) % len(d.sections)
+	case tcell.KeyRight, tcell.KeyTab:
+		d.selected = (d.selected + 1) % len(d.sections)
+	case tcell.KeyEnter:
+		d.askConfirmation()
+	case tcell.KeyRune:
+		switch r := key.Rune(); {
+		case r == 'q':
+			return true
+		case r == 'r':
+			d.Refresh()
+		case r == ' ':
+			d.askConfirmation()
+		case r >= '1' && r <= '9':
+			for i, number := range d.sections {
+				if strconv.Itoa(number) == string(r) {
+					d.selected = i
+				}
+			}
+		}
+	}
+	return false
+}
+
+// askConfirmation must be called with mtx locked
+func (d *Dashboard) askConfirmation() {
+	r := d.readings[d.selected]
+	if r.err != nil || r.section == nil {
+		// State is unknown, switching would be a guess
+		d.message = "section can't be read, refresh first"
+		return
+	}
+	d.confirm = true
+	d.pending = switching{index: d.selected, number: d.sections[d.selected], state: !r.section.State}
+	d.message = fmt.Sprintf("Switch output %d %s? [y/N]", d.pending.number, onOff(d.pending.state))
+}
+
+// switchSelected switches section, as it was confirmed. It must be called with mtx locked.
+func (d *Dashboard) switchSelected() {
+	number, state := d.pending.number, d.pending.state
+	if r := d.readings[d.pending.index]; r.err != nil || r.section == nil {
+		d.message = fmt.Sprintf("output %d can't be read anymore, not switched", number)
+		return
+	}
+	go func() {
+		if _, err := d.access.SetState(number, state); err != nil {
+			d.mtx.Lock()
+			d.message = fmt.Sprintf("error on switching output %d: %v", number, err)
+			d.mtx.Unlock()
+		}
+		d.Refresh()
+	}()
+}
+
+func (d *Dashboard) draw() {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	s := d.screen
+	s.Clear()
+	bold := tcell.StyleDefault.Bold(true)
+	dim := tcell.StyleDefault.Dim(true)
+
+	put(s, 0, 0, bold, d.title)
+	if !d.updated.IsZero() {
+		put(s, labelWidth+len(d.sections)*columnWidth-8, 0, dim, d.updated.Format("15:04:05"))
+	}
+
+	labels := []string{"Output", "State", "Voltage", "Current", "Mode", "Trip"}
+	for row, label := range labels {
+		put(s, 0, row+2, dim, label)
+	}
+
+	for i, number := range d.sections {
+		x := labelWidth + i*columnWidth
+		numberStyle := bold
+		if i == d.selected {
+			numberStyle = numberStyle.Reverse(true)
+		}
+		put(s, x, 2, numberStyle, " "+strconv.Itoa(number)+" ")
+
+		r := d.readings[i]
+		switch {
+		case r.err != nil:
+			put(s, x, 3, tcell.StyleDefault.Foreground(tcell.ColorRed), "error")
+			put(s, x, 4, dim, truncate(r.err.Error(), columnWidth-1))
+			continue
+		case r.section == nil:
+			put(s, x, 3, dim, "-")
+			continue
+		}
+
+		section := r.section
+		stateStyle := tcell.StyleDefault.Foreground(tcell.ColorGray)
+		if section.State {
+			stateStyle = tcell.StyleDefault.Foreground(tcell.ColorGreen).Bold(true)
+		}
+		put(s, x, 3, stateStyle, onOff(section.State))
+		put(s, x, 4, tcell.StyleDefault, section.ActualVoltage+" / "+section.SetVoltage+" V")
+		put(s, x, 5, tcell.StyleDefault, section.ActualCurrent+" / "+section.SetCurrent+" A")
+		put(s, x, 6, modeStyle(section.Mode), section.Mode.String())
+		tripStyle := tcell.StyleDefault
+		if section.Trip != 0 {
+			tripStyle = tripStyle.Foreground(tcell.ColorRed).Bold(true)
+		}
+		put(s, x, 7, tripStyle, section.Trip.String())
+	}
+
+	put(s, 0, 9, dim, help)
+	if d.message != "" {
+		style := tcell.StyleDefault
+		if d.confirm {
+			style = style.Foreground(tcell.ColorYellow).Bold(true)
+		}
+		put(s, 0, 10, style, d.message)
+	}
+	s.Show()
+}
+
+func (d *Dashboard) verify() error {
+	if d.access == nil {
+		return psu.ErrNoAccess
+	}
+	if len(d.sections) == 0 {
+		return psu.ErrNoSection
+	}
+	return nil
+}
+
+// modeStyle highlights the same modes as View
+func modeStyle(m psu.Mode) tcell.Style {
+	switch m {
+	case psu.ModeCC:
+		return tcell.StyleDefault.Foreground(tcell.ColorYellow).Bold(true)
+	case psu.ModeUnregulated:
+		return tcell.StyleDefault.Foreground(tcell.ColorRed).Bold(true)
+	default:
+		return tcell.StyleDefault
+	}
+}
+
+func put(s tcell.Screen, x, y int, style tcell.Style, text string) {
+	for _, r := range text {
+		s.SetContent(x, y, r, nil, style)
+		x++
+	}
+}
+
+func truncate(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+func onOff(state bool) string {
+	if state {
+		return "ON"
+	}
+	return "OFF"
+}
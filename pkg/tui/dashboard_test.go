@@ -0,0 +1,234 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package tui_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/psu"
+	"psu/pkg/tui"
+)
+
+type DashboardTestSuite struct {
+	suite.Suite
+	mock   *AccessMocker
+	screen *ScreenMocker
+	done   chan struct{}
+}
+
+// ScreenMocker keeps text shown on screen, as contents of SimulationScreen can't be read concurrently
+type ScreenMocker struct {
+	tcell.SimulationScreen
+	mtx  sync.Mutex
+	text string
+}
+
+type AccessMocker struct {
+	mock.Mock
+}
+
+func TestDashboard(t *testing.T) {
+	suite.Run(t, new(DashboardTestSuite))
+}
+
+func (t *DashboardTestSuite) SetupTest() {
+	t.mock = new(AccessMocker)
+	t.screen = &ScreenMocker{SimulationScreen: tcell.NewSimulationScreen("UTF-8")}
+	t.done = make(chan struct{})
+}
+
+// run starts dashboard, it is stopped at the end of test
+func (t *DashboardTestSuite) run() {
+	d, err := tui.New(
+		tui.WithAccess(t.mock),
+		tui.WithSections(1, 2),
+		tui.WithInterval(time.Hour),
+		tui.WithScreen(t.screen),
+	)
+	t.Require().Nil(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer close(t.done)
+		t.Nil(d.Run(ctx))
+	}()
+	t.T().Cleanup(func() {
+		cancel()
+		<-t.done
+	})
+}
+
+func (t *DashboardTestSuite) text() string {
+	t.screen.mtx.Lock()
+	defer t.screen.mtx.Unlock()
+	return t.screen.text
+}
+
+func (t *DashboardTestSuite) waitFor(text string) {
+	t.Require().Eventually(func() bool {
+		return strings.Contains(t.text(), text)
+	}, time.Second, 5*time.Millisecond, text)
+}
+
+func (t *DashboardTestSuite) expectSections() {
+	t.mock.On("Section", 1).Return(&psu.Section{
+		State:         true,
+		ActualVoltage: "12.00",
+		SetVoltage:    "12.00",
+		ActualCurrent: "0.500",
+		SetCurrent:    "1.000",
+		Mode:          psu.ModeCV,
+	}, nil)
+	t.mock.On("Section", 2).Return(&psu.Section{
+		ActualVoltage: "0.00",
+		SetVoltage:    "5.00",
+		ActualCurrent: "0.000",
+		SetCurrent:    "2.000",
+		Trip:          psu.TripOverCurrent,
+	}, nil)
+}
+
+func (t *DashboardTestSuite) TestNew() {
+	d, err := tui.New()
+	t.Nil(d)
+	t.ErrorIs(err, psu.ErrNoAccess)
+
+	d, err = tui.New(tui.WithAccess(t.mock))
+	t.Nil(d)
+	t.ErrorIs(err, psu.ErrNoSection)
+
+	d, err = tui.New(tui.WithAccess(t.mock), tui.WithSections(1), tui.WithInterval(0))
+	t.Nil(d)
+	t.ErrorIs(err, tui.ErrInvalidInterval)
+}
+
+func (t *DashboardTestSuite) TestShowsSections() {
+	t.expectSections()
+	t.run()
+	t.waitFor("12.00 / 12.00 V")
+	text := t.text()
+	t.Contains(text, "0.500 / 1.000 A")
+	t.Contains(text, "0.00 / 5.00 V")
+	t.Contains(text, "OCP")
+	t.Contains(text, "CV")
+}
+
+func (t *DashboardTestSuite) TestError() {
+	var nilSection *psu.Section
+	t.mock.On("Section", 1).Return(nilSection, errors.New("timeout"))
+	t.mock.On("Section", 2).Return(nilSection, errors.New("timeout"))
+	t.run()
+	t.waitFor("timeout")
+
+	// State is unknown, so it can't be switched
+	t.screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	t.waitFor("can't be read")
+	t.mock.AssertNotCalled(t.T(), "SetState", mock.Anything, mock.Anything)
+}
+
+func (t *DashboardTestSuite) TestToggle() {
+	t.expectSections()
+	switched := make(chan struct{})
+	t.mock.On("SetState", 2, true).Return(true, nil).Once().Run(func(mock.Arguments) {
+		close(switched)
+	})
+	t.run()
+	t.waitFor("12.00 / 12.00 V")
+
+	t.screen.InjectKey(tcell.KeyRune, '2', tcell.ModNone)
+	t.screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	t.waitFor("Switch output 2 ON? [y/N]")
+	t.screen.InjectKey(tcell.KeyRune, 'y', tcell.ModNone)
+
+	select {
+	case <-switched:
+	case <-time.After(time.Second):
+		t.Fail("output wasn't switched")
+	}
+}
+
+func (t *DashboardTestSuite) TestErrorBeforeConfirm() {
+	var nilSection *psu.Section
+	release := make(chan time.Time)
+	t.mock.On("Section", 1).Return(&psu.Section{State: true}, nil).Once()
+	t.mock.On("Section", 1).Return(nilSection, errors.New("timeout")).WaitUntil(release)
+	t.mock.On("Section", 2).Return(&psu.Section{}, nil)
+	t.run()
+	t.waitFor("ON")
+
+	// Reading fails, while confirmation is shown
+	t.screen.InjectKey(tcell.KeyRune, 'r', tcell.ModNone)
+	t.screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	t.waitFor("Switch output 1 OFF? [y/N]")
+	close(release)
+	t.waitFor("timeout")
+	t.screen.InjectKey(tcell.KeyRune, 'y', tcell.ModNone)
+	t.waitFor("output 1 can't be read anymore")
+	t.mock.AssertNotCalled(t.T(), "SetState", mock.Anything, mock.Anything)
+}
+
+func (t *DashboardTestSuite) TestCancel() {
+	t.expectSections()
+	t.run()
+	t.waitFor("12.00 / 12.00 V")
+
+	t.screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	t.waitFor("Switch output 1 OFF? [y/N]")
+	t.screen.InjectKey(tcell.KeyRune, 'n', tcell.ModNone)
+	t.Require().Eventually(func() bool {
+		return !strings.Contains(t.text(), "Switch output")
+	}, time.Second, 5*time.Millisecond)
+	t.mock.AssertNotCalled(t.T(), "SetState", mock.Anything, mock.Anything)
+}
+
+func (t *DashboardTestSuite) TestQuit() {
+	t.expectSections()
+	t.run()
+	t.waitFor("12.00 / 12.00 V")
+
+	t.screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+	select {
+	case <-t.done:
+	case <-time.After(time.Second):
+		t.Fail("dashboard didn't quit")
+	}
+}
+
+func (a *AccessMocker) Section(number int) (*psu.Section, error) {
+	args := a.Called(number)
+	return args.Get(0).(*psu.Section), args.Error(1)
+}
+
+func (a *AccessMocker) SetState(number int, state bool) (bool, error) {
+	args := a.Called(number, state)
+	return args.Bool(0), args.Error(1)
+}
+
+// Show keeps content of screen, line by line
+func (s *ScreenMocker) Show() {
+	s.SimulationScreen.Show()
+	cells, width, _ := s.GetContents()
+	var b strings.Builder
+	for i, cell := range cells {
+		if len(cell.Runes) > 0 {
+			b.WriteRune(cell.Runes[0])
+		}
+		if (i+1)%width == 0 {
+			b.WriteRune('\n')
+		}
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.text = b.String()
+}
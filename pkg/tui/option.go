@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package tui
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"psu/pkg/psu"
+)
+
+type Option func(*Dashboard) error
+
+func WithPSU(p *psu.PSU) Option {
+	return func(d *Dashboard) error {
+		return WithAccess(p)(d)
+	}
+}
+
+func WithAccess(a psu.Access) Option {
+	return func(d *Dashboard) error {
+		d.access = a
+		return nil
+	}
+}
+
+func WithSections(sections ...int) Option {
+	return func(d *Dashboard) error {
+		d.sections = append(d.sections, sections...)
+		return nil
+	}
+}
+
+// WithInterval sets how often sections are read
+func WithInterval(t time.Duration) Option {
+	return func(d *Dashboard) error {
+		if t <= 0 {
+			return ErrInvalidInterval
+		}
+		d.interval = t
+		return nil
+	}
+}
+
+// WithScreen replaces terminal, e.g. with tcell.NewSimulationScreen
+func WithScreen(s tcell.Screen) Option {
+	return func(d *Dashboard) error {
+		d.screen = s
+		return nil
+	}
+}
+
+// WithTitle replaces default "CPX400DP" title
+func WithTitle(title string) Option {
+	return func(d *Dashboard) error {
+		d.title = title
+		return nil
+	}
+}
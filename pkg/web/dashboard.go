@@ -0,0 +1,274 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+// Package web serves dashboard, which shows live readings of PSU in browser.
+//
+// Readings are pushed over WebSocket (/ws) as Message of type "sections".
+// Browser switches output by sending Message of type "state".
+package web
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"psu/pkg/api"
+	"psu/pkg/psu"
+)
+
+//go:embed static
+var static embed.FS
+
+// Message is exchanged over WebSocket
+type Message struct {
+	// Type is "sections" (server to browser), "state" (browser to server) or "error" (server to browser)
+	Type     string    `json:"type"`
+	Sections []Reading `json:"sections,omitempty"`
+	Section  int       `json:"section,omitempty"`
+	State    bool      `json:"state,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Reading of single section, Error is set if section couldn't be read
+type Reading struct {
+	api.Section
+	Error string `json:"error,omitempty"`
+}
+
+type Dashboard struct {
+	access   psu.Access
+	sections []int
+	interval time.Duration
+	upgrader websocket.Upgrader
+	mux      *http.ServeMux
+	trigger  chan struct{}
+
+	mtx     sync.Mutex
+	clients map[*client]struct{}
+	last    []byte
+}
+
+type client struct {
+	conn *websocket.Conn
+	send chan []byte
+}
+
+var (
+	ErrInvalidInterval = errors.New("interval must be positive")
+)
+
+var (
+	_ http.Handler = (*Dashboard)(nil)
+)
+
+func New(opts ...Option) (*Dashboard, error) {
+	d := &Dashboard{
+		access:   nil,
+		sections: nil,
+		interval: 1 * time.Second,
+		mux:      http.NewServeMux(),
+		trigger:  make(chan struct{}, 1),
+		clients:  make(map[*client]struct{}),
+	}
+	for _, opt := range opts {
+		if err := opt(d); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.verify(); err != nil {
+		return nil, err
+	}
+
+	root, err := fs.Sub(static, "static")
+	if err != nil {
+		return nil, err
+	}
+	d.mux.Handle("/", http.FileServer(http.FS(root)))
+	d.mux.HandleFunc("/ws", d.websocket)
+	return d, nil
+}
+
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mux.ServeHTTP(w, r)
+}
+
+// Run polls PSU and pushes readings to connected browsers, until ctx is done
+func (d *Dashboard) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		d.poll()
+		select {
+		case <-ctx.Done():
+			d.disconnectAll()
+			return
+		case <-ticker.C:
+		case <-d.trigger:
+		}
+	}
+}
+
+func (d *Dashboard) poll() {
+	msg := Message{Type: "sections", Sections: make([]Reading, len(d.sections))}
+	for i, number := range d.sections {
+		msg.Sections[i] = d.read(number)
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Error("error on encoding readings: ", err)
+		return
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.last = data
+	for c := range d.clients {
+		d.push(c, data)
+	}
+}
+
+func (d *Dashboard) read(number int) Reading {
+	reading := Reading{Section: api.Section{Section: number, Trip: []string{}}}
+	s, err := d.access.Section(number)
+	if err != nil {
+		reading.Error = err.Error()
+		return reading
+	}
+	section, err := api.NewSection(number, s)
+	if err != nil {
+		reading.Error = err.Error()
+		return reading
+	}
+	reading.Section = section
+	return reading
+}
+
+// push must be called with mtx locked
+func (d *Dashboard) push(c *client, data []byte) {
+	select {
+	case c.send <- data:
+	default:
+		// Browser can't keep up, it will reconnect
+		log.Debug("dropping slow client ", c.conn.RemoteAddr())
+		d.disconnect(c)
+	}
+}
+
+func (d *Dashboard) websocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := d.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Error("error on upgrading connection: ", err)
+		return
+	}
+	c := &client{conn: conn, send: make(chan []byte, 8)}
+
+	d.mtx.Lock()
+	d.clients[c] = struct{}{}
+	if d.last != nil {
+		c.send <- d.last
+	}
+	d.mtx.Unlock()
+
+	go d.write(c)
+	d.readMessages(c)
+}
+
+// readMessages handles messages from browser, until connection is closed
+func (d *Dashboard) readMessages(c *client) {
+	defer func() {
+		d.mtx.Lock()
+		d.disconnect(c)
+		d.mtx.Unlock()
+	}()
+	for {
+		var msg Message
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		if msg.Type != "state" {
+			continue
+		}
+		if !d.handles(msg.Section) {
+			d.sendError(c, "no such section")
+			continue
+		}
+		if _, err := d.access.SetState(msg.Section, msg.State); err != nil {
+			d.sendError(c, err.Error())
+		}
+		d.Refresh()
+	}
+}
+
+func (d *Dashboard) write(c *client) {
+	for data := range c.send {
+		if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			log.Debug("error on writing to ", c.conn.RemoteAddr(), ": ", err)
+			break
+		}
+	}
+	_ = c.conn.Close()
+}
+
+func (d *Dashboard) sendError(c *client, text string) {
+	data, err := json.Marshal(Message{Type: "error", Error: text})
+	if err != nil {
+		return
+	}
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if _, ok := d.clients[c]; ok {
+		d.push(c, data)
+	}
+}
+
+// Refresh polls PSU as soon as possible
+func (d *Dashboard) Refresh() {
+	select {
+	case d.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// disconnect must be called with mtx locked
+func (d *Dashboard) disconnect(c *client) {
+	if _, ok := d.clients[c]; !ok {
+		return
+	}
+	delete(d.clients, c)
+	close(c.send)
+}
+
+func (d *Dashboard) disconnectAll() {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	for c := range d.clients {
+		d.disconnect(c)
+	}
+}
+
+func (d *Dashboard) handles(number int) bool {
+	for _, section := range d.sections {
+		if section == number {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Dashboard) verify() error {
+	if d.access == nil {
+		return psu.ErrNoAccess
+	}
+	if len(d.sections) == 0 {
+		return psu.ErrNoSection
+	}
+	return nil
+}
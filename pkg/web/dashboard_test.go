@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package web_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"psu/pkg/psu"
+	"psu/pkg/web"
+)
+
+type DashboardTestSuite struct {
+	suite.Suite
+	mock *AccessMocker
+}
+
+type AccessMocker struct {
+	mock.Mock
+}
+
+func TestDashboard(t *testing.T) {
+	suite.Run(t, new(DashboardTestSuite))
+}
+
+func (t *DashboardTestSuite) SetupTest() {
+	t.mock = new(AccessMocker)
+}
+
+// serve starts dashboard and returns its url
+func (t *DashboardTestSuite) serve() string {
+	d, err := web.New(web.WithAccess(t.mock), web.WithSections(1, 2), web.WithInterval(time.Hour))
+	t.Require().Nil(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(ctx)
+	}()
+	server := httptest.NewServer(d)
+	t.T().Cleanup(func() {
+		cancel()
+		<-done
+		server.Close()
+	})
+	return server.URL
+}
+
+func (t *DashboardTestSuite) dial(url string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http")+"/ws", nil)
+	t.Require().Nil(err)
+	t.Require().Nil(conn.SetReadDeadline(time.Now().Add(time.Second)))
+	t.T().Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func (t *DashboardTestSuite) TestNew() {
+	d, err := web.New()
+	t.Nil(d)
+	t.ErrorIs(err, psu.ErrNoAccess)
+
+	d, err = web.New(web.WithAccess(t.mock), web.WithSections(1), web.WithInterval(0))
+	t.Nil(d)
+	t.ErrorIs(err, web.ErrInvalidInterval)
+}
+
+func (t *DashboardTestSuite) TestIndex() {
+	r := t.Require()
+	t.mock.On("Section", mock.Anything).Return(&psu.Section{}, nil)
+	url := t.serve()
+
+	resp, err := http.Get(url + "/")
+	r.Nil(err)
+	defer resp.Body.Close()
+	r.Equal(http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	r.Nil(err)
+	r.Contains(string(body), "<title>CPX400DP</title>")
+}
+
+func (t *DashboardTestSuite) TestReadings() {
+	r := t.Require()
+	var nilSection *psu.Section
+	t.mock.On("Section", 1).Return(&psu.Section{
+		State:         true,
+		ActualVoltage: "5.00",
+		SetVoltage:    "5.00",
+		ActualCurrent: "0.100",
+		SetCurrent:    "1.000",
+		Mode:          psu.ModeCV,
+	}, nil)
+	t.mock.On("Section", 2).Return(nilSection, errors.New("timeout"))
+	url := t.serve()
+
+	conn := t.dial(url)
+	var msg web.Message
+	r.Nil(conn.ReadJSON(&msg))
+	r.Equal("sections", msg.Type)
+	r.Len(msg.Sections, 2)
+	r.Equal(1, msg.Sections[0].Section.Section)
+	r.True(msg.Sections[0].State)
+	r.Equal(5.0, msg.Sections[0].ActualVoltage)
+	r.Equal("CV", msg.Sections[0].Mode)
+	r.Equal("", msg.Sections[0].Error)
+	r.Equal(2, msg.Sections[1].Section.Section)
+	r.Equal("timeout", msg.Sections[1].Error)
+}
+
+func (t *DashboardTestSuite) TestSetState() {
+	r := t.Require()
+	t.mock.On("Section", mock.Anything).Return(&psu.Section{ActualVoltage: "0", SetVoltage: "0", ActualCurrent: "0", SetCurrent: "0"}, nil)
+	called := make(chan struct{})
+	t.mock.On("SetState", 2, true).Return(true, nil).Once().Run(func(mock.Arguments) {
+		close(called)
+	})
+	url := t.serve()
+
+	conn := t.dial(url)
+	var msg web.Message
+	r.Nil(conn.ReadJSON(&msg))
+
+	r.Nil(conn.WriteJSON(web.Message{Type: "state", Section: 2, State: true}))
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		r.Fail("SetState not called")
+	}
+	// Readings are refreshed right after switching
+	r.Nil(conn.ReadJSON(&msg))
+	r.Equal("sections", msg.Type)
+
+	r.Nil(conn.WriteJSON(web.Message{Type: "state", Section: 3, State: true}))
+	r.Nil(conn.ReadJSON(&msg))
+	r.Equal("error", msg.Type)
+	r.Equal("no such section", msg.Error)
+}
+
+func (a *AccessMocker) Section(section int) (*psu.Section, error) {
+	args := a.Called(section)
+	return args.Get(0).(*psu.Section), args.Error(1)
+}
+
+func (a *AccessMocker) SetState(section int, value bool) (bool, error) {
+	args := a.Called(section, value)
+	return args.Bool(0), args.Error(1)
+}
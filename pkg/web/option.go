@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2023 a-clap. All rights reserved.
+ * Use of this source code is governed by a MIT-style license that can be found in the LICENSE file.
+ */
+
+package web
+
+import (
+	"time"
+
+	"psu/pkg/psu"
+)
+
+type Option func(*Dashboard) error
+
+func WithPSU(p *psu.PSU) Option {
+	return func(d *Dashboard) error {
+		return WithAccess(p)(d)
+	}
+}
+
+func WithAccess(a psu.Access) Option {
+	return func(d *Dashboard) error {
+		d.access = a
+		return nil
+	}
+}
+
+func WithSections(sections ...int) Option {
+	return func(d *Dashboard) error {
+		d.sections = append(d.sections, sections...)
+		return nil
+	}
+}
+
+// WithInterval sets how often readings are pushed to browsers
+func WithInterval(t time.Duration) Option {
+	return func(d *Dashboard) error {
+		if t <= 0 {
+			return ErrInvalidInterval
+		}
+		d.interval = t
+		return nil
+	}
+}